@@ -0,0 +1,156 @@
+package beancount
+
+import (
+	"bufio"
+	"bytes"
+	"math/big"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+var (
+	accountRe   = regexp.MustCompile(`^(Assets|Liabilities|Equity|Income|Expenses)(:[\p{Lu}\p{Nd}][\p{L}\p{Nd}-]*)+$`)
+	commodityRe = regexp.MustCompile(`^[A-Z][A-Z0-9'._-]{0,22}[A-Z0-9]$`)
+)
+
+// check verifies a subset of bean-check semantics on the output of
+// Write: valid names, accounts opened before use, commodity constraints
+// and balanced transactions.
+func check(t *testing.T, name string, data []byte) {
+	opened := make(map[string]string)     // account -> date
+	constraint := make(map[string]string) // account -> commodity
+	var date, header string
+	weights := make(map[string]*big.Rat)
+	flush := func() {
+		for cur, w := range weights {
+			if w.Sign() != 0 {
+				t.Errorf("%s: transaction %q does not balance in %s: %s", name, header, cur, w.FloatString(2))
+			}
+		}
+		weights = make(map[string]*big.Rat)
+	}
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		line := s.Text()
+		fields := strings.Fields(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "option") || strings.HasPrefix(line, "    "):
+			continue
+		case !strings.HasPrefix(line, " "):
+			flush()
+			date, header = fields[0], line
+			switch fields[1] {
+			case "open":
+				if !accountRe.MatchString(fields[2]) {
+					t.Errorf("%s: invalid account name %q", name, fields[2])
+				}
+				if _, ok := opened[fields[2]]; ok {
+					t.Errorf("%s: account %s opened twice", name, fields[2])
+				}
+				opened[fields[2]] = date
+				if len(fields) > 3 {
+					constraint[fields[2]] = fields[3]
+				}
+			case "commodity", "price":
+				if !commodityRe.MatchString(fields[2]) {
+					t.Errorf("%s: invalid commodity %q", name, fields[2])
+				}
+			case "balance":
+				if d, ok := opened[fields[2]]; !ok || d >= date {
+					t.Errorf("%s: balance of account %s not opened before %s", name, fields[2], date)
+				}
+			}
+		case strings.HasSuffix(fields[0], ":"):
+			// Metadata.
+		default:
+			acct := fields[0]
+			if d, ok := opened[acct]; !ok || d > date {
+				t.Errorf("%s: account %s used on %s before being opened", name, acct, date)
+			}
+			if c := constraint[acct]; c != fields[2] {
+				t.Errorf("%s: posting in %s to account %s of %s", name, fields[2], acct, c)
+			}
+			x, _ := new(big.Rat).SetString(fields[1])
+			cur := fields[2]
+			if len(fields) == 6 && fields[3] == "@@" {
+				total, _ := new(big.Rat).SetString(fields[4])
+				if x.Sign() < 0 {
+					total.Neg(total)
+				}
+				x, cur = total, fields[5]
+			}
+			if weights[cur] == nil {
+				weights[cur] = new(big.Rat)
+			}
+			weights[cur].Add(weights[cur], x)
+		}
+	}
+	flush()
+}
+
+func TestWrite(t *testing.T) {
+	files, _ := filepath.Glob("../xmlimport/testdata/*.gml2")
+	for _, name := range files {
+		book, err := xmlimport.ImportFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		book.Recompute()
+		var buf bytes.Buffer
+		if err := Write(&buf, book); err != nil {
+			t.Fatal(err)
+		}
+		check(t, name, buf.Bytes())
+
+		out := buf.String()
+		switch filepath.Base(name) {
+		case "stocks.gml2":
+			for _, s := range []string{
+				"option \"operating_currency\" \"USD\"",
+				"open Assets:Broker:ACME ACME\n",
+				"2013-01-10 price ACME 50.00 USD\n",
+				"10.00 ACME @@ 500.00 USD\n",
+				"name: \"Acme Corp\"",
+			} {
+				if !strings.Contains(out, s) {
+					t.Errorf("%s: output does not contain %q", name, s)
+				}
+			}
+		case "carols-data-file.gml2":
+			for _, s := range []string{
+				"open Expenses:Utilities:Electric USD\n",
+				"open Equity:Opening-Balances USD\n",
+				"balance Assets:Checking ",
+				"commodity AMD-OPT-2-8-14-98\n",
+			} {
+				if !strings.Contains(out, s) {
+					t.Errorf("%s: output does not contain %q", name, s)
+				}
+			}
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	e := &exporter{commodities: make(map[string]string)}
+	for id, exp := range map[string]string{
+		"USD":                            "USD",
+		"Adbe":                           "ADBE",
+		"AMD Opt. 5/3/99":                "AMD-OPT.-5-3-99",
+		"swpix":                          "SWPIX",
+		"X":                              "XX",
+		"1st":                            "C1ST",
+		"Firsthand Technology Value Fun": "FIRSTHAND-TECHNOLOGY-VAL",
+	} {
+		if got := e.commodity(id); got != exp {
+			t.Errorf("commodity(%q) = %q, expected %q", id, got, exp)
+		}
+	}
+	if got := component("pocket cash"); got != "Pocket-cash" {
+		t.Errorf("component = %q", got)
+	}
+}
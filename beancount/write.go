@@ -0,0 +1,329 @@
+// Package beancount implements export of books to the Beancount
+// plain text format.
+package beancount
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Root returns the Beancount root account for an account type.
+func Root(typ string) string {
+	acct := types.Account{Type: typ}
+	switch {
+	case acct.IsAsset():
+		return "Assets"
+	case acct.IsLiability():
+		return "Liabilities"
+	case typ == "INCOME":
+		return "Income"
+	case typ == "EXPENSE":
+		return "Expenses"
+	}
+	return "Equity"
+}
+
+// exporter holds the names assigned to accounts and commodities.
+type exporter struct {
+	book        *types.Book
+	accounts    map[*types.Account]string
+	commodities map[string]string
+}
+
+// Write writes book in Beancount syntax: commodity and open directives,
+// prices, transactions and balance assertions for the latest consistent
+// reconciliation of each account. Gnucash identifiers are kept as guid
+// metadata. The book must have been recomputed.
+func Write(w io.Writer, book *types.Book) error {
+	e := &exporter{
+		book:        book,
+		accounts:    make(map[*types.Account]string),
+		commodities: make(map[string]string),
+	}
+	e.assignNames()
+	bw := bufio.NewWriter(w)
+
+	if cur := book.DefaultCurrency(); cur != "" {
+		fmt.Fprintf(bw, "option \"operating_currency\" \"%s\"\n\n", e.commodity(cur))
+	}
+
+	trns := sortedTransactions(book)
+	start := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+	if len(trns) > 0 {
+		start = day(trns[0].Date)
+	}
+
+	for _, c := range book.Commodities {
+		if c.Space == "template" {
+			continue
+		}
+		fmt.Fprintf(bw, "%s commodity %s\n", start.Format("2006-01-02"), e.commodity(c.Id))
+		if c.Name != "" {
+			fmt.Fprintf(bw, "  name: %s\n", quote(c.Name))
+		}
+		if c.Space != "" {
+			fmt.Fprintf(bw, "  namespace: %s\n", quote(c.Space))
+		}
+	}
+	fmt.Fprintln(bw)
+
+	accts := make([]*types.Account, 0, len(e.accounts))
+	for acct := range e.accounts {
+		accts = append(accts, acct)
+	}
+	sort.Slice(accts, func(i, j int) bool { return e.accounts[accts[i]] < e.accounts[accts[j]] })
+	for _, acct := range accts {
+		open := start
+		for i, f := range book.Flows[acct] {
+			if d := day(f.Parent.Date); i == 0 || d.Before(open) {
+				open = d
+			}
+		}
+		fmt.Fprintf(bw, "%s open %s", open.Format("2006-01-02"), e.accounts[acct])
+		if acct.Unit != "" {
+			fmt.Fprintf(bw, " %s", e.commodity(acct.Unit))
+		}
+		fmt.Fprintf(bw, "\n  guid: %s\n", quote(string(acct.Id)))
+		if acct.Description != "" {
+			fmt.Fprintf(bw, "  description: %s\n", quote(acct.Description))
+		}
+	}
+	fmt.Fprintln(bw)
+
+	for _, p := range book.Prices {
+		fmt.Fprintf(bw, "%s price %s %s %s\n", p.Time.Format("2006-01-02"),
+			e.commodity(p.Commodity), p.Value.Decimal(), e.commodity(p.Currency))
+	}
+	if len(book.Prices) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	for _, trn := range trns {
+		e.writeTransaction(bw, trn)
+	}
+
+	for _, acct := range accts {
+		if date, bal, ok := reconciledBalance(book, acct); ok {
+			fmt.Fprintf(bw, "%s balance %s %s %s\n", date.AddDate(0, 0, 1).Format("2006-01-02"),
+				e.accounts[acct], (*types.Amount)(bal).Decimal(), e.commodity(unitOf(acct, book)))
+		}
+	}
+	return bw.Flush()
+}
+
+func (e *exporter) writeTransaction(w io.Writer, trn *types.Transaction) {
+	fmt.Fprintf(w, "%s * %s\n", trn.Date.Format("2006-01-02"), quote(trn.Description))
+	fmt.Fprintf(w, "  guid: %s\n", quote(string(trn.Id)))
+	if trn.Number != "" {
+		fmt.Fprintf(w, "  number: %s\n", quote(trn.Number))
+	}
+	if trn.Notes != "" {
+		fmt.Fprintf(w, "  notes: %s\n", quote(trn.Notes))
+	}
+	if trn.ExternalId != "" {
+		fmt.Fprintf(w, "  external_id: %s\n", quote(trn.ExternalId))
+	}
+	if trn.IsVoid() {
+		fmt.Fprintf(w, "  void_reason: %s\n", quote(trn.VoidReason))
+	}
+	for _, f := range trn.Flows {
+		var amount string
+		unit := f.Account.Unit
+		if unit == "" || unit == trn.Currency || f.Quantity == nil {
+			amount = f.Price.Decimal() + " " + e.commodity(trn.Currency)
+		} else {
+			cost := new(big.Rat).Abs(f.Price.Rat())
+			amount = fmt.Sprintf("%s %s @@ %s %s", f.Quantity.Decimal(), e.commodity(unit),
+				(*types.Amount)(cost).Decimal(), e.commodity(trn.Currency))
+		}
+		fmt.Fprintf(w, "  %-50s %20s\n", e.accounts[f.Account], amount)
+		fmt.Fprintf(w, "    guid: %s\n", quote(string(f.Id)))
+		if f.Memo != "" {
+			fmt.Fprintf(w, "    memo: %s\n", quote(f.Memo))
+		}
+		if f.State.IsReconciled() && !f.ReconciledTime.IsZero() {
+			fmt.Fprintf(w, "    reconciled: %s\n", f.ReconciledTime.Format("2006-01-02"))
+		}
+		if f.VoidPrice != nil {
+			fmt.Fprintf(w, "    void_value: %s %s\n", f.VoidPrice.Decimal(), e.commodity(trn.Currency))
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// assignNames computes valid and unique Beancount names for accounts:
+// the root is chosen from the account type, and a top-level account
+// with the name of the root is omitted.
+func (e *exporter) assignNames() {
+	used := make(map[string]bool)
+	accts := make([]*types.Account, 0, len(e.book.Accounts))
+	for _, acct := range e.book.Accounts {
+		if acct.Type != "ROOT" {
+			accts = append(accts, acct)
+		}
+	}
+	sort.Slice(accts, func(i, j int) bool { return accts[i].Name < accts[j].Name })
+	for _, acct := range accts {
+		root := Root(acct.Type)
+		parts := strings.Split(strings.TrimPrefix(acct.Name, "/"), "/")
+		if len(parts) > 1 && strings.EqualFold(parts[0], root) {
+			parts = parts[1:]
+		}
+		name := root
+		for _, p := range parts {
+			name += ":" + component(p)
+		}
+		base := name
+		for n := 2; used[name]; n++ {
+			name = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[name] = true
+		e.accounts[acct] = name
+	}
+}
+
+// component returns a valid account name component: it starts with
+// a capital letter or a digit and contains letters, digits and dashes.
+func component(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if b.Len() == 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if name == "" {
+		return "X"
+	}
+	return name
+}
+
+// commodity returns a valid Beancount currency name: 2 to 24
+// characters among capital letters, digits and '._-, starting with
+// a letter and ending with a letter or digit.
+func (e *exporter) commodity(id string) string {
+	if name, ok := e.commodities[id]; ok {
+		return name
+	}
+	var b strings.Builder
+	for _, r := range strings.ToUpper(id) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '\'', r == '.', r == '_':
+			b.WriteRune(r)
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), "-"):
+			b.WriteByte('-')
+		}
+	}
+	name := strings.TrimRight(b.String(), "'._-")
+	if name == "" || name[0] < 'A' || name[0] > 'Z' {
+		name = "C" + name
+	}
+	if len(name) > 24 {
+		name = strings.TrimRight(name[:24], "'._-")
+	}
+	if len(name) < 2 {
+		name += "X"
+	}
+	// Avoid collisions between distinct ids.
+	base := name
+	for n := 2; e.hasCommodity(name); n++ {
+		suffix := fmt.Sprint(n)
+		if len(base)+len(suffix) > 24 {
+			base = base[:24-len(suffix)]
+		}
+		name = base + suffix
+	}
+	e.commodities[id] = name
+	return name
+}
+
+func (e *exporter) hasCommodity(name string) bool {
+	for _, n := range e.commodities {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reconciledBalance returns the latest date where the balance of
+// reconciled flows of acct equals the balance of all its flows, and
+// that balance. Dates are the reconciliation dates of flows and the
+// last reconciliation date of the account.
+func reconciledBalance(book *types.Book, acct *types.Account) (time.Time, *big.Rat, bool) {
+	var dates []time.Time
+	if !acct.LastReconcile.IsZero() {
+		dates = append(dates, day(acct.LastReconcile))
+	}
+	for _, f := range book.Flows[acct] {
+		if f.State.IsReconciled() && !f.ReconciledTime.IsZero() {
+			dates = append(dates, day(f.ReconciledTime))
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].After(dates[j]) })
+	for _, d := range dates {
+		end := d.AddDate(0, 0, 1)
+		all, reconciled := new(big.Rat), new(big.Rat)
+		for _, f := range book.Flows[acct] {
+			x := f.Units().Rat()
+			if day(f.Parent.Date).Before(end) {
+				all.Add(all, x)
+			}
+			if f.State.IsReconciled() && !f.ReconciledTime.IsZero() && day(f.ReconciledTime).Before(end) {
+				reconciled.Add(reconciled, x)
+			}
+		}
+		if all.Cmp(reconciled) == 0 {
+			return d, all, true
+		}
+	}
+	return time.Time{}, nil, false
+}
+
+// unitOf returns the commodity of account balances.
+func unitOf(acct *types.Account, book *types.Book) string {
+	if acct.Unit != "" {
+		return acct.Unit
+	}
+	return book.DefaultCurrency()
+}
+
+func sortedTransactions(book *types.Book) []*types.Transaction {
+	trns := make([]*types.Transaction, 0, len(book.Transactions))
+	for _, trn := range book.Transactions {
+		trns = append(trns, trn)
+	}
+	sort.Slice(trns, func(i, j int) bool {
+		a, b := trns[i], trns[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Id < b.Id
+	})
+	return trns
+}
+
+// day returns the date of t, as a UTC midnight time.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	return `"` + strings.Replace(s, `"`, `\"`, -1) + `"`
+}
@@ -0,0 +1,219 @@
+// Package camtimport implements reading of ISO 20022 bank to customer
+// statements (camt.053) and debit/credit notifications (camt.054).
+package camtimport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Document is the root element of camt messages. Element names are
+// matched without namespace so that all versions of the schemas
+// (camt.053.001.02 and later) are accepted.
+type Document struct {
+	XMLName       xml.Name
+	Statements    []Report `xml:"BkToCstmrStmt>Stmt"`
+	Notifications []Report `xml:"BkToCstmrDbtCdtNtfctn>Ntfctn"`
+}
+
+// A Report is a statement or a notification.
+type Report struct {
+	Id      string    `xml:"Id"`
+	Created string    `xml:"CreDtTm"`
+	From    string    `xml:"FrToDt>FrDtTm"`
+	To      string    `xml:"FrToDt>ToDtTm"`
+	Account Account   `xml:"Acct"`
+	Balance []Balance `xml:"Bal"`
+	Entries []Entry   `xml:"Ntry"`
+}
+
+type Account struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+type Balance struct {
+	Code      string `xml:"Tp>CdOrPrtry>Cd"`
+	Amount    Amount `xml:"Amt"`
+	CdtDbtInd string `xml:"CdtDbtInd"`
+	Date      Date   `xml:"Dt"`
+}
+
+type Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// A Date holds either a date or a date and time.
+type Date struct {
+	Date     string `xml:"Dt"`
+	DateTime string `xml:"DtTm"`
+}
+
+type Entry struct {
+	Ref         string   `xml:"NtryRef"`
+	Amount      Amount   `xml:"Amt"`
+	CdtDbtInd   string   `xml:"CdtDbtInd"`
+	Status      Status   `xml:"Sts"`
+	BookingDate Date     `xml:"BookgDt"`
+	ValueDate   Date     `xml:"ValDt"`
+	ServicerRef string   `xml:"AcctSvcrRef"`
+	Details     []Detail `xml:"NtryDtls>TxDtls"`
+	Info        string   `xml:"AddtlNtryInf"`
+}
+
+// Status is a plain string before camt.053.001.08, and a
+// code element afterwards.
+type Status struct {
+	Text string `xml:",chardata"`
+	Code string `xml:"Cd"`
+}
+
+func (s Status) String() string {
+	if s.Code != "" {
+		return s.Code
+	}
+	return strings.TrimSpace(s.Text)
+}
+
+type Detail struct {
+	EndToEndId   string   `xml:"Refs>EndToEndId"`
+	ServicerRef  string   `xml:"Refs>AcctSvcrRef"`
+	Amount       *Amount  `xml:"Amt"`
+	CdtDbtInd    string   `xml:"CdtDbtInd"`
+	Debtor       string   `xml:"RltdPties>Dbtr>Nm"`
+	DebtorParty  string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+	DebtorIBAN   string   `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+	Creditor     string   `xml:"RltdPties>Cdtr>Nm"`
+	CreditorPty  string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+	CreditorIBAN string   `xml:"RltdPties>CdtrAcct>Id>IBAN"`
+	Unstructured []string `xml:"RmtInf>Ustrd"`
+	Reference    []string `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+	Info         string   `xml:"AddtlTxInf"`
+}
+
+// Read reads a camt document from r.
+func Read(r io.Reader) (*Document, error) {
+	doc := new(Document)
+	if err := xml.NewDecoder(r).Decode(doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Statements) == 0 && len(doc.Notifications) == 0 {
+		return nil, fmt.Errorf("no camt.053 statement or camt.054 notification found")
+	}
+	return doc, nil
+}
+
+// ReadFile reads the named camt file.
+func ReadFile(name string) (*Document, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Time parses the date.
+func (d Date) Time() (time.Time, error) {
+	if d.Date != "" {
+		return time.Parse("2006-01-02", strings.TrimSpace(d.Date))
+	}
+	return parseDateTime(d.DateTime)
+}
+
+func parseDateTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// signed returns the amount with a sign from the account holder's
+// point of view: credits are positive, debits negative.
+func signed(a Amount, ind string) (*types.Amount, error) {
+	x, ok := new(big.Rat).SetString(strings.TrimSpace(a.Value))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", a.Value)
+	}
+	switch ind {
+	case "CRDT":
+	case "DBIT":
+		x.Neg(x)
+	default:
+		return nil, fmt.Errorf("invalid credit/debit indicator %q", ind)
+	}
+	return (*types.Amount)(x), nil
+}
+
+// Opening returns the opening balance of a statement (OPBD, or PRCD
+// in some banks), or nil if absent.
+func (r *Report) Opening() (*types.Amount, time.Time, error) {
+	return r.balance("OPBD", "PRCD")
+}
+
+// Closing returns the booked closing balance of a statement,
+// or nil if absent.
+func (r *Report) Closing() (*types.Amount, time.Time, error) {
+	return r.balance("CLBD")
+}
+
+func (r *Report) balance(codes ...string) (*types.Amount, time.Time, error) {
+	for _, code := range codes {
+		for _, b := range r.Balance {
+			if b.Code != code {
+				continue
+			}
+			amt, err := signed(b.Amount, b.CdtDbtInd)
+			if err != nil {
+				return nil, time.Time{}, err
+			}
+			t, err := b.Date.Time()
+			return amt, t, err
+		}
+	}
+	return nil, time.Time{}, nil
+}
+
+// Check verifies that the opening balance plus all booked entries
+// equals the closing balance.
+func (r *Report) Check() error {
+	opening, _, err := r.Opening()
+	if err != nil {
+		return err
+	}
+	closing, _, err := r.Closing()
+	if err != nil {
+		return err
+	}
+	if opening == nil || closing == nil {
+		return nil
+	}
+	sum := new(big.Rat).Set(opening.Rat())
+	for _, e := range r.Entries {
+		if st := e.Status.String(); st != "" && st != "BOOK" {
+			continue
+		}
+		amt, err := signed(e.Amount, e.CdtDbtInd)
+		if err != nil {
+			return err
+		}
+		sum.Add(sum, amt.Rat())
+	}
+	if sum.Cmp(closing.Rat()) != 0 {
+		return fmt.Errorf("statement %s: opening balance %s plus entries is %s, closing balance is %s",
+			r.Id, opening, (*types.Amount)(sum), closing)
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package camtimport
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func TestStatement(t *testing.T) {
+	doc, err := ReadFile("testdata/camt053.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Statements) != 1 {
+		t.Fatalf("got %d statements, expected 1", len(doc.Statements))
+	}
+	st := &doc.Statements[0]
+	if st.Account.IBAN != "DE89370400440532013000" || len(st.Entries) != 4 {
+		t.Fatalf("unexpected statement %+v", st)
+	}
+	if err := st.Check(); err != nil {
+		t.Error(err)
+	}
+
+	bank := &types.Account{Id: "bank", Name: "/Bank", Type: "BANK", Unit: "EUR"}
+	imb := &types.Account{Id: "imb", Name: "/Imbalance-EUR", Type: "BANK", Unit: "EUR"}
+	trns, err := st.Import(bank, imb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trns) != 3 {
+		t.Fatalf("got %d transactions, expected 3 (pending entry skipped)", len(trns))
+	}
+	salary := trns[0]
+	if salary.Description != "ACME GmbH" || salary.ExternalId != "REF-0001" ||
+		!strings.Contains(salary.Notes, "Gehalt Maerz 2013") || !strings.Contains(salary.Notes, "DE02100100100006820101") {
+		t.Errorf("unexpected transaction %+v", salary)
+	}
+	bill := trns[1]
+	if bill.Date.Format("2006-01-02") != "2013-03-02" || !strings.Contains(bill.Notes, "Booked: 2013-03-04") ||
+		!strings.Contains(bill.Notes, "RF18539007547034") || bill.Flows[0].Price.String() != "-55.50" {
+		t.Errorf("unexpected transaction %+v", bill)
+	}
+	batch := trns[2]
+	if len(batch.Flows) != 3 || batch.Flows[1].Price.String() != "60.00" || !strings.Contains(batch.Flows[2].Memo, "Dinner") {
+		t.Errorf("unexpected batch transaction %+v", batch.Flows)
+	}
+
+	// Check balances against a book holding the opening balance.
+	book := &types.Book{
+		Accounts:     map[types.GUID]*types.Account{"bank": bank, "imb": imb},
+		Transactions: make(map[types.GUID]*types.Transaction),
+	}
+	opening := &types.Transaction{Id: "open", Date: time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)}
+	opening.Flows = []types.Flow{
+		{Account: bank, Price: (*types.Amount)(parseRat("1000"))},
+		{Account: imb, Price: (*types.Amount)(parseRat("-1000"))},
+	}
+	book.Transactions[opening.Id] = opening
+	book.Recompute()
+	if err := st.CheckBook(book, bank); err == nil || !strings.Contains(err.Error(), "closing balance") {
+		t.Errorf("expected closing balance mismatch, got %v", err)
+	}
+	for _, trn := range trns {
+		book.Transactions[trn.Id] = trn
+	}
+	book.Recompute()
+	if err := st.CheckBook(book, bank); err != nil {
+		t.Error(err)
+	}
+	// The bill, valued on 2013-03-02, is the first entry in the book.
+	st.Entries[0].BookingDate = Date{Date: "2013-03-03"}
+	if err := st.CheckBook(book, bank); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNotification(t *testing.T) {
+	doc, err := ReadFile("testdata/camt054.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Notifications) != 1 {
+		t.Fatalf("got %d notifications, expected 1", len(doc.Notifications))
+	}
+	bank := &types.Account{Name: "/Bank", Unit: "EUR"}
+	trns, err := doc.Notifications[0].Import(bank, bank)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trns) != 1 || trns[0].Description != "Carol" || trns[0].ExternalId != "NTF-REF-1" ||
+		!strings.Contains(trns[0].Notes, "E2E-42") {
+		t.Errorf("unexpected transactions %+v", trns)
+	}
+}
+
+func parseRat(s string) *big.Rat {
+	x, ok := new(big.Rat).SetString(s)
+	if !ok {
+		panic(s)
+	}
+	return x
+}
@@ -0,0 +1,179 @@
+package camtimport
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Counterparty returns the name and IBAN of the other party of a
+// transaction, given the direction of the entry.
+func (d *Detail) Counterparty(ind string) (name, iban string) {
+	if ind == "CRDT" {
+		return firstNonEmpty(d.Debtor, d.DebtorParty), d.DebtorIBAN
+	}
+	return firstNonEmpty(d.Creditor, d.CreditorPty), d.CreditorIBAN
+}
+
+// Remittance returns the remittance information of a transaction.
+func (d *Detail) Remittance() string {
+	parts := append([]string(nil), d.Unstructured...)
+	parts = append(parts, d.Reference...)
+	return strings.Join(parts, " ")
+}
+
+// Date returns the date of an entry in the book: its value date,
+// or else its booking date.
+func (e *Entry) Date() Date {
+	if e.ValueDate == (Date{}) {
+		return e.BookingDate
+	}
+	return e.ValueDate
+}
+
+// Import converts the booked entries of the report into transactions
+// of acct balanced against counter. Entries with several transaction
+// details are split into one counter flow per detail.
+func (r *Report) Import(acct, counter *types.Account) ([]*types.Transaction, error) {
+	now := time.Now()
+	var trns []*types.Transaction
+	for _, e := range r.Entries {
+		if st := e.Status.String(); st != "" && st != "BOOK" {
+			continue
+		}
+		amount, err := signed(e.Amount, e.CdtDbtInd)
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %s", e.Ref, err)
+		}
+		date, err := e.Date().Time()
+		if err != nil {
+			return nil, fmt.Errorf("entry %s: %s", e.Ref, err)
+		}
+		trn := &types.Transaction{
+			Id:          types.NewGUID(),
+			Date:        date,
+			Stamp:       now,
+			Currency:    firstNonEmpty(e.Amount.Currency, r.Account.Currency, acct.Unit),
+			Description: e.Info,
+			ExternalId:  firstNonEmpty(e.ServicerRef, e.Ref),
+		}
+		var notes []string
+		if booked, err := e.BookingDate.Time(); err == nil && !booked.Equal(date) {
+			notes = append(notes, "Booked: "+booked.Format("2006-01-02"))
+		}
+		trn.Flows = append(trn.Flows, newFlow(trn, acct, amount, ""))
+
+		rest := new(big.Rat).Neg(amount.Rat())
+		for i := range e.Details {
+			d := &e.Details[i]
+			name, iban := d.Counterparty(e.CdtDbtInd)
+			if i == 0 {
+				if name != "" {
+					trn.Description = name
+				}
+				if trn.ExternalId == "" {
+					trn.ExternalId = firstNonEmpty(d.ServicerRef, d.EndToEndId)
+				}
+			}
+			var memo []string
+			if iban != "" {
+				memo = append(memo, iban)
+			}
+			if rem := d.Remittance(); rem != "" {
+				memo = append(memo, rem)
+			}
+			if d.EndToEndId != "" && d.EndToEndId != "NOTPROVIDED" {
+				memo = append(memo, "E2E "+d.EndToEndId)
+			}
+			if len(e.Details) == 1 || d.Amount == nil {
+				notes = append(notes, memo...)
+				continue
+			}
+			// Batch entry: one counter flow per detail.
+			x, err := signed(*d.Amount, firstNonEmpty(d.CdtDbtInd, e.CdtDbtInd))
+			if err != nil {
+				return nil, fmt.Errorf("entry %s: %s", e.Ref, err)
+			}
+			x.Rat().Neg(x.Rat())
+			rest.Sub(rest, x.Rat())
+			trn.Flows = append(trn.Flows, newFlow(trn, counter, x, strings.Join(memo, " ")))
+		}
+		if rest.Sign() != 0 || len(trn.Flows) == 1 {
+			trn.Flows = append(trn.Flows, newFlow(trn, counter, (*types.Amount)(rest), ""))
+		}
+		trn.Notes = strings.Join(notes, "\n")
+		if trn.Description == "" {
+			trn.Description = trn.Notes
+		}
+		trns = append(trns, trn)
+	}
+	return trns, nil
+}
+
+// CheckBook compares the opening and closing balances of the report
+// with the balance of acct in book, which must have been recomputed.
+// The opening balance is compared with the flows before the first
+// entry date, as in Import, and the closing balance with flows up to
+// the closing date.
+func (r *Report) CheckBook(book *types.Book, acct *types.Account) error {
+	opening, _, err := r.Opening()
+	if err != nil {
+		return err
+	}
+	closing, closingDate, err := r.Closing()
+	if err != nil {
+		return err
+	}
+	var start time.Time
+	for _, e := range r.Entries {
+		if t, err := e.Date().Time(); err == nil && (start.IsZero() || t.Before(start)) {
+			start = t
+		}
+	}
+	if start.IsZero() {
+		start = closingDate
+	}
+	end := closingDate.AddDate(0, 0, 1)
+	before, upto := new(big.Rat), new(big.Rat)
+	for _, f := range book.Flows[acct] {
+		d := f.Parent.Date
+		if d.Before(start) {
+			before.Add(before, f.Units().Rat())
+		}
+		if d.Before(end) {
+			upto.Add(upto, f.Units().Rat())
+		}
+	}
+	if opening != nil && before.Cmp(opening.Rat()) != 0 {
+		return fmt.Errorf("statement %s: opening balance is %s, book has %s on %s",
+			r.Id, opening, (*types.Amount)(before), start.Format("2006-01-02"))
+	}
+	if closing != nil && upto.Cmp(closing.Rat()) != 0 {
+		return fmt.Errorf("statement %s: closing balance is %s, book has %s on %s",
+			r.Id, closing, (*types.Amount)(upto), closingDate.Format("2006-01-02"))
+	}
+	return nil
+}
+
+func newFlow(trn *types.Transaction, acct *types.Account, x *types.Amount, memo string) types.Flow {
+	return types.Flow{
+		Id:       types.NewGUID(),
+		Account:  acct,
+		Memo:     memo,
+		Price:    new(types.Amount).SetRat(x.Rat()),
+		Quantity: new(types.Amount).SetRat(x.Rat()),
+		Parent:   trn,
+	}
+}
+
+func firstNonEmpty(s ...string) string {
+	for _, x := range s {
+		if x != "" {
+			return x
+		}
+	}
+	return ""
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/remyoudompheng/gocash/gui"
+	"github.com/remyoudompheng/gocash/rules"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// categorizeOptions controls the assignment of counter-accounts
+// to uncategorized transactions.
+type categorizeOptions struct {
+	rules   string  // Path to a rule file.
+	learn   bool    // Guess from past transactions.
+	minProb float64 // Minimum probability of guesses.
+}
+
+func (o *categorizeOptions) register(flags *flag.FlagSet) {
+	flags.StringVar(&o.rules, "rules", "", "path to categorization rules (JSON)")
+	flags.BoolVar(&o.learn, "learn", false, "categorize from similar past transactions")
+	flags.Float64Var(&o.minProb, "minprob", 0.9, "minimum probability of learnt categories")
+}
+
+// categorizer returns a function categorizing transactions of acct,
+// and describing how they were categorized. The book must have been
+// recomputed.
+func (o *categorizeOptions) categorizer(book *types.Book, acct *types.Account) func(*types.Transaction) string {
+	var set *rules.Set
+	if o.rules != "" {
+		var err error
+		set, err = rules.Load(o.rules)
+		if err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+	var learnt *rules.Classifier
+	if o.learn {
+		learnt = rules.Train(book, acct)
+	}
+	return func(trn *types.Transaction) string {
+		if set != nil {
+			if r := set.Apply(book, acct, trn); r != nil {
+				return fmt.Sprintf(" [%s: %s]", r.Name, r.Counter)
+			}
+		}
+		if learnt != nil {
+			if a := learnt.Apply(acct, trn, o.minProb); a != nil {
+				return fmt.Sprintf(" [learnt: %s]", a.Name)
+			}
+		}
+		return ""
+	}
+}
+
+func cmdCategorize(args []string) {
+	flags := flag.NewFlagSet("categorize", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	acctName := flags.String("account", "", "name of the account to categorize")
+	httpAddr := flags.String("http", "", "address of HTTP server to browse the categorized book")
+	var opts categorizeOptions
+	opts.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gocash categorize -f book.xml -account NAME [-rules rules.json] [-learn]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *acctName == "" || opts.rules == "" && !opts.learn {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	book := loadBook(*filename)
+	acct := book.AccountByName(*acctName)
+	if acct == nil {
+		log.Fatalf("ERROR: no such account %q", *acctName)
+	}
+	categorize := opts.categorizer(book, acct)
+	n := 0
+	for _, f := range book.Flows[acct] {
+		trn := f.Parent
+		if how := categorize(trn); how != "" {
+			fmt.Printf("%s %10s %s%s\n", trn.Date.Format("2006-01-02"), f.Price, trn.Description, how)
+			n++
+		}
+	}
+	log.Printf("%d transactions categorized", n)
+	book.Recompute()
+
+	if *httpAddr != "" {
+		if err := gui.StartServer(*httpAddr, book, nil); err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+}
+
+func cmdProposeRules(args []string) {
+	flags := flag.NewFlagSet("propose-rules", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	acctName := flags.String("account", "", "name of the account to learn from")
+	rulesFile := flags.String("rules", "", "path to existing categorization rules (JSON)")
+	minCount := flags.Int("min", 3, "minimum number of similar transactions")
+	write := flags.Bool("w", false, "append proposed rules to the rules file")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gocash propose-rules -f book.xml -account NAME [-rules rules.json -w]\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *acctName == "" || *write && *rulesFile == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	book := loadBook(*filename)
+	acct := book.AccountByName(*acctName)
+	if acct == nil {
+		log.Fatalf("ERROR: no such account %q", *acctName)
+	}
+	set := new(rules.Set)
+	if *rulesFile != "" {
+		var err error
+		set, err = rules.Load(*rulesFile)
+		if err != nil && !(os.IsNotExist(err) && *write) {
+			log.Fatalf("ERROR: %s", err)
+		}
+		if set == nil {
+			set = new(rules.Set)
+		}
+	}
+	props := rules.Propose(book, acct, set, *minCount)
+	for _, r := range props {
+		fmt.Printf("%-40s %-32s -> %s\n", r.Name, r.Description, r.Counter)
+	}
+	log.Printf("%d rules proposed", len(props))
+	if *write && len(props) > 0 {
+		set.Rules = append(set.Rules, props...)
+		if err := set.Save(*rulesFile); err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+}
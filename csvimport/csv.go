@@ -0,0 +1,231 @@
+package csvimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Row is a parsed line of a CSV statement.
+type Row struct {
+	Line        int
+	Date        time.Time
+	Description string
+	Memo        string
+	Number      string
+	Id          string
+	Amount      *types.Amount // Signed from the account holder's point of view.
+	Err         error         // Why the row could not be parsed.
+}
+
+// Read parses a CSV statement. Rows that cannot be parsed are
+// returned with a non-nil Err.
+func (p *Profile) Read(r io.Reader) ([]Row, error) {
+	if err := p.Check(); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	switch p.Encoding {
+	case "latin1", "iso-8859-1":
+		data = decode8bit(data, nil)
+	case "windows-1252":
+		data = decode8bit(data, &cp1252)
+	}
+
+	cr := csv.NewReader(bufio.NewReader(bytes.NewReader(data)))
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	if p.Delimiter != "" {
+		cr.Comma, _ = utf8.DecodeRuneInString(p.Delimiter)
+	}
+	var rows []Row
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, err
+		}
+		if line <= p.SkipRows || isBlank(rec) {
+			continue
+		}
+		rows = append(rows, p.parseRow(line, rec))
+	}
+	return rows, nil
+}
+
+// ReadFile parses the named CSV statement.
+func (p *Profile) ReadFile(name string) ([]Row, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return p.Read(f)
+}
+
+func (p *Profile) parseRow(line int, rec []string) (row Row) {
+	row.Line = line
+	col := func(n int) string {
+		if n <= 0 || n > len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[n-1])
+	}
+	layout := p.DateLayout
+	if layout == "" {
+		layout = "2006-01-02"
+	}
+	var err error
+	row.Date, err = time.Parse(layout, col(p.DateColumn))
+	if err != nil {
+		row.Err = fmt.Errorf("invalid date %q", col(p.DateColumn))
+		return row
+	}
+	row.Description = col(p.DescriptionColumn)
+	row.Memo = col(p.MemoColumn)
+	row.Number = col(p.NumberColumn)
+	row.Id = col(p.IdColumn)
+
+	amount := new(big.Rat)
+	if p.AmountColumn > 0 {
+		x, err := p.parseAmount(col(p.AmountColumn))
+		if err != nil {
+			row.Err = err
+			return row
+		}
+		amount.Set(x)
+	} else {
+		debit, err := p.parseAmount(col(p.DebitColumn))
+		if err != nil {
+			row.Err = err
+			return row
+		}
+		credit, err := p.parseAmount(col(p.CreditColumn))
+		if err != nil {
+			row.Err = err
+			return row
+		}
+		// Debit columns may or may not carry a minus sign.
+		amount.Sub(credit, debit.Abs(debit))
+	}
+	if p.Negate {
+		amount.Neg(amount)
+	}
+	row.Amount = (*types.Amount)(amount)
+	return row
+}
+
+// parseAmount parses a decimal number, ignoring thousands separators
+// and currency symbols. An empty string is zero.
+func (p *Profile) parseAmount(s string) (*big.Rat, error) {
+	orig := s
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= '0' && r <= '9', r == '-', r == '+':
+			return r
+		case r == ',' && p.DecimalComma, r == '.' && !p.DecimalComma:
+			return '.'
+		case r == '(' || r == ')':
+			// Accounting notation for negative amounts.
+			return '-'
+		}
+		return -1
+	}, s)
+	if strings.HasSuffix(s, "-") {
+		// Trailing sign, or closing parenthesis.
+		s = "-" + strings.Trim(s, "-")
+	}
+	if s == "" {
+		return new(big.Rat), nil
+	}
+	x, ok := new(big.Rat).SetString(strings.TrimPrefix(s, "+"))
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", orig)
+	}
+	return x, nil
+}
+
+func isBlank(rec []string) bool {
+	for _, s := range rec {
+		if strings.TrimSpace(s) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// cp1252 maps bytes 0x80-0x9f of Windows-1252 to Unicode.
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8d, 'Ž', 0x8f,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9d, 'ž', 'Ÿ',
+}
+
+// decode8bit converts Latin-1 text, with optional Windows-1252
+// extensions, to UTF-8.
+func decode8bit(b []byte, ext *[32]rune) []byte {
+	buf := make([]byte, 0, len(b))
+	for _, c := range b {
+		r := rune(c)
+		if ext != nil && c >= 0x80 && c < 0xa0 {
+			r = ext[c-0x80]
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	return buf
+}
+
+// Import converts parsed rows into transactions of acct balanced
+// against counter. Rows with errors are skipped.
+func Import(rows []Row, acct, counter *types.Account) []*types.Transaction {
+	now := time.Now()
+	var trns []*types.Transaction
+	for _, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		trn := &types.Transaction{
+			Id:          types.NewGUID(),
+			Date:        row.Date,
+			Stamp:       now,
+			Currency:    acct.Unit,
+			Description: row.Description,
+			Number:      row.Number,
+			ExternalId:  row.Id,
+		}
+		neg := new(big.Rat).Neg(row.Amount.Rat())
+		trn.Flows = []types.Flow{
+			{
+				Id:       types.NewGUID(),
+				Account:  acct,
+				Memo:     row.Memo,
+				Price:    new(types.Amount).SetRat(row.Amount.Rat()),
+				Quantity: new(types.Amount).SetRat(row.Amount.Rat()),
+				Parent:   trn,
+			},
+			{
+				Id:       types.NewGUID(),
+				Account:  counter,
+				Price:    new(types.Amount).SetRat(neg),
+				Quantity: new(types.Amount).SetRat(neg),
+				Parent:   trn,
+			},
+		}
+		trns = append(trns, trn)
+	}
+	return trns
+}
@@ -0,0 +1,84 @@
+package csvimport
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func TestReadDebitCredit(t *testing.T) {
+	p, err := LoadProfile("testdata/debitcredit.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := p.ReadFile("testdata/debitcredit.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("got %d rows, expected 4", len(rows))
+	}
+	expected := []struct{ date, desc, amount string }{
+		{"2013-03-05", "CB CAFÉ DU COIN", "-12.50"},
+		{"2013-03-06", "VIR SALAIRE", "1500.00"},
+		{"2013-03-07", "PRLV EDF", "-45.10"},
+	}
+	for i, exp := range expected {
+		row := rows[i]
+		if row.Err != nil {
+			t.Errorf("row %d: %s", row.Line, row.Err)
+			continue
+		}
+		if d := row.Date.Format("2006-01-02"); d != exp.date || row.Description != exp.desc || row.Amount.String() != exp.amount {
+			t.Errorf("row %d: got %s %q %s, expected %v", row.Line, d, row.Description, row.Amount, exp)
+		}
+	}
+	if rows[3].Err == nil {
+		t.Errorf("row %d: expected invalid date error", rows[3].Line)
+	}
+}
+
+func TestReadSigned(t *testing.T) {
+	p := &Profile{
+		SkipRows:          1,
+		DateColumn:        2,
+		DateLayout:        "1/2/2006",
+		DescriptionColumn: 3,
+		AmountColumn:      4,
+		Negate:            true,
+	}
+	const input = "Id,Date,Payee,Amount\n" +
+		"1,3/5/2013,\"Store, Inc.\",\"$1,234.56\"\n" +
+		"2,3/6/2013,Refund,-20\n"
+	rows, err := p.Read(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].Description != "Store, Inc." ||
+		rows[0].Amount.String() != "-1234.56" || rows[1].Amount.String() != "20.00" {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+
+	bank := &types.Account{Name: "/Bank", Unit: "USD"}
+	imb := &types.Account{Name: "/Imbalance-USD", Unit: "USD"}
+	trns := Import(rows, bank, imb)
+	if len(trns) != 2 {
+		t.Fatalf("got %d transactions", len(trns))
+	}
+	for _, trn := range trns {
+		sum := new(types.Amount).Add(trn.Flows[0].Price).Add(trn.Flows[1].Price)
+		if sum.Rat().Sign() != 0 || trn.Flows[1].Account != imb {
+			t.Errorf("transaction %q is not balanced against counter account", trn.Description)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := (&Profile{AmountColumn: 2}).Check(); err == nil {
+		t.Errorf("expected error for missing date column")
+	}
+	if err := (&Profile{DateColumn: 1, AmountColumn: 2, Encoding: "utf-16"}).Check(); err == nil {
+		t.Errorf("expected error for unsupported encoding")
+	}
+}
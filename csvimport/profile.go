@@ -0,0 +1,74 @@
+// Package csvimport implements reading of bank statements exported
+// as CSV files, driven by a profile describing their layout.
+package csvimport
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// A Profile describes the layout of CSV files of a bank.
+// Column numbers start at 1; zero means the column is absent.
+type Profile struct {
+	Name      string
+	Delimiter string // Field delimiter (default ",").
+	SkipRows  int    // Number of header rows to skip.
+	Encoding  string // utf-8 (default), latin1 or windows-1252.
+
+	DateColumn        int
+	DateLayout        string // A Go time layout (default "2006-01-02").
+	DescriptionColumn int
+	MemoColumn        int
+	NumberColumn      int
+	IdColumn          int // A unique identifier of the entry.
+
+	// Either AmountColumn holds a signed amount, or DebitColumn
+	// and CreditColumn hold unsigned amounts.
+	AmountColumn int
+	DebitColumn  int
+	CreditColumn int
+	DecimalComma bool // Amounts use a decimal comma.
+	Negate       bool // Amounts are signed from the bank's point of view.
+}
+
+// LoadProfile reads a profile from the named JSON file.
+func LoadProfile(name string) (*Profile, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	p := new(Profile)
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %s", name, err)
+	}
+	return p, p.Check()
+}
+
+// Save writes the profile to the named file.
+func (p *Profile) Save(name string) error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// Check verifies that the profile is consistent.
+func (p *Profile) Check() error {
+	if p.DateColumn <= 0 {
+		return fmt.Errorf("profile %q: missing date column", p.Name)
+	}
+	if p.AmountColumn <= 0 && p.DebitColumn <= 0 && p.CreditColumn <= 0 {
+		return fmt.Errorf("profile %q: missing amount columns", p.Name)
+	}
+	if len([]rune(p.Delimiter)) > 1 {
+		return fmt.Errorf("profile %q: invalid delimiter %q", p.Name, p.Delimiter)
+	}
+	switch p.Encoding {
+	case "", "utf-8", "latin1", "iso-8859-1", "windows-1252":
+	default:
+		return fmt.Errorf("profile %q: unsupported encoding %q", p.Name, p.Encoding)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/remyoudompheng/gocash/beancount"
+	"github.com/remyoudompheng/gocash/ledger"
+	"github.com/remyoudompheng/gocash/qif"
+	"github.com/remyoudompheng/gocash/register"
+)
+
+// createOutput opens the named output file, or standard output
+// if name is empty.
+func createOutput(name string) io.WriteCloser {
+	if name == "" {
+		return os.Stdout
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+	return f
+}
+
+func cmdExportQIF(args []string) {
+	flags := flag.NewFlagSet("export-qif", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	acctName := flags.String("account", "", "export only the named account")
+	output := flags.String("o", "", "output file (default: standard output)")
+	flags.Parse(args)
+
+	book := loadBook(*filename)
+	out := createOutput(*output)
+	var err error
+	if *acctName != "" {
+		acct := book.AccountByName(*acctName)
+		if acct == nil {
+			log.Fatalf("ERROR: no such account %q", *acctName)
+		}
+		err = qif.WriteAccount(out, book, acct)
+	} else {
+		err = qif.Write(out, book)
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+}
+
+func cmdExportLedger(args []string) {
+	flags := flag.NewFlagSet("export-ledger", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	output := flags.String("o", "", "output file (default: standard output)")
+	flags.Parse(args)
+
+	book := loadBook(*filename)
+	out := createOutput(*output)
+	err := ledger.Write(out, book)
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+}
+
+func cmdExportBeancount(args []string) {
+	flags := flag.NewFlagSet("export-beancount", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	output := flags.String("o", "", "output file (default: standard output)")
+	flags.Parse(args)
+
+	book := loadBook(*filename)
+	out := createOutput(*output)
+	err := beancount.Write(out, book)
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+}
+
+func cmdExportRegister(args []string) {
+	flags := flag.NewFlagSet("export-register", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	acctName := flags.String("account", "", "name of the exported account")
+	format := flags.String("format", "", "output format: csv or xlsx (default: from output file name, or csv)")
+	from := flags.String("from", "", "first date of exported flows (YYYY-MM-DD)")
+	to := flags.String("to", "", "last date of exported flows (YYYY-MM-DD)")
+	text := flags.String("match", "", "export only flows whose description or memo contains this text")
+	output := flags.String("o", "", "output file (default: standard output)")
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = "csv"
+		if filepath.Ext(*output) == ".xlsx" {
+			*format = "xlsx"
+		}
+	}
+	if *format != "csv" && *format != "xlsx" {
+		log.Fatalf("ERROR: unknown format %q", *format)
+	}
+	flt := register.Filter{Text: *text}
+	var err error
+	if *from != "" {
+		if flt.From, err = time.Parse("2006-01-02", *from); err != nil {
+			log.Fatalf("ERROR: invalid start date: %s", err)
+		}
+	}
+	if *to != "" {
+		if flt.To, err = time.Parse("2006-01-02", *to); err != nil {
+			log.Fatalf("ERROR: invalid end date: %s", err)
+		}
+	}
+
+	book := loadBook(*filename)
+	acct := book.AccountByName(*acctName)
+	if acct == nil {
+		log.Fatalf("ERROR: no such account %q", *acctName)
+	}
+	lines := register.Lines(book, acct, flt)
+	out := createOutput(*output)
+	if *format == "xlsx" {
+		err = register.WriteXLSX(out, acct.Name, lines)
+	} else {
+		err = register.WriteCSV(out, lines)
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+}
@@ -0,0 +1,215 @@
+package gui
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// An accountForm holds the fields of the account editor.
+type accountForm struct {
+	Id          types.GUID // Empty for a new account.
+	Name        string     // The last component of the account name.
+	Parent      string     // The full name of the parent, empty for top level.
+	Type        string
+	Unit        string
+	Description string
+	Placeholder bool
+	Hidden      bool
+	Error       string
+
+	// Choices.
+	Parents     []string // Possible parents.
+	Targets     []string // Possible targets of flows when deleting.
+	Commodities []string
+	Flows       int // Number of flows of the account.
+}
+
+// pageEditAccount shows the account editor for the account given by
+// the name form value, or for a new account under the account given
+// by the parent form value.
+func pageEditAccount(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
+	var form accountForm
+	if name := req.Form.Get("name"); name != "" {
+		acct := book.AccountByName(name)
+		if acct == nil {
+			return fmt.Errorf("no such account: %q", name)
+		}
+		form = newAccountForm(book, acct)
+	} else {
+		form.Parent = req.Form.Get("parent")
+		form.Unit = book.DefaultCurrency()
+		if parent := book.AccountByName(form.Parent); parent != nil {
+			form.Type, form.Unit = parent.Type, parent.Unit
+		}
+	}
+	return renderAccountForm(book, w, &form)
+}
+
+// newAccountForm returns the editor fields of an existing account.
+func newAccountForm(book *types.Book, acct *types.Account) accountForm {
+	form := accountForm{
+		Id:          acct.Id,
+		Name:        path.Base(acct.Name),
+		Type:        acct.Type,
+		Unit:        acct.Unit,
+		Description: acct.Description,
+		Placeholder: acct.Placeholder,
+		Hidden:      acct.Hidden,
+	}
+	if parent := book.Parent(acct); parent != nil && parent.Type != "ROOT" {
+		form.Parent = parent.Name
+	}
+	return form
+}
+
+func renderAccountForm(book *types.Book, w io.Writer, form *accountForm) error {
+	acct := book.Accounts[form.Id]
+	for _, a := range book.Accounts {
+		if a.Type == "ROOT" || acct != nil && (a == acct || strings.HasPrefix(a.Name, acct.Name+"/")) {
+			continue
+		}
+		form.Parents = append(form.Parents, a.Name)
+		if acct != nil && a.Unit == acct.Unit && !a.Placeholder {
+			form.Targets = append(form.Targets, a.Name)
+		}
+	}
+	sort.Strings(form.Parents)
+	sort.Strings(form.Targets)
+	for _, c := range book.Commodities {
+		if c.Space != "template" {
+			form.Commodities = append(form.Commodities, c.Id)
+		}
+	}
+	title := "New account"
+	if acct != nil {
+		title = "Edit account " + acct.Name
+		form.Flows = len(book.Flows[acct])
+	}
+	return accountEditTpl.Execute(w, templateData{
+		Title:       title,
+		Book:        book,
+		Account:     acct,
+		AccountForm: form,
+	})
+}
+
+// pageSaveAccount creates or updates an account from the posted
+// editor form.
+func pageSaveAccount(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	book := hist.Book
+	req.ParseForm()
+	form := accountForm{
+		Id:          types.GUID(req.PostForm.Get("id")),
+		Name:        strings.TrimSpace(req.PostForm.Get("name")),
+		Parent:      req.PostForm.Get("parent"),
+		Type:        req.PostForm.Get("type"),
+		Unit:        strings.TrimSpace(req.PostForm.Get("unit")),
+		Description: strings.TrimSpace(req.PostForm.Get("description")),
+		Placeholder: req.PostForm.Get("placeholder") != "",
+		Hidden:      req.PostForm.Get("hidden") != "",
+	}
+	acct, err := form.saveHistory(hist)
+	if err != nil {
+		form.Error = err.Error()
+		return renderAccountForm(book, w, &form)
+	}
+	return redirect("/account/?name=" + url.QueryEscape(acct.Name))
+}
+
+// saveHistory saves the account through hist so that it can be undone.
+func (form *accountForm) saveHistory(hist *history.History) (acct *types.Account, err error) {
+	desc := "Add account "
+	if old := hist.Book.Accounts[form.Id]; old != nil {
+		desc = "Edit account " + old.Name + " as "
+	}
+	if form.Parent != "" {
+		desc += form.Parent
+	}
+	desc += "/" + form.Name
+	err = hist.Do(history.EditAccount(desc, func(book *types.Book) (err error) {
+		acct, err = form.save(book)
+		return err
+	}))
+	return acct, err
+}
+
+func (form *accountForm) save(book *types.Book) (*types.Account, error) {
+	var parent *types.Account
+	if form.Parent != "" {
+		parent = book.AccountByName(form.Parent)
+		if parent == nil {
+			return nil, fmt.Errorf("no such account: %q", form.Parent)
+		}
+	}
+	if form.Id == "" {
+		acct, err := book.AddAccount(parent, form.Name, form.Type, form.Unit, form.Description)
+		if err != nil {
+			return nil, err
+		}
+		acct.Placeholder, acct.Hidden = form.Placeholder, form.Hidden
+		return acct, nil
+	}
+
+	acct := book.Accounts[form.Id]
+	if acct == nil {
+		return nil, fmt.Errorf("no such account: %q", form.Id)
+	}
+	if acct.Unit != form.Unit && len(book.Flows[acct]) > 0 {
+		return nil, fmt.Errorf("cannot change the commodity of account %s which has flows", acct.Name)
+	}
+	if form.Type == "" || form.Type == "ROOT" {
+		return nil, fmt.Errorf("invalid account type %q", form.Type)
+	}
+	if err := book.MoveAccount(acct, parent, form.Name); err != nil {
+		return nil, err
+	}
+	acct.Type = form.Type
+	acct.Unit = form.Unit
+	acct.Description = form.Description
+	acct.Placeholder, acct.Hidden = form.Placeholder, form.Hidden
+	return acct, nil
+}
+
+// pageDeleteAccount deletes the account given by the posted name form
+// value, after assigning its flows to the account given by the
+// reassign form value.
+func pageDeleteAccount(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	book := hist.Book
+	req.ParseForm()
+	name := req.PostForm.Get("name")
+	acct := book.AccountByName(name)
+	if acct == nil {
+		return fmt.Errorf("no such account: %q", name)
+	}
+	var target *types.Account
+	if s := req.PostForm.Get("reassign"); s != "" {
+		target = book.AccountByName(s)
+		if target == nil {
+			return fmt.Errorf("no such account: %q", s)
+		}
+	}
+	if err := hist.Do(history.DeleteAccount(acct, target)); err != nil {
+		form := newAccountForm(book, acct)
+		form.Error = err.Error()
+		return renderAccountForm(book, w, &form)
+	}
+	if target != nil {
+		return redirect("/account/?name=" + url.QueryEscape(target.Name))
+	}
+	return redirect("/")
+}
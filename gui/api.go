@@ -0,0 +1,767 @@
+package gui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/reconcile"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// The JSON API is served under apiPrefix. Amounts are encoded as
+// exact fractions ("367/100") and accept decimal numbers; dates are
+// encoded as YYYY-MM-DD and accept RFC 3339 times.
+const apiPrefix = "/api/v1/"
+
+// An apiAccount is the JSON representation of an account. Name is
+// the full slash separated name, and Balance the sum of its flows
+// in the account commodity.
+type apiAccount struct {
+	Id            types.GUID
+	Name          string
+	Type          string
+	Unit          string
+	Description   string
+	Placeholder   bool
+	Hidden        bool
+	LastReconcile string       `json:",omitempty"`
+	Parent        types.GUID   `json:",omitempty"`
+	Children      []types.GUID `json:",omitempty"`
+	Balance       *types.Amount
+}
+
+type apiTransaction struct {
+	Id          types.GUID
+	Date        string
+	Currency    string
+	Description string
+	Notes       string
+	Number      string
+	ExternalId  string
+	VoidReason  string `json:",omitempty"` // Set by the void action only.
+	VoidTime    string `json:",omitempty"`
+	Flows       []apiFlow
+}
+
+type apiFlow struct {
+	Id             types.GUID
+	Account        types.GUID
+	AccountName    string // Used to find the account if Account is empty.
+	Memo           string
+	Price          *types.Amount // Value in the transaction currency.
+	Quantity       *types.Amount // Amount in the account commodity.
+	State          types.ReconcileState
+	ReconciledTime string        `json:",omitempty"`
+	VoidPrice      *types.Amount `json:",omitempty"` // Amounts before voiding.
+	VoidQuantity   *types.Amount `json:",omitempty"`
+}
+
+// An apiHistory is the audit log of the book, with the descriptions
+// of the edits that can be undone and redone.
+type apiHistory struct {
+	Log  []history.Record
+	Undo string `json:",omitempty"`
+	Redo string `json:",omitempty"`
+}
+
+func newAPIHistory(hist *history.History) apiHistory {
+	h := apiHistory{Log: hist.Log()}
+	if e := hist.NextUndo(); e != nil {
+		h.Undo = e.Description
+	}
+	if e := hist.NextRedo(); e != nil {
+		h.Redo = e.Description
+	}
+	return h
+}
+
+// An apiVoidRequest is the body of a request to void a transaction.
+type apiVoidRequest struct {
+	Reason string
+}
+
+// An apiLine is a flow of an account register with its transaction.
+type apiLine struct {
+	Transaction types.GUID
+	Date        string
+	Description string
+	Flow        apiFlow
+	Balance     *types.Amount
+}
+
+// An apiPage is a page of a paginated list.
+type apiPage struct {
+	Total  int
+	Offset int
+	Items  interface{}
+}
+
+type apiError struct {
+	Code    int
+	Message string
+}
+
+func (e *apiError) Error() string { return e.Message }
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{http.StatusBadRequest, fmt.Sprintf(format, args...)}
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &apiError{http.StatusNotFound, fmt.Sprintf(format, args...)}
+}
+
+// apiHandler serves the JSON API over the book of s. GET requests
+// hold a read lock, and other requests the write lock.
+func apiHandler(s *store) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			log.Printf("%s %s from %s", req.Method, req.URL, req.RemoteAddr)
+			// The body is read before taking the lock, so that slow
+			// clients do not hold it.
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				log.Printf("ERROR: %s", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.ParseForm()
+			lock := s.write
+			if req.Method == "GET" {
+				lock = s.read
+			}
+			// The response is encoded while holding the lock, since
+			// it refers to the book.
+			var code int
+			resp := new(bytes.Buffer)
+			lock(func(hist *history.History) error {
+				var v interface{}
+				var err error
+				code, v, err = serveAPI(hist, req)
+				if err != nil {
+					code = http.StatusInternalServerError
+					if e, ok := err.(*apiError); ok {
+						code = e.Code
+					}
+					log.Printf("ERROR: %s", err)
+					v = map[string]string{"Error": err.Error()}
+				}
+				if v != nil {
+					enc := json.NewEncoder(resp)
+					enc.SetIndent("", "  ")
+					enc.Encode(v)
+				}
+				return nil
+			})
+			w.Header().Set("Content-Type", "application/json; charset=utf-8")
+			w.WriteHeader(code)
+			w.Write(resp.Bytes())
+		})
+}
+
+// serveAPI dispatches an API request and returns the HTTP status
+// and the value to encode in the response.
+func serveAPI(hist *history.History, req *http.Request) (int, interface{}, error) {
+	book := hist.Book
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(req.URL.Path, apiPrefix), "/"), "/")
+	var id types.GUID
+	if len(parts) > 1 {
+		id = types.GUID(parts[1])
+	}
+	method := req.Method
+	switch {
+	case parts[0] == "book" && len(parts) == 1 && method == "GET":
+		return http.StatusOK, map[string]interface{}{
+			"DefaultCurrency": book.DefaultCurrency(),
+			"Accounts":        len(book.Accounts),
+			"Transactions":    len(book.Transactions),
+			"Commodities":     len(book.Commodities),
+			"Prices":          len(book.Prices),
+		}, nil
+	case parts[0] == "accounts" && len(parts) == 1 && method == "GET":
+		return http.StatusOK, apiListAccounts(book), nil
+	case parts[0] == "accounts" && len(parts) == 1 && method == "POST":
+		return apiCreateAccount(hist, req)
+	case parts[0] == "accounts" && len(parts) == 2 && method == "GET":
+		acct := book.Accounts[id]
+		if acct == nil {
+			return 0, nil, notFound("no such account: %q", id)
+		}
+		return http.StatusOK, newAPIAccount(book, acct), nil
+	case parts[0] == "accounts" && len(parts) == 2 && method == "PUT":
+		return apiUpdateAccount(hist, id, req)
+	case parts[0] == "accounts" && len(parts) == 2 && method == "DELETE":
+		return apiDeleteAccount(hist, id, req)
+	case parts[0] == "accounts" && len(parts) == 3 && parts[2] == "flows" && method == "GET":
+		return apiAccountFlows(book, id, req)
+	case parts[0] == "accounts" && len(parts) == 3 && parts[2] == "reconcile" && method == "GET":
+		return apiReconcileStatus(book, id, req)
+	case parts[0] == "accounts" && len(parts) == 3 && parts[2] == "reconcile" && method == "POST":
+		return apiReconcile(hist, id, req)
+	case parts[0] == "transactions" && len(parts) == 1 && method == "GET":
+		return apiListTransactions(book, req)
+	case parts[0] == "transactions" && len(parts) == 1 && method == "POST":
+		return apiSaveTransaction(hist, "", req)
+	case parts[0] == "transactions" && len(parts) == 2 && method == "GET":
+		trn := book.Transactions[id]
+		if trn == nil {
+			return 0, nil, notFound("no such transaction: %q", id)
+		}
+		return http.StatusOK, newAPITransaction(trn), nil
+	case parts[0] == "transactions" && len(parts) == 2 && method == "PUT":
+		return apiSaveTransaction(hist, id, req)
+	case parts[0] == "transactions" && len(parts) == 2 && method == "DELETE":
+		if book.Transactions[id] == nil {
+			return 0, nil, notFound("no such transaction: %q", id)
+		}
+		if err := hist.Do(history.DeleteTransaction(book, id)); err != nil {
+			return 0, nil, err
+		}
+		return http.StatusNoContent, nil, nil
+	case parts[0] == "transactions" && len(parts) == 3 && parts[2] == "void" && method == "POST":
+		return apiVoidTransaction(hist, id, req)
+	case parts[0] == "transactions" && len(parts) == 3 && parts[2] == "unvoid" && method == "POST":
+		return apiVoidTransaction(hist, id, req)
+	case parts[0] == "history" && len(parts) == 1 && method == "GET":
+		return http.StatusOK, newAPIHistory(hist), nil
+	case parts[0] == "history" && len(parts) == 2 && parts[1] == "undo" && method == "POST":
+		if err := hist.Undo(); err != nil {
+			return 0, nil, &apiError{http.StatusConflict, err.Error()}
+		}
+		return http.StatusOK, newAPIHistory(hist), nil
+	case parts[0] == "history" && len(parts) == 2 && parts[1] == "redo" && method == "POST":
+		if err := hist.Redo(); err != nil {
+			return 0, nil, &apiError{http.StatusConflict, err.Error()}
+		}
+		return http.StatusOK, newAPIHistory(hist), nil
+	case parts[0] == "commodities" && len(parts) == 1 && method == "GET":
+		return http.StatusOK, book.Commodities, nil
+	case parts[0] == "prices" && len(parts) == 1 && method == "GET":
+		prices := types.Prices{}
+		for _, p := range book.Prices {
+			if c := req.Form.Get("commodity"); c != "" && p.Commodity != c {
+				continue
+			}
+			if c := req.Form.Get("currency"); c != "" && p.Currency != c {
+				continue
+			}
+			prices = append(prices, p)
+		}
+		return http.StatusOK, prices, nil
+	}
+	return 0, nil, notFound("no such method: %s %s", method, req.URL.Path)
+}
+
+func apiListAccounts(book *types.Book) []apiAccount {
+	accts := make([]apiAccount, 0, len(book.Accounts))
+	for _, acct := range sortAccts(book) {
+		accts = append(accts, newAPIAccount(book, acct))
+	}
+	return accts
+}
+
+func newAPIAccount(book *types.Book, acct *types.Account) apiAccount {
+	a := apiAccount{
+		Id:          acct.Id,
+		Name:        acct.Name,
+		Type:        acct.Type,
+		Unit:        acct.Unit,
+		Description: acct.Description,
+		Placeholder: acct.Placeholder,
+		Hidden:      acct.Hidden,
+		Balance:     new(types.Amount),
+	}
+	if !acct.LastReconcile.IsZero() {
+		a.LastReconcile = acct.LastReconcile.Format("2006-01-02")
+	}
+	if parent := book.Parent(acct); parent != nil {
+		a.Parent = parent.Id
+	}
+	for _, c := range acct.Children {
+		a.Children = append(a.Children, c.Id)
+	}
+	for _, f := range book.Flows[acct] {
+		a.Balance.Add(f.Units())
+	}
+	return a
+}
+
+// decode decodes the JSON body of req into v.
+func decode(req *http.Request, v interface{}) error {
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid request: %s", err)
+	}
+	return nil
+}
+
+// splitName returns the parent of an account given by its full name,
+// and the last component of the name.
+func splitName(book *types.Book, name string) (parent *types.Account, leaf string, err error) {
+	dir, leaf := path.Split(strings.TrimSuffix(name, "/"))
+	if dir = strings.TrimSuffix(dir, "/"); dir != "" {
+		parent = book.AccountByName(dir)
+		if parent == nil {
+			return nil, "", badRequest("no such account: %q", dir)
+		}
+	}
+	return parent, leaf, nil
+}
+
+func apiCreateAccount(hist *history.History, req *http.Request) (int, interface{}, error) {
+	book := hist.Book
+	var a apiAccount
+	if err := decode(req, &a); err != nil {
+		return 0, nil, err
+	}
+	parent, leaf, err := splitName(book, a.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+	var acct *types.Account
+	err = hist.Do(history.EditAccount("Add account "+a.Name, func(book *types.Book) (err error) {
+		acct, err = book.AddAccount(parent, leaf, a.Type, a.Unit, a.Description)
+		if err == nil {
+			acct.Placeholder, acct.Hidden = a.Placeholder, a.Hidden
+		}
+		return err
+	}))
+	if err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	return http.StatusCreated, newAPIAccount(book, acct), nil
+}
+
+func apiUpdateAccount(hist *history.History, id types.GUID, req *http.Request) (int, interface{}, error) {
+	book := hist.Book
+	acct := book.Accounts[id]
+	if acct == nil {
+		return 0, nil, notFound("no such account: %q", id)
+	}
+	var a apiAccount
+	if err := decode(req, &a); err != nil {
+		return 0, nil, err
+	}
+	form := accountForm{
+		Id:          id,
+		Type:        a.Type,
+		Unit:        a.Unit,
+		Description: a.Description,
+		Placeholder: a.Placeholder,
+		Hidden:      a.Hidden,
+	}
+	parent, leaf, err := splitName(book, a.Name)
+	if err != nil {
+		return 0, nil, err
+	}
+	form.Name = leaf
+	if parent != nil && parent.Type != "ROOT" {
+		form.Parent = parent.Name
+	}
+	if _, err := form.saveHistory(hist); err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	return http.StatusOK, newAPIAccount(book, acct), nil
+}
+
+func apiDeleteAccount(hist *history.History, id types.GUID, req *http.Request) (int, interface{}, error) {
+	book := hist.Book
+	acct := book.Accounts[id]
+	if acct == nil {
+		return 0, nil, notFound("no such account: %q", id)
+	}
+	var target *types.Account
+	if s := req.Form.Get("reassign"); s != "" {
+		target = book.Accounts[types.GUID(s)]
+		if target == nil {
+			return 0, nil, badRequest("no such account: %q", s)
+		}
+	}
+	if err := hist.Do(history.DeleteAccount(acct, target)); err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+// pagination returns the offset and limit form values.
+func pagination(req *http.Request) (offset, limit int, err error) {
+	limit = 100
+	if s := req.Form.Get("offset"); s != "" {
+		if offset, err = strconv.Atoi(s); err != nil || offset < 0 {
+			return 0, 0, badRequest("invalid offset %q", s)
+		}
+	}
+	if s := req.Form.Get("limit"); s != "" {
+		if limit, err = strconv.Atoi(s); err != nil || limit <= 0 || limit > 1000 {
+			return 0, 0, badRequest("invalid limit %q", s)
+		}
+	}
+	return offset, limit, nil
+}
+
+func paginate(n, offset, limit int) (start, end int) {
+	start, end = offset, offset+limit
+	if start > n {
+		start = n
+	}
+	if end > n {
+		end = n
+	}
+	return start, end
+}
+
+// apiAccountFlows returns the register of an account, filtered by
+// the from, to and text form values.
+func apiAccountFlows(book *types.Book, id types.GUID, req *http.Request) (int, interface{}, error) {
+	acct := book.Accounts[id]
+	if acct == nil {
+		return 0, nil, notFound("no such account: %q", id)
+	}
+	flt, err := formFilter(req)
+	if err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	offset, limit, err := pagination(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	var lines []apiLine
+	bal := new(big.Rat)
+	for _, f := range book.Flows[acct] {
+		bal.Add(bal, f.Units().Rat())
+		if flt.Match(f) {
+			lines = append(lines, apiLine{
+				Transaction: f.Parent.Id,
+				Date:        f.Parent.Date.Format("2006-01-02"),
+				Description: f.Parent.Description,
+				Flow:        newAPIFlow(f),
+				Balance:     new(types.Amount).SetRat(bal),
+			})
+		}
+	}
+	start, end := paginate(len(lines), offset, limit)
+	return http.StatusOK, apiPage{Total: len(lines), Offset: start, Items: lines[start:end]}, nil
+}
+
+// apiListTransactions returns transactions sorted by date, filtered
+// by the account (identifier or name), from, to and text form values.
+func apiListTransactions(book *types.Book, req *http.Request) (int, interface{}, error) {
+	flt, err := formFilter(req)
+	if err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	offset, limit, err := pagination(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	var acct *types.Account
+	if s := req.Form.Get("account"); s != "" {
+		acct = book.Accounts[types.GUID(s)]
+		if acct == nil {
+			acct = book.AccountByName(s)
+		}
+		if acct == nil {
+			return 0, nil, badRequest("no such account: %q", s)
+		}
+	}
+	var trns []*types.Transaction
+	for _, trn := range book.Transactions {
+		for i := range trn.Flows {
+			f := &trn.Flows[i]
+			if (acct == nil || f.Account == acct) && flt.Match(f) {
+				trns = append(trns, trn)
+				break
+			}
+		}
+	}
+	sort.Slice(trns, func(i, j int) bool {
+		a, b := trns[i], trns[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Id < b.Id
+	})
+	start, end := paginate(len(trns), offset, limit)
+	items := make([]apiTransaction, 0, end-start)
+	for _, trn := range trns[start:end] {
+		items = append(items, newAPITransaction(trn))
+	}
+	return http.StatusOK, apiPage{Total: len(trns), Offset: start, Items: items}, nil
+}
+
+func newAPITransaction(trn *types.Transaction) apiTransaction {
+	t := apiTransaction{
+		Id:          trn.Id,
+		Date:        trn.Date.Format("2006-01-02"),
+		Currency:    trn.Currency,
+		Description: trn.Description,
+		Notes:       trn.Notes,
+		Number:      trn.Number,
+		ExternalId:  trn.ExternalId,
+		VoidReason:  trn.VoidReason,
+		Flows:       make([]apiFlow, 0, len(trn.Flows)),
+	}
+	if !trn.VoidTime.IsZero() {
+		t.VoidTime = trn.VoidTime.Format(time.RFC3339)
+	}
+	for i := range trn.Flows {
+		t.Flows = append(t.Flows, newAPIFlow(&trn.Flows[i]))
+	}
+	return t
+}
+
+func newAPIFlow(f *types.Flow) apiFlow {
+	a := apiFlow{
+		Id:           f.Id,
+		Memo:         f.Memo,
+		Price:        f.Price,
+		Quantity:     f.Units(),
+		State:        f.State,
+		VoidPrice:    f.VoidPrice,
+		VoidQuantity: f.VoidQuantity,
+	}
+	if f.Account != nil {
+		a.Account, a.AccountName = f.Account.Id, f.Account.Name
+	}
+	if !f.ReconciledTime.IsZero() {
+		a.ReconciledTime = f.ReconciledTime.Format(time.RFC3339)
+	}
+	return a
+}
+
+// parseAPITime parses a date or an RFC 3339 time.
+func parseAPITime(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, s)
+}
+
+// apiSaveTransaction creates a transaction, or replaces the
+// transaction id if it is not empty.
+func apiSaveTransaction(hist *history.History, id types.GUID, req *http.Request) (int, interface{}, error) {
+	book := hist.Book
+	var old *types.Transaction
+	if id != "" {
+		old = book.Transactions[id]
+		if old == nil {
+			return 0, nil, notFound("no such transaction: %q", id)
+		}
+		if old.IsVoid() {
+			return 0, nil, &apiError{http.StatusConflict, "transaction is voided and cannot be edited"}
+		}
+	}
+	var t apiTransaction
+	if err := decode(req, &t); err != nil {
+		return 0, nil, err
+	}
+	trn, err := t.transaction(book, old)
+	if err == nil {
+		err = trn.Check()
+	}
+	if err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	code := http.StatusOK
+	if old == nil {
+		code = http.StatusCreated
+	}
+	if err := hist.Do(history.SaveTransaction(book, trn)); err != nil {
+		return 0, nil, err
+	}
+	return code, newAPITransaction(trn), nil
+}
+
+// apiVoidTransaction voids transaction id with the reason given in
+// the request body, or restores it for the unvoid action.
+func apiVoidTransaction(hist *history.History, id types.GUID, req *http.Request) (int, interface{}, error) {
+	book := hist.Book
+	if book.Transactions[id] == nil {
+		return 0, nil, notFound("no such transaction: %q", id)
+	}
+	cmd := history.UnvoidTransaction(book, id)
+	if !strings.HasSuffix(req.URL.Path, "/unvoid") {
+		var r apiVoidRequest
+		if err := decode(req, &r); err != nil {
+			return 0, nil, err
+		}
+		cmd = history.VoidTransaction(book, id, r.Reason, time.Now())
+	}
+	if err := hist.Do(cmd); err != nil {
+		return 0, nil, &apiError{http.StatusConflict, err.Error()}
+	}
+	return http.StatusOK, newAPITransaction(book.Transactions[id]), nil
+}
+
+// transaction builds a transaction from its JSON representation.
+// An update keeps the identifier, entry time, external identifier,
+// and the time of day of an unchanged date of old.
+func (t *apiTransaction) transaction(book *types.Book, old *types.Transaction) (*types.Transaction, error) {
+	date, err := parseAPITime(t.Date)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", t.Date)
+	}
+	trn := &types.Transaction{
+		Id:          types.NewGUID(),
+		Date:        date,
+		Stamp:       time.Now(),
+		Currency:    t.Currency,
+		Description: t.Description,
+		Notes:       t.Notes,
+		Number:      t.Number,
+		ExternalId:  t.ExternalId,
+	}
+	if old != nil {
+		trn.Id, trn.Stamp = old.Id, old.Stamp
+		if trn.ExternalId == "" {
+			trn.ExternalId = old.ExternalId
+		}
+		if old.Date.Format("2006-01-02") == t.Date {
+			trn.Date = old.Date
+		}
+	}
+	for i, a := range t.Flows {
+		acct := book.Accounts[a.Account]
+		if a.Account == "" {
+			acct = book.AccountByName(a.AccountName)
+		}
+		if acct == nil {
+			name := string(a.Account)
+			if name == "" {
+				name = a.AccountName
+			}
+			return nil, fmt.Errorf("flow %d: no such account %q", i+1, name)
+		}
+		if a.Price == nil {
+			return nil, fmt.Errorf("flow %d has no value", i+1)
+		}
+		if a.State == types.Voided {
+			return nil, fmt.Errorf("flow %d: transactions are voided with the void action", i+1)
+		}
+		f := types.Flow{
+			Id:       a.Id,
+			Memo:     a.Memo,
+			Account:  acct,
+			Price:    a.Price,
+			Quantity: a.Quantity,
+			State:    a.State,
+		}
+		if f.Id == "" {
+			f.Id = types.NewGUID()
+		}
+		if f.Quantity == nil {
+			if acct.Unit != "" && acct.Unit != trn.Currency {
+				return nil, fmt.Errorf("flow %d: a quantity in %s is required for account %s",
+					i+1, acct.Unit, acct.Name)
+			}
+			f.Quantity = new(types.Amount).SetRat(a.Price.Rat())
+		}
+		if a.ReconciledTime != "" {
+			if f.ReconciledTime, err = parseAPITime(a.ReconciledTime); err != nil {
+				return nil, fmt.Errorf("flow %d: invalid reconciliation time %q", i+1, a.ReconciledTime)
+			}
+		}
+		trn.Flows = append(trn.Flows, f)
+	}
+	return trn, nil
+}
+
+// An apiReconcileRequest is a reconciliation request: the ticked flows
+// are cleared, or reconciled if Finish is set.
+type apiReconcileRequest struct {
+	Date    string
+	Balance *types.Amount
+	Flows   []types.GUID
+	Finish  bool
+}
+
+// An apiReconcileReport describes the reconciliation of an account
+// at a statement date.
+type apiReconcileReport struct {
+	LastReconcile string        `json:",omitempty"`
+	Opening       *types.Amount // Balance of reconciled flows.
+	Difference    *types.Amount `json:",omitempty"`
+	Candidates    []apiLine
+}
+
+// apiReconcileStatus returns the flows to reconcile up to the
+// statement date given by the date form value.
+func apiReconcileStatus(book *types.Book, id types.GUID, req *http.Request) (int, interface{}, error) {
+	acct := book.Accounts[id]
+	if acct == nil {
+		return 0, nil, notFound("no such account: %q", id)
+	}
+	date := time.Now()
+	if s := req.Form.Get("date"); s != "" {
+		var err error
+		if date, err = parseAPITime(s); err != nil {
+			return 0, nil, badRequest("invalid date %q", s)
+		}
+	}
+	return http.StatusOK, newReconcileStatus(book, acct, date), nil
+}
+
+func newReconcileStatus(book *types.Book, acct *types.Account, date time.Time) apiReconcileReport {
+	st := apiReconcileReport{
+		Opening:    new(types.Amount).SetRat(reconcile.Opening(book, acct)),
+		Candidates: []apiLine{},
+	}
+	if !acct.LastReconcile.IsZero() {
+		st.LastReconcile = acct.LastReconcile.Format("2006-01-02")
+	}
+	for _, f := range reconcile.Candidates(book, acct, date) {
+		st.Candidates = append(st.Candidates, apiLine{
+			Transaction: f.Parent.Id,
+			Date:        f.Parent.Date.Format("2006-01-02"),
+			Description: f.Parent.Description,
+			Flow:        newAPIFlow(f),
+		})
+	}
+	return st
+}
+
+// apiReconcile clears or reconciles flows of an account, and returns
+// the status of the reconciliation.
+func apiReconcile(hist *history.History, id types.GUID, req *http.Request) (int, interface{}, error) {
+	book := hist.Book
+	acct := book.Accounts[id]
+	if acct == nil {
+		return 0, nil, notFound("no such account: %q", id)
+	}
+	var r apiReconcileRequest
+	if err := decode(req, &r); err != nil {
+		return 0, nil, err
+	}
+	date, err := parseAPITime(r.Date)
+	if err != nil {
+		return 0, nil, badRequest("invalid date %q", r.Date)
+	}
+	if r.Balance == nil {
+		return 0, nil, badRequest("missing statement balance")
+	}
+	st := reconcile.Statement{Date: date, Balance: r.Balance.Rat()}
+	flows, err := reconcile.Lookup(book, acct, r.Flows)
+	if err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	diff := reconcile.Difference(book, acct, st, flows)
+	if err := hist.Do(history.Reconcile(acct, st, flows, r.Finish)); err != nil {
+		return 0, nil, badRequest("%s", err)
+	}
+	status := newReconcileStatus(book, acct, date)
+	status.Difference = new(types.Amount).SetRat(diff)
+	return http.StatusOK, status, nil
+}
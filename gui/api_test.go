@@ -0,0 +1,196 @@
+package gui
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/types"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func TestAPI(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	h := apiHandler(newStore(book))
+	call := func(method, url, body string, code int, v interface{}) {
+		t.Helper()
+		req := httptest.NewRequest(method, url, strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != code {
+			t.Fatalf("%s %s: got status %d, expected %d: %s", method, url, rec.Code, code, rec.Body)
+		}
+		if v != nil {
+			if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+				t.Fatalf("%s %s: %s", method, url, err)
+			}
+		}
+	}
+
+	var accts []apiAccount
+	call("GET", "/api/v1/accounts", "", http.StatusOK, &accts)
+	ids := make(map[string]string)
+	for _, a := range accts {
+		ids[a.Name] = string(a.Id)
+	}
+	if len(accts) != len(book.Accounts) || ids["/Assets/Broker"] == "" {
+		t.Fatalf("got accounts %v", ids)
+	}
+
+	var acct apiAccount
+	call("GET", "/api/v1/accounts/"+ids["/Assets/Broker/ACME"], "", http.StatusOK, &acct)
+	if acct.Balance.String() != "10.00" || acct.Parent != book.AccountByName("/Assets/Broker").Id {
+		t.Errorf("got account %+v", acct)
+	}
+
+	// Create an account and a transaction.
+	call("POST", "/api/v1/accounts", `{"Name": "/Expenses/Rent", "Type": "EXPENSE", "Unit": "USD"}`,
+		http.StatusCreated, &acct)
+	var trn apiTransaction
+	call("POST", "/api/v1/transactions", `{"Date": "2014-02-01", "Currency": "USD", "Description": "Rent",
+		"Flows": [{"AccountName": "/Assets/Broker", "Price": "-400"}, {"Account": "`+string(acct.Id)+`", "Price": 400}]}`,
+		http.StatusCreated, &trn)
+	if len(trn.Flows) != 2 || trn.Flows[1].AccountName != "/Expenses/Rent" || trn.Flows[1].Quantity.String() != "400.00" {
+		t.Errorf("got transaction %+v", trn)
+	}
+	call("GET", "/api/v1/accounts/"+string(acct.Id), "", http.StatusOK, &acct)
+	if acct.Balance.String() != "400.00" {
+		t.Errorf("got balance %s, expected 400.00", acct.Balance)
+	}
+
+	// Invalid transactions are rejected.
+	for _, body := range []string{
+		`{"Date": "2014-02-01", "Currency": "USD", "Flows": [{"AccountName": "/Assets/Broker", "Price": "-400"}]}`,
+		`{"Date": "2014-02-01", "Currency": "USD", "Flows": [{"AccountName": "/Nowhere", "Price": "0"}]}`,
+		`{"Date": "2014-02-01", "Currency": "USD", "Flows": [{"AccountName": "/Assets/Broker/ACME", "Price": "0"}]}`,
+		`{"Date": "yesterday", "Currency": "USD"}`,
+		`{"Unknown": 1}`,
+	} {
+		call("POST", "/api/v1/transactions", body, http.StatusBadRequest, nil)
+	}
+
+	// Update, then list with filters.
+	trn.Description = "Monthly rent"
+	trn.Flows[0].Price, trn.Flows[1].Price = nil, nil
+	body, _ := json.Marshal(trn)
+	call("PUT", "/api/v1/transactions/"+string(trn.Id), string(body), http.StatusBadRequest, nil)
+	call("PUT", "/api/v1/transactions/"+string(trn.Id), strings.Replace(string(body), `"Price":null`, `"Price":"450"`, 1),
+		http.StatusBadRequest, nil)
+	s := strings.Replace(string(body), `"Price":null`, `"Price":"-450"`, 1)
+	s = strings.Replace(s, `"Price":null`, `"Price":"450"`, 1)
+	ids0 := trn.Flows[0].Id
+	call("PUT", "/api/v1/transactions/"+string(trn.Id), s, http.StatusOK, &trn)
+	if trn.Description != "Monthly rent" || trn.Flows[0].Id != ids0 || trn.Flows[1].Price.String() != "450.00" {
+		t.Errorf("got updated transaction %+v", trn)
+	}
+
+	var page struct {
+		Total  int
+		Offset int
+		Items  []apiTransaction
+	}
+	call("GET", "/api/v1/transactions?account=/Assets/Broker&limit=2&offset=1", "", http.StatusOK, &page)
+	if page.Total != 6 || len(page.Items) != 2 || page.Offset != 1 {
+		t.Errorf("got page %d/%d at %d", len(page.Items), page.Total, page.Offset)
+	}
+	call("GET", "/api/v1/transactions?text=rent&from=2014-01-01", "", http.StatusOK, &page)
+	if page.Total != 1 || page.Items[0].Id != trn.Id {
+		t.Errorf("got %d transactions, expected %s", page.Total, trn.Id)
+	}
+
+	// Void and unvoid.
+	url := "/api/v1/transactions/" + string(trn.Id)
+	call("POST", url+"/void", `{"Reason": "Paid twice"}`, http.StatusOK, &trn)
+	if trn.VoidReason != "Paid twice" || trn.Flows[1].State != types.Voided || trn.Flows[1].VoidPrice.String() != "450.00" {
+		t.Errorf("got voided transaction %+v", trn)
+	}
+	call("GET", "/api/v1/accounts/"+string(acct.Id), "", http.StatusOK, &acct)
+	if acct.Balance.String() != "0.00" {
+		t.Errorf("got balance %s after voiding, expected 0.00", acct.Balance)
+	}
+	call("PUT", url, s, http.StatusConflict, nil)
+	call("POST", url+"/void", `{"Reason": "again"}`, http.StatusConflict, nil)
+	trn = apiTransaction{}
+	call("POST", url+"/unvoid", "", http.StatusOK, &trn)
+	if trn.VoidReason != "" || trn.Flows[1].State != types.NotCleared || trn.Flows[1].Price.String() != "450.00" {
+		t.Errorf("got unvoided transaction %+v", trn)
+	}
+
+	// Delete.
+	call("DELETE", "/api/v1/accounts/"+string(acct.Id), "", http.StatusBadRequest, nil)
+	call("DELETE", "/api/v1/transactions/"+string(trn.Id), "", http.StatusNoContent, nil)
+	call("DELETE", "/api/v1/accounts/"+string(acct.Id), "", http.StatusNoContent, nil)
+	call("GET", "/api/v1/transactions/"+string(trn.Id), "", http.StatusNotFound, nil)
+
+	// Undo the deletions.
+	var hist apiHistory
+	call("POST", "/api/v1/history/undo", "", http.StatusOK, &hist)
+	call("POST", "/api/v1/history/undo", "", http.StatusOK, &hist)
+	if !strings.HasPrefix(hist.Undo, "Unvoid transaction") || !strings.HasPrefix(hist.Redo, "Delete transaction") {
+		t.Errorf("got undo %q, redo %q", hist.Undo, hist.Redo)
+	}
+	call("GET", "/api/v1/transactions/"+string(trn.Id), "", http.StatusOK, &trn)
+	call("GET", "/api/v1/accounts/"+string(acct.Id), "", http.StatusOK, &acct)
+	if acct.Balance.String() != "400.00" {
+		t.Errorf("got balance %s after undo, expected 400.00", acct.Balance)
+	}
+	call("POST", "/api/v1/history/redo", "", http.StatusOK, &hist)
+	call("GET", "/api/v1/transactions/"+string(trn.Id), "", http.StatusNotFound, nil)
+	call("GET", "/api/v1/history", "", http.StatusOK, &hist)
+	if n := len(hist.Log); n != 10 || hist.Log[n-1].Action != "Redo" {
+		t.Errorf("got log %+v", hist.Log)
+	}
+	call("GET", "/api/v1/unknown", "", http.StatusNotFound, nil)
+}
+
+func TestAPIReconcile(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	h := apiHandler(newStore(book))
+	broker := book.AccountByName("/Assets/Broker")
+	url := "/api/v1/accounts/" + string(broker.Id) + "/reconcile"
+	call := func(method, url, body string, code int) (rep apiReconcileReport) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(method, url, strings.NewReader(body)))
+		if rec.Code != code {
+			t.Fatalf("%s %s: got status %d, expected %d: %s", method, url, rec.Code, code, rec.Body)
+		}
+		json.Unmarshal(rec.Body.Bytes(), &rep)
+		return rep
+	}
+
+	rep := call("GET", url+"?date=2013-06-30", "", http.StatusOK)
+	if len(rep.Candidates) != 3 || rep.Opening.Rat().Sign() != 0 {
+		t.Fatalf("got %d candidates, opening %s", len(rep.Candidates), rep.Opening)
+	}
+	var ids []string
+	for _, l := range rep.Candidates {
+		ids = append(ids, `"`+string(l.Flow.Id)+`"`)
+	}
+	flows := "[" + strings.Join(ids, ",") + "]"
+
+	rep = call("POST", url, `{"Date": "2013-06-30", "Balance": "250", "Flows": `+flows+`}`, http.StatusOK)
+	if rep.Difference.String() != "50.00" || rep.Candidates[0].Flow.State != types.Cleared {
+		t.Errorf("got difference %s, state %s", rep.Difference, rep.Candidates[0].Flow.State)
+	}
+	call("POST", url, `{"Date": "2013-06-30", "Balance": "250", "Flows": `+flows+`, "Finish": true}`, http.StatusBadRequest)
+	rep = call("POST", url, `{"Date": "2013-06-30", "Balance": "200", "Flows": `+flows+`, "Finish": true}`, http.StatusOK)
+	if len(rep.Candidates) != 0 || rep.Opening.String() != "200.00" || rep.LastReconcile != "2013-06-30" {
+		t.Errorf("got report %+v after finishing", rep)
+	}
+	for _, f := range book.Flows[broker][:3] {
+		if f.State != types.Reconciled {
+			t.Errorf("flow %s of %s is not reconciled", f.Id, f.Parent.Date)
+		}
+	}
+}
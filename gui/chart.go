@@ -0,0 +1,228 @@
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"math/big"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Charts are rendered as inline SVG so that they work without
+// any external service.
+
+const (
+	chartWidth   = 800
+	chartHeight  = 300
+	chartMargin  = 60
+	chartPadding = 10
+)
+
+var chartColors = []string{
+	"#1f77b4", "#ff7f0e", "#2ca02c", "#d62728", "#9467bd",
+	"#8c564b", "#e377c2", "#7f7f7f", "#bcbd22", "#17becf",
+}
+
+type chartSeries struct {
+	Name   string
+	Values []float64
+}
+
+func ratsToFloats(rats []*big.Rat) []float64 {
+	fs := make([]float64, len(rats))
+	for i, x := range rats {
+		fs[i], _ = x.Float64()
+	}
+	return fs
+}
+
+// chartScale maps values in [min, max] to the vertical chart area.
+type chartScale struct{ min, max float64 }
+
+func newChartScale(series []chartSeries) chartScale {
+	s := chartScale{min: 0, max: 0}
+	for _, ser := range series {
+		for _, v := range ser.Values {
+			s.min = math.Min(s.min, v)
+			s.max = math.Max(s.max, v)
+		}
+	}
+	if s.max == s.min {
+		s.max = s.min + 1
+	}
+	return s
+}
+
+func (s chartScale) y(v float64) float64 {
+	h := float64(chartHeight - 2*chartPadding)
+	return chartPadding + h*(s.max-v)/(s.max-s.min)
+}
+
+func svgHeader(buf *bytes.Buffer, height int) {
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" class="chart" width="%d" height="%d" viewBox="0 0 %d %d">`,
+		chartWidth, height, chartWidth, height)
+}
+
+// svgAxes draws the horizontal axes and value labels.
+func svgAxes(buf *bytes.Buffer, s chartScale) {
+	for _, v := range []float64{s.min, 0, s.max} {
+		y := s.y(v)
+		fmt.Fprintf(buf, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ccc"/>`,
+			chartMargin, y, chartWidth-chartPadding, y)
+		fmt.Fprintf(buf, `<text x="%d" y="%.1f" font-size="10" text-anchor="end">%.0f</text>`,
+			chartMargin-4, y+3, v)
+	}
+}
+
+// svgLegend draws the series names below the chart area.
+func svgLegend(buf *bytes.Buffer, names []string) {
+	for i, name := range names {
+		x := chartMargin + (i%4)*180
+		y := chartHeight + 20 + (i/4)*16
+		fmt.Fprintf(buf, `<rect x="%d" y="%d" width="10" height="10" fill="%s"/>`,
+			x, y-9, chartColors[i%len(chartColors)])
+		fmt.Fprintf(buf, `<text x="%d" y="%d" font-size="11">%s</text>`,
+			x+14, y, template.HTMLEscapeString(name))
+	}
+}
+
+func legendHeight(n int) int { return 30 + (n+3)/4*16 }
+
+// lineChart renders series as lines over the given x labels.
+func lineChart(labels []string, series []chartSeries) template.HTML {
+	buf := new(bytes.Buffer)
+	s := newChartScale(series)
+	svgHeader(buf, chartHeight+legendHeight(len(series)))
+	svgAxes(buf, s)
+	step := float64(chartWidth-chartMargin-2*chartPadding) / math.Max(1, float64(len(labels)-1))
+	x := func(i int) float64 { return chartMargin + chartPadding + step*float64(i) }
+	every := len(labels)/10 + 1
+	for i, l := range labels {
+		if i%every == 0 {
+			fmt.Fprintf(buf, `<text x="%.1f" y="%d" font-size="10" text-anchor="middle">%s</text>`,
+				x(i), chartHeight+8, template.HTMLEscapeString(l))
+		}
+	}
+	names := make([]string, len(series))
+	for k, ser := range series {
+		names[k] = ser.Name
+		buf.WriteString(`<polyline fill="none" stroke-width="2" points="`)
+		for i, v := range ser.Values {
+			fmt.Fprintf(buf, "%.1f,%.1f ", x(i), s.y(v))
+		}
+		fmt.Fprintf(buf, `" stroke="%s"/>`, chartColors[k%len(chartColors)])
+	}
+	svgLegend(buf, names)
+	buf.WriteString("</svg>")
+	return template.HTML(buf.String())
+}
+
+// stackedBarChart renders series as bars stacked over each x label.
+// Negative values are stacked below the axis.
+func stackedBarChart(labels []string, series []chartSeries) template.HTML {
+	buf := new(bytes.Buffer)
+	// Compute the scale from stacked totals.
+	var totals []chartSeries
+	pos := make([]float64, len(labels))
+	neg := make([]float64, len(labels))
+	for _, ser := range series {
+		for i, v := range ser.Values {
+			if v > 0 {
+				pos[i] += v
+			} else {
+				neg[i] += v
+			}
+		}
+	}
+	totals = append(totals, chartSeries{Values: pos}, chartSeries{Values: neg})
+	s := newChartScale(totals)
+	svgHeader(buf, chartHeight+legendHeight(len(series)))
+	svgAxes(buf, s)
+
+	slot := float64(chartWidth-chartMargin-chartPadding) / math.Max(1, float64(len(labels)))
+	width := slot * 0.8
+	every := len(labels)/10 + 1
+	for i, l := range labels {
+		x := chartMargin + slot*float64(i)
+		if i%every == 0 {
+			fmt.Fprintf(buf, `<text x="%.1f" y="%d" font-size="10" text-anchor="middle">%s</text>`,
+				x+slot/2, chartHeight+8, template.HTMLEscapeString(l))
+		}
+		up, down := 0.0, 0.0
+		for k, ser := range series {
+			v := ser.Values[i]
+			var y0, y1 float64
+			if v >= 0 {
+				y0, y1 = s.y(up+v), s.y(up)
+				up += v
+			} else {
+				y0, y1 = s.y(down), s.y(down+v)
+				down += v
+			}
+			fmt.Fprintf(buf, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %.2f</title></rect>`,
+				x+slot*0.1, y0, width, y1-y0, chartColors[k%len(chartColors)],
+				template.HTMLEscapeString(ser.Name), v)
+		}
+	}
+	names := make([]string, len(series))
+	for k, ser := range series {
+		names[k] = ser.Name
+	}
+	svgLegend(buf, names)
+	buf.WriteString("</svg>")
+	return template.HTML(buf.String())
+}
+
+// pieChart renders the relative weights of positive values.
+func pieChart(names []string, values []float64) template.HTML {
+	buf := new(bytes.Buffer)
+	svgHeader(buf, chartHeight+legendHeight(len(names)))
+	total := 0.0
+	for _, v := range values {
+		if v > 0 {
+			total += v
+		}
+	}
+	const r = chartHeight/2 - chartPadding
+	cx, cy := float64(chartWidth/2), float64(chartHeight/2)
+	angle := -math.Pi / 2
+	for k, v := range values {
+		if v <= 0 || total == 0 {
+			continue
+		}
+		color := chartColors[k%len(chartColors)]
+		title := fmt.Sprintf("%s: %.2f (%.1f%%)", names[k], v, 100*v/total)
+		if v == total {
+			fmt.Fprintf(buf, `<circle cx="%.1f" cy="%.1f" r="%d" fill="%s"><title>%s</title></circle>`,
+				cx, cy, r, color, template.HTMLEscapeString(title))
+			continue
+		}
+		next := angle + 2*math.Pi*v/total
+		large := 0
+		if next-angle > math.Pi {
+			large = 1
+		}
+		fmt.Fprintf(buf, `<path d="M%.1f,%.1f L%.1f,%.1f A%d,%d 0 %d,1 %.1f,%.1f Z" fill="%s"><title>%s</title></path>`,
+			cx, cy, cx+r*math.Cos(angle), cy+r*math.Sin(angle),
+			r, r, large, cx+r*math.Cos(next), cy+r*math.Sin(next),
+			color, template.HTMLEscapeString(title))
+		angle = next
+	}
+	svgLegend(buf, names)
+	buf.WriteString("</svg>")
+	return template.HTML(buf.String())
+}
+
+// balanceChart renders the running balance of an account register.
+func balanceChart(flows []*types.Flow) template.HTML {
+	bals := cumulFlows(flows)
+	labels := make([]string, len(flows))
+	values := make([]float64, len(flows))
+	for i, f := range flows {
+		labels[i] = f.Parent.Date.Format("2006-01-02")
+		values[i], _ = bals[i].Rat().Float64()
+	}
+	return lineChart(labels, []chartSeries{{Name: "Balance", Values: values}})
+}
@@ -0,0 +1,82 @@
+package gui
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/register"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// exportRegister serves the register of the account named by the name
+// form value as a CSV or XLSX download, according to the format form
+// value. Flows can be filtered by the from, to and text form values.
+func exportRegister(s *store) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			log.Printf("%s %s from %s", req.Method, req.URL, req.RemoteAddr)
+			resp := new(bytes.Buffer)
+			var ctype, filename string
+			err := s.read(func(hist *history.History) (err error) {
+				ctype, filename, err = writeRegister(hist.Book, resp, req)
+				return err
+			})
+			if err != nil {
+				log.Printf("ERROR: %s", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", ctype)
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			w.Write(resp.Bytes())
+		})
+}
+
+// writeRegister writes the register requested by req to resp, and
+// returns its content type and file name.
+func writeRegister(book *types.Book, resp *bytes.Buffer, req *http.Request) (ctype, filename string, err error) {
+	req.ParseForm()
+	acctname := req.Form.Get("name")
+	account := book.AccountByName(acctname)
+	if account == nil {
+		return "", "", fmt.Errorf("no such account: %q", acctname)
+	}
+	flt, err := formFilter(req)
+	if err != nil {
+		return "", "", err
+	}
+	lines := register.Lines(book, account, flt)
+	base := strings.Replace(path.Base(account.Name), `"`, "", -1)
+	switch format := req.Form.Get("format"); format {
+	case "", "csv":
+		return "text/csv; charset=utf-8", base + ".csv", register.WriteCSV(resp, lines)
+	case "xlsx":
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			base + ".xlsx", register.WriteXLSX(resp, account.Name, lines)
+	default:
+		return "", "", fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// formFilter reads a register filter from the from, to
+// and text form values.
+func formFilter(req *http.Request) (flt register.Filter, err error) {
+	flt.Text = req.Form.Get("text")
+	if s := req.Form.Get("from"); s != "" {
+		if flt.From, err = time.Parse("2006-01-02", s); err != nil {
+			return flt, fmt.Errorf("invalid start date: %s", err)
+		}
+	}
+	if s := req.Form.Get("to"); s != "" {
+		if flt.To, err = time.Parse("2006-01-02", s); err != nil {
+			return flt, fmt.Errorf("invalid end date: %s", err)
+		}
+	}
+	return flt, nil
+}
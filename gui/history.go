@@ -0,0 +1,86 @@
+package gui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/remyoudompheng/gocash/history"
+)
+
+// pageHistory shows the audit log of the book, newest first, with
+// buttons to undo and redo edits.
+func pageHistory(hist *history.History, w io.Writer, req *http.Request) error {
+	log := hist.Log()
+	for i, j := 0, len(log)-1; i < j; i, j = i+1, j-1 {
+		log[i], log[j] = log[j], log[i]
+	}
+	return historyTpl.Execute(w, templateData{
+		Title:   "History",
+		Book:    hist.Book,
+		History: hist,
+		Log:     log,
+	})
+}
+
+// pageUndo reverts the last edit of the book.
+func pageUndo(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	if err := hist.Undo(); err != nil {
+		return err
+	}
+	return redirect("/history/")
+}
+
+// pageRedo applies again the last undone edit of the book.
+func pageRedo(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	if err := hist.Redo(); err != nil {
+		return err
+	}
+	return redirect("/history/")
+}
+
+// exportHistory serves the audit log as a CSV or JSON download,
+// according to the format form value.
+func exportHistory(s *store) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			log.Printf("%s %s from %s", req.Method, req.URL, req.RemoteAddr)
+			var records []history.Record
+			s.read(func(hist *history.History) error {
+				records = hist.Log()
+				return nil
+			})
+			resp := new(bytes.Buffer)
+			var ctype, filename string
+			var err error
+			switch format := req.FormValue("format"); format {
+			case "", "csv":
+				ctype, filename = "text/csv; charset=utf-8", "history.csv"
+				err = history.WriteCSV(resp, records)
+			case "json":
+				ctype, filename = "application/json; charset=utf-8", "history.json"
+				enc := json.NewEncoder(resp)
+				enc.SetIndent("", "  ")
+				err = enc.Encode(records)
+			default:
+				err = fmt.Errorf("unknown format %q", format)
+			}
+			if err != nil {
+				log.Printf("ERROR: %s", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			w.Header().Set("Content-Type", ctype)
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+			w.Write(resp.Bytes())
+		})
+}
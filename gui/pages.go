@@ -4,14 +4,18 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/remyoudompheng/gocash/reports"
 	"github.com/remyoudompheng/gocash/types"
 )
 
 func pageHome(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
 	return homeTpl.Execute(w, templateData{
 		Title: "Gocash",
 		Book:  book,
+		Form:  req.Form,
 	})
 }
 
@@ -41,3 +45,135 @@ func pageAccount(book *types.Book, w io.Writer, req *http.Request) error {
 		Account: account,
 	})
 }
+
+// formPeriods reads report periods from the period, fiscal,
+// from and to form values.
+func formPeriods(req *http.Request) (reports.Periods, error) {
+	period := req.Form.Get("period")
+	if period == "" {
+		period = "monthly"
+	}
+	fiscal := 1
+	if s := req.Form.Get("fiscal"); s != "" {
+		var err error
+		fiscal, err = strconv.Atoi(s)
+		if err != nil {
+			return reports.Periods{}, fmt.Errorf("invalid fiscal year start: %s", err)
+		}
+	}
+	return reports.ParsePeriods(period, fiscal, req.Form.Get("from"), req.Form.Get("to"))
+}
+
+func pageNetWorth(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
+	periods, err := formPeriods(req)
+	if err != nil {
+		return err
+	}
+	currency := req.Form.Get("currency")
+	if currency == "" {
+		currency = book.DefaultCurrency()
+	}
+	rep := reports.NetWorth(book, currency, periods)
+
+	labels := make([]string, len(rep.T))
+	for i, t := range rep.T {
+		labels[i] = t.Format("2006-01-02")
+	}
+	series := []chartSeries{{Name: "Net worth", Values: ratsToFloats(rep.NetWorth)}}
+	for i, g := range rep.Groups {
+		series = append(series, chartSeries{Name: g, Values: ratsToFloats(rep.Series[i])})
+	}
+
+	return networthTpl.Execute(w, templateData{
+		Title:    "Net worth",
+		Book:     book,
+		Form:     req.Form,
+		Currency: currency,
+		NetWorth: &rep,
+		Chart:    lineChart(labels, series),
+	})
+}
+
+func pageExpenses(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
+	periods, err := formPeriods(req)
+	if err != nil {
+		return err
+	}
+	depth := 2
+	if s := req.Form.Get("depth"); s != "" {
+		depth, err = strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid depth: %s", err)
+		}
+	}
+	rep := reports.Expenses(book, depth, periods)
+
+	labels := make([]string, len(rep.T))
+	for i, t := range rep.T {
+		labels[i] = t.Format("2006-01-02")
+	}
+	series := make([]chartSeries, len(rep.Categories))
+	for i, c := range rep.Categories {
+		series[i] = chartSeries{Name: c, Values: ratsToFloats(rep.Amounts[i])}
+	}
+
+	return expensesTpl.Execute(w, templateData{
+		Title:    "Expenses",
+		Book:     book,
+		Form:     req.Form,
+		Expenses: &rep,
+		Chart:    stackedBarChart(labels, series),
+		Pie:      pieChart(rep.Categories, ratsToFloats(rep.Totals)),
+	})
+}
+
+func pageSpending(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
+	periods, err := formPeriods(req)
+	if err != nil {
+		return err
+	}
+	opts := reports.DefaultSpendingOptions
+	if s := req.Form.Get("depth"); s != "" {
+		opts.Depth, err = strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid depth: %s", err)
+		}
+	}
+	rep := reports.Spending(book, periods, opts)
+	return spendingTpl.Execute(w, templateData{
+		Title:    "Spending",
+		Book:     book,
+		Form:     req.Form,
+		Spending: &rep,
+	})
+}
+
+func pagePortfolio(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
+	periods, err := formPeriods(req)
+	if err != nil {
+		return err
+	}
+	currency := req.Form.Get("currency")
+	if currency == "" {
+		currency = book.DefaultCurrency()
+	}
+	rep := reports.Portfolio(book, currency, periods)
+	names := make([]string, len(rep.Holdings))
+	values := make([]float64, len(rep.Holdings))
+	for i, h := range rep.Holdings {
+		names[i] = h.Account.Name
+		values[i], _ = h.Value.Float64()
+	}
+	return portfolioTpl.Execute(w, templateData{
+		Title:     "Portfolio",
+		Book:      book,
+		Form:      req.Form,
+		Currency:  currency,
+		Portfolio: &rep,
+		Pie:       pieChart(names, values),
+	})
+}
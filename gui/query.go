@@ -0,0 +1,52 @@
+package gui
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/remyoudompheng/gocash/query"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A queryResult is the outcome of a query of the query page.
+type queryResult struct {
+	Query   string
+	Error   string
+	Columns []string
+	Rows    [][]queryCell
+}
+
+type queryCell struct {
+	Text   string
+	Number bool
+}
+
+// pageQuery runs the query in form value q and shows the result
+// as a table.
+func pageQuery(book *types.Book, w io.Writer, req *http.Request) error {
+	res := &queryResult{Query: req.FormValue("q")}
+	if res.Query != "" {
+		q, err := query.Parse(res.Query)
+		var r *query.Result
+		if err == nil {
+			r, err = q.Run(book)
+		}
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Columns = r.Columns
+			for _, line := range r.Rows {
+				cells := make([]queryCell, len(line))
+				for i, v := range line {
+					cells[i] = queryCell{Text: query.Format(v), Number: query.IsNumber(v)}
+				}
+				res.Rows = append(res.Rows, cells)
+			}
+		}
+	}
+	return queryTpl.Execute(w, templateData{
+		Title: "Query",
+		Book:  book,
+		Query: res,
+	})
+}
@@ -0,0 +1,123 @@
+package gui
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/reconcile"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A reconcileForm holds the fields of the reconciliation page.
+type reconcileForm struct {
+	Date    string
+	Balance string
+	Opening *big.Rat
+	Flows   []reconcileRow
+	Error   string
+}
+
+type reconcileRow struct {
+	Flow    *types.Flow
+	Amount  string // Exact amount in the account commodity.
+	Checked bool
+}
+
+// pageReconcile shows the reconciliation of the account given by the
+// name form value against a statement given by the date and balance
+// form values. Flows that are already cleared are initially ticked.
+func pageReconcile(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
+	acct := book.AccountByName(req.Form.Get("name"))
+	if acct == nil {
+		return fmt.Errorf("no such account: %q", req.Form.Get("name"))
+	}
+	form := reconcileForm{
+		Date:    req.Form.Get("date"),
+		Balance: req.Form.Get("balance"),
+	}
+	if form.Date == "" {
+		form.Date = time.Now().Format("2006-01-02")
+	}
+	return renderReconcile(book, w, acct, &form, nil)
+}
+
+// renderReconcile shows the reconciliation page. The given flows are
+// ticked, or the cleared flows if checked is nil.
+func renderReconcile(book *types.Book, w io.Writer, acct *types.Account, form *reconcileForm, checked map[types.GUID]bool) error {
+	date, err := time.ParseInLocation("2006-01-02", form.Date, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid statement date %q", form.Date)
+	}
+	form.Opening = reconcile.Opening(book, acct)
+	for _, f := range reconcile.Candidates(book, acct, date) {
+		row := reconcileRow{Flow: f, Amount: f.Units().Decimal(), Checked: f.State == types.Cleared}
+		if checked != nil {
+			row.Checked = checked[f.Id]
+		}
+		form.Flows = append(form.Flows, row)
+	}
+	return reconcileTpl.Execute(w, templateData{
+		Title:     "Reconcile " + acct.Name,
+		Book:      book,
+		Account:   acct,
+		Reconcile: form,
+	})
+}
+
+// pageSaveReconcile finishes or postpones a reconciliation, according
+// to the action form value. Ticked flows are given by flow form values.
+func pageSaveReconcile(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	book := hist.Book
+	req.ParseForm()
+	acct := book.AccountByName(req.PostForm.Get("name"))
+	if acct == nil {
+		return fmt.Errorf("no such account: %q", req.PostForm.Get("name"))
+	}
+	form := reconcileForm{
+		Date:    req.PostForm.Get("date"),
+		Balance: strings.TrimSpace(req.PostForm.Get("balance")),
+	}
+	checked := make(map[types.GUID]bool)
+	var ids []types.GUID
+	for _, id := range req.PostForm["flow"] {
+		checked[types.GUID(id)] = true
+		ids = append(ids, types.GUID(id))
+	}
+	st, err := parseStatement(form.Date, form.Balance)
+	if err == nil {
+		var flows []*types.Flow
+		flows, err = reconcile.Lookup(book, acct, ids)
+		if err == nil {
+			finish := req.PostForm.Get("action") == "finish"
+			err = hist.Do(history.Reconcile(acct, st, flows, finish))
+		}
+	}
+	if err != nil {
+		form.Error = err.Error()
+		return renderReconcile(book, w, acct, &form, checked)
+	}
+	return redirect("/account/?name=" + url.QueryEscape(acct.Name))
+}
+
+func parseStatement(date, balance string) (st reconcile.Statement, err error) {
+	st.Date, err = time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return st, fmt.Errorf("invalid statement date %q", date)
+	}
+	var ok bool
+	st.Balance, ok = new(big.Rat).SetString(balance)
+	if !ok {
+		return st, fmt.Errorf("invalid statement balance %q", balance)
+	}
+	return st, nil
+}
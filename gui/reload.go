@@ -0,0 +1,94 @@
+package gui
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Watch describes the file of the book served by StartServer,
+// which is reloaded when the file changes.
+type Watch struct {
+	Filename string
+	Interval time.Duration // How often the file is checked.
+	Load     func(filename string) (*types.Book, error)
+}
+
+// watch polls the file of w and reloads the book when its size or
+// modification time changed, and then stayed the same for a polling
+// interval, so that files being written are not read. It returns when
+// done is closed.
+func (s *store) watch(w *Watch, done <-chan struct{}) {
+	last, _ := os.Stat(w.Filename)
+	changed := false
+	tick := time.NewTicker(w.Interval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-tick.C:
+		}
+		fi, err := os.Stat(w.Filename)
+		if err != nil {
+			// The file may be replaced by a new version.
+			continue
+		}
+		if last == nil || fi.Size() != last.Size() || !fi.ModTime().Equal(last.ModTime()) {
+			last, changed = fi, true
+			continue
+		}
+		if !changed {
+			continue
+		}
+		changed = false
+		log.Printf("%s changed, reloading", w.Filename)
+		book, err := w.Load(w.Filename)
+		if err != nil {
+			log.Printf("ERROR: failed to reload %s: %s", w.Filename, err)
+			continue
+		}
+		s.replace(book, "Reload "+w.Filename)
+	}
+}
+
+// heartbeat is the interval of comments sent on idle event streams,
+// so that proxies keep them open.
+var heartbeat = 30 * time.Second
+
+// serveEvents streams server-sent events to pages: a reload event is
+// sent when the book is reloaded.
+func serveEvents(s *store) http.Handler {
+	return http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			log.Printf("%s %s from %s", req.Method, req.URL, req.RemoteAddr)
+			flusher, ok := w.(http.Flusher)
+			if !ok {
+				http.Error(w, "streaming not supported", http.StatusInternalServerError)
+				return
+			}
+			c := s.subscribe()
+			defer s.unsubscribe(c)
+			w.Header().Set("Content-Type", "text/event-stream")
+			w.Header().Set("Cache-Control", "no-cache")
+			fmt.Fprint(w, ": connected\n\n")
+			flusher.Flush()
+			tick := time.NewTicker(heartbeat)
+			defer tick.Stop()
+			for {
+				select {
+				case ev := <-c:
+					fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev, time.Now().Format(time.RFC3339))
+				case <-tick.C:
+					fmt.Fprint(w, ": heartbeat\n\n")
+				case <-req.Context().Done():
+					return
+				}
+				flusher.Flush()
+			}
+		})
+}
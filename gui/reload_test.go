@@ -0,0 +1,93 @@
+package gui
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func TestWatch(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "book.gnucash")
+	if err := os.WriteFile(filename, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded := make(chan string, 10)
+	w := &Watch{
+		Filename: filename,
+		Interval: 10 * time.Millisecond,
+		Load: func(name string) (*types.Book, error) {
+			data, err := os.ReadFile(name)
+			loaded <- string(data)
+			return &types.Book{}, err
+		},
+	}
+	s := newStore(&types.Book{})
+	old := s.hist
+	done := make(chan struct{})
+	defer close(done)
+	go s.watch(w, done)
+
+	time.Sleep(50 * time.Millisecond)
+	if len(loaded) != 0 {
+		t.Fatalf("unchanged file was reloaded")
+	}
+	if err := os.WriteFile(filename, []byte("version 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case data := <-loaded:
+		if data != "version 2" {
+			t.Errorf("loaded %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("changed file was not reloaded")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(loaded) != 0 {
+		t.Errorf("file was reloaded twice")
+	}
+	var hist *history.History
+	s.read(func(h *history.History) error {
+		hist = h
+		return nil
+	})
+	if hist == old {
+		t.Fatal("book was not replaced")
+	}
+	if log := hist.Log(); len(log) != 1 || log[0].Action != "Reload" {
+		t.Errorf("got log %+v", log)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	s := newStore(&types.Book{})
+	srv := httptest.NewServer(serveEvents(s))
+	defer srv.Close()
+	resp, err := srv.Client().Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ctype := resp.Header.Get("Content-Type"); ctype != "text/event-stream" {
+		t.Errorf("got content type %q", ctype)
+	}
+	r := bufio.NewReader(resp.Body)
+	if line, _ := r.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("got %q", line)
+	}
+	r.ReadString('\n')
+	s.replace(&types.Book{}, "Reload book")
+	if line, _ := r.ReadString('\n'); line != "event: reload\n" {
+		t.Errorf("got %q", line)
+	}
+	if line, _ := r.ReadString('\n'); !strings.HasPrefix(line, "data: ") {
+		t.Errorf("got %q", line)
+	}
+}
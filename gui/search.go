@@ -0,0 +1,42 @@
+package gui
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/remyoudompheng/gocash/search"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// maxSearchResults is the number of flows shown by the search page.
+const maxSearchResults = 500
+
+// A searchResult is the outcome of a query of the search page.
+type searchResult struct {
+	Query string
+	Error string
+	Flows []*types.Flow // The first matching flows.
+	Total int
+}
+
+// pageSearch finds flows matching the query in form value q.
+func pageSearch(book *types.Book, w io.Writer, req *http.Request) error {
+	res := &searchResult{Query: req.FormValue("q")}
+	if res.Query != "" {
+		q, err := search.Parse(res.Query)
+		if err != nil {
+			res.Error = err.Error()
+		} else {
+			res.Flows = search.Search(book, q)
+			res.Total = len(res.Flows)
+			if len(res.Flows) > maxSearchResults {
+				res.Flows = res.Flows[:maxSearchResults]
+			}
+		}
+	}
+	return searchTpl.Execute(w, templateData{
+		Title:  "Search",
+		Book:   book,
+		Search: res,
+	})
+}
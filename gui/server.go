@@ -2,46 +2,119 @@ package gui
 
 import (
 	"bytes"
+	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"math"
 	"math/big"
 	"net/http"
 	_ "net/http/pprof"
+	"net/url"
 	"path/filepath"
 	"sort"
 
 	"github.com/remyoudompheng/go-misc/weblibs"
 
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/query"
+	"github.com/remyoudompheng/gocash/reports"
 	"github.com/remyoudompheng/gocash/types"
 )
 
-func StartServer(addr string, book *types.Book) error {
+// StartServer serves the book at addr. If watch is not nil, the book
+// is reloaded when its file changes.
+func StartServer(addr string, book *types.Book, watch *Watch) error {
 	parseTemplates()
 	err := weblibs.RegisterAll(http.DefaultServeMux)
 	if err != nil {
 		return err
 	}
-	http.Handle("/", curryBook(book, pageHome))
-	http.Handle("/account/", curryBook(book, pageAccount))
-	http.Handle("/static/", http.StripPrefix("/static/",
-		http.FileServer(http.Dir(StaticDir)),
-	))
+	s := newStore(book)
+	registerHandlers(http.DefaultServeMux, s)
+	if watch != nil {
+		log.Printf("watching %s for changes", watch.Filename)
+		go s.watch(watch, nil)
+	}
 	log.Printf("starting HTTP server at %s", addr)
 	return http.ListenAndServe(addr, nil)
 }
 
+// registerHandlers registers the pages of the book of s in mux.
+func registerHandlers(mux *http.ServeMux, s *store) {
+	mux.Handle("/", curryBook(s, pageHome))
+	mux.Handle("/account/", curryBook(s, pageAccount))
+	mux.Handle("/account/export", exportRegister(s))
+	mux.Handle("/account/edit", curryBook(s, pageEditAccount))
+	mux.Handle("/account/save", curryHistory(s, pageSaveAccount))
+	mux.Handle("/account/delete", curryHistory(s, pageDeleteAccount))
+	mux.Handle("/account/reconcile", curryBook(s, pageReconcile))
+	mux.Handle("/account/reconcile/save", curryHistory(s, pageSaveReconcile))
+	mux.Handle("/transaction/", curryBook(s, pageTransaction))
+	mux.Handle("/transaction/save", curryHistory(s, pageSaveTransaction))
+	mux.Handle("/transaction/delete", curryHistory(s, pageDeleteTransaction))
+	mux.Handle("/transaction/void", curryHistory(s, pageVoidTransaction))
+	mux.Handle("/history/", curryHistory(s, pageHistory))
+	mux.Handle("/history/undo", curryHistory(s, pageUndo))
+	mux.Handle("/history/redo", curryHistory(s, pageRedo))
+	mux.Handle("/history/export", exportHistory(s))
+	mux.Handle(apiPrefix, apiHandler(s))
+	mux.Handle("/search/", curryBook(s, pageSearch))
+	mux.Handle("/query/", curryBook(s, pageQuery))
+	mux.Handle("/networth/", curryBook(s, pageNetWorth))
+	mux.Handle("/expenses/", curryBook(s, pageExpenses))
+	mux.Handle("/spending/", curryBook(s, pageSpending))
+	mux.Handle("/portfolio/", curryBook(s, pagePortfolio))
+	mux.Handle("/events", serveEvents(s))
+	mux.Handle("/static/", http.StripPrefix("/static/",
+		http.FileServer(http.Dir(StaticDir)),
+	))
+}
+
 type httpHandler func(*types.Book, io.Writer, *http.Request) error
 
+// A redirect is returned by handlers to redirect the client
+// to another page, typically after a form submission.
+type redirect string
+
+func (r redirect) Error() string { return "redirect to " + string(r) }
+
 // curryBook makes http handlers out of handlers parameterized
-// by an accounting book.
-func curryBook(book *types.Book, h httpHandler) http.Handler {
+// by an accounting book, which they may only read.
+func curryBook(s *store, h httpHandler) http.Handler {
+	return serveLocked(s.read, func(hist *history.History, w io.Writer, req *http.Request) error {
+		return h(hist.Book, w, req)
+	})
+}
+
+// A historyHandler is a handler that edits the book through
+// its history.
+type historyHandler func(*history.History, io.Writer, *http.Request) error
+
+// curryHistory makes http handlers out of handlers parameterized
+// by the history of a book, which may edit it.
+func curryHistory(s *store, h historyHandler) http.Handler {
+	return serveLocked(s.write, h)
+}
+
+// serveLocked makes an http handler calling h through lock, which is
+// the read or write method of a store. The form is read before taking
+// the lock, so that slow clients do not hold it, and the response is
+// written after the lock is released.
+func serveLocked(lock func(func(*history.History) error) error, h historyHandler) http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, req *http.Request) {
 			log.Printf("%s %s from %s", req.Method, req.URL, req.RemoteAddr)
+			if err := req.ParseForm(); err != nil {
+				log.Printf("ERROR: %s", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
 			resp := new(bytes.Buffer)
-			err := h(book, resp, req)
-			if err == nil {
+			err := lock(func(hist *history.History) error { return h(hist, resp, req) })
+			if r, ok := err.(redirect); ok {
+				http.Redirect(w, req, string(r), http.StatusSeeOther)
+			} else if err == nil {
 				w.Write(resp.Bytes())
 			} else {
 				log.Printf("ERROR: %s", err)
@@ -57,9 +130,16 @@ func tplPath(name string) string { return filepath.Join(StaticDir, "templates",
 func parseTemplate(name string) (*template.Template, error) {
 	return template.New(name).
 		Funcs(template.FuncMap{
-		"sortAccts": sortAccts,
-		"cumul":     cumulFlows,
-	}).
+			"sortAccts":    sortAccts,
+			"cumul":        cumulFlows,
+			"amount":       ratAmount,
+			"periods":      periodNames,
+			"chart":        balanceChart,
+			"percent":      percent,
+			"share":        share,
+			"accountTypes": accountTypes,
+			"queryColumns": queryColumns,
+		}).
 		ParseFiles(tplPath("common"), tplPath(name))
 }
 
@@ -72,6 +152,30 @@ func cumulFlows(flows []*types.Flow) (bals []*types.Amount) {
 	return
 }
 
+func accountTypes() []string { return types.AccountTypes }
+
+func queryColumns() []string { return query.ColumnNames }
+
+func periodNames() (names []string) {
+	for p := reports.Monthly; p <= reports.FiscalYear; p++ {
+		names = append(names, p.String())
+	}
+	return
+}
+
+// percent formats a ratio as a percentage.
+func percent(x float64) string {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return "-"
+	}
+	return fmt.Sprintf("%+.1f%%", 100*x)
+}
+
+// share formats a fraction of a total as a percentage.
+func share(x float64) string { return fmt.Sprintf("%.1f%%", 100*x) }
+
+func ratAmount(x *big.Rat) *types.Amount { return (*types.Amount)(x) }
+
 func sortAccts(b *types.Book) []*types.Account {
 	accts := make([]*types.Account, 0, len(b.Accounts))
 	for _, a := range b.Accounts {
@@ -92,16 +196,51 @@ func (s byTypeAndName) Less(i, j int) bool {
 	return s[i].Name < s[j].Name
 }
 
-var homeTpl, bookTpl, accountTpl *template.Template
+var (
+	homeTpl, bookTpl, accountTpl          *template.Template
+	networthTpl, expensesTpl, spendingTpl *template.Template
+	portfolioTpl, transactionTpl          *template.Template
+	accountEditTpl, reconcileTpl          *template.Template
+	historyTpl, searchTpl, queryTpl       *template.Template
+)
 
 func parseTemplates() {
 	homeTpl = template.Must(parseTemplate("home")).Lookup("common")
 	bookTpl = template.Must(parseTemplate("book")).Lookup("common")
 	accountTpl = template.Must(parseTemplate("account")).Lookup("common")
+	networthTpl = template.Must(parseTemplate("networth")).Lookup("common")
+	expensesTpl = template.Must(parseTemplate("expenses")).Lookup("common")
+	spendingTpl = template.Must(parseTemplate("spending")).Lookup("common")
+	portfolioTpl = template.Must(parseTemplate("portfolio")).Lookup("common")
+	transactionTpl = template.Must(parseTemplate("transaction")).Lookup("common")
+	accountEditTpl = template.Must(parseTemplate("accountedit")).Lookup("common")
+	reconcileTpl = template.Must(parseTemplate("reconcile")).Lookup("common")
+	historyTpl = template.Must(parseTemplate("history")).Lookup("common")
+	searchTpl = template.Must(parseTemplate("search")).Lookup("common")
+	queryTpl = template.Must(parseTemplate("query")).Lookup("common")
 }
 
 type templateData struct {
 	Title   string
 	Book    *types.Book
 	Account *types.Account
+
+	// Editors.
+	Transaction *transactionForm
+	AccountForm *accountForm
+	Reconcile   *reconcileForm
+	History     *history.History
+	Log         []history.Record
+	Search      *searchResult
+	Query       *queryResult
+
+	// Reports.
+	Form      url.Values
+	Currency  string
+	NetWorth  *reports.NetWorthReport
+	Expenses  *reports.ExpenseReport
+	Spending  *reports.SpendingReport
+	Portfolio *reports.PortfolioReport
+	Chart     template.HTML
+	Pie       template.HTML
 }
@@ -0,0 +1,76 @@
+package gui
+
+import (
+	"log"
+	"sync"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A store holds the history of the served book and guards it:
+// handlers reading the book hold a read lock, and edits hold the write
+// lock. Reloading the file replaces the history and the book as a
+// whole.
+type store struct {
+	mu   sync.RWMutex
+	hist *history.History
+
+	events  sync.Mutex
+	clients map[chan string]bool // Channels of event streams.
+}
+
+func newStore(book *types.Book) *store {
+	return &store{hist: history.New(book), clients: make(map[chan string]bool)}
+}
+
+// read calls f with the current history while holding a read lock.
+// Neither f nor its results may keep references to the book after
+// it returns.
+func (s *store) read(f func(hist *history.History) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return f(s.hist)
+}
+
+// write calls f with the current history while holding the write
+// lock.
+func (s *store) write(f func(hist *history.History) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return f(s.hist)
+}
+
+// replace replaces the book and notifies event streams.
+func (s *store) replace(book *types.Book, description string) {
+	s.mu.Lock()
+	if e := s.hist.NextUndo(); e != nil {
+		log.Printf("WARNING: %s discards edits, last one: %s", description, e.Description)
+	}
+	s.hist = s.hist.Reload(book, description)
+	s.mu.Unlock()
+
+	s.events.Lock()
+	defer s.events.Unlock()
+	for c := range s.clients {
+		select {
+		case c <- "reload":
+		default:
+			// The client has an event pending.
+		}
+	}
+}
+
+func (s *store) subscribe() chan string {
+	c := make(chan string, 1)
+	s.events.Lock()
+	s.clients[c] = true
+	s.events.Unlock()
+	return c
+}
+
+func (s *store) unsubscribe(c chan string) {
+	s.events.Lock()
+	delete(s.clients, c)
+	s.events.Unlock()
+}
@@ -0,0 +1,164 @@
+package gui
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+// TestConcurrentAccess reads and edits the book from concurrent
+// requests, and reloads it. It is meant to be run with the race
+// detector.
+func TestConcurrentAccess(t *testing.T) {
+	StaticDir = "../static/"
+	parseTemplates()
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	s := newStore(book)
+	mux := http.NewServeMux()
+	registerHandlers(mux, s)
+
+	var failures sync.Map
+	do := func(method, u, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, u, strings.NewReader(body))
+		if method == "POST" && !strings.HasPrefix(u, apiPrefix) {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		// Edits may fail when other requests changed the book,
+		// but reads may not.
+		if method == "GET" && rec.Code != http.StatusOK {
+			failures.Store(u, rec.Body.String())
+		}
+		return rec
+	}
+
+	reads := []string{
+		"/",
+		"/account/?name=/Assets/Broker",
+		"/networth/?period=quarterly",
+		"/spending/",
+		"/portfolio/",
+		"/search/?q=" + url.QueryEscape("account:/Assets/** date:2013"),
+		"/query/?q=" + url.QueryEscape("SELECT account, sum(amount) GROUP BY 1"),
+		"/history/export?format=json",
+		"/account/export?name=/Assets/Broker",
+		apiPrefix + "transactions",
+		apiPrefix + "accounts",
+		apiPrefix + "history",
+	}
+	const rounds = 20
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				do("GET", reads[(i+j)%len(reads)], "")
+			}
+		}(i)
+	}
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < rounds/2; j++ {
+				rec := do("POST", apiPrefix+"transactions", fmt.Sprintf(`{"Date": "2014-02-%02d", "Currency": "USD",
+					"Description": "Writer %d", "Flows": [{"AccountName": "/Assets/Broker", "Price": "-%d"},
+					{"AccountName": "/Expenses/Food", "Price": "%d"}]}`, j+1, i, j+1, j+1))
+				var trn apiTransaction
+				json.Unmarshal(rec.Body.Bytes(), &trn)
+				form := url.Values{"id": {string(trn.Id)}, "reason": {"Duplicate"}}
+				do("POST", "/transaction/void", form.Encode())
+				do("POST", apiPrefix+"history/undo", "")
+				do("POST", "/history/redo", "")
+				do("DELETE", apiPrefix+"transactions/"+string(trn.Id), "")
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 3; j++ {
+			b, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			b.Recompute()
+			s.replace(b, "Reload stocks.gml2")
+		}
+	}()
+	wg.Wait()
+
+	failures.Range(func(k, v interface{}) bool {
+		t.Errorf("%s: %s", k, v)
+		return true
+	})
+	s.read(func(hist *history.History) error {
+		for acct, flows := range hist.Book.Flows {
+			sum := new(big.Rat)
+			for _, f := range flows {
+				sum.Add(sum, f.Price.Rat())
+			}
+			if hist.Book.Balance[acct].Rat().Cmp(sum) != 0 {
+				t.Errorf("balance of %s is %s, expected %s", acct.Name, hist.Book.Balance[acct], sum.FloatString(2))
+			}
+		}
+		return nil
+	})
+}
+
+// TestStalledClient checks that edits whose body is still being sent
+// do not block other requests.
+func TestStalledClient(t *testing.T) {
+	StaticDir = "../static/"
+	parseTemplates()
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	mux := http.NewServeMux()
+	registerHandlers(mux, newStore(book))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	for _, u := range []string{"/transaction/save", apiPrefix + "transactions"} {
+		body, stall := io.Pipe()
+		defer stall.Close()
+		req, _ := http.NewRequest("POST", srv.URL+u, body)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if strings.HasPrefix(u, apiPrefix) {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		go srv.Client().Do(req)
+		stall.Write([]byte("id="))
+	}
+	client := &http.Client{Timeout: 2 * time.Second}
+	for _, u := range []string{"/", apiPrefix + "accounts"} {
+		resp, err := client.Get(srv.URL + u)
+		if err != nil {
+			t.Fatalf("%s: %s", u, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: got status %s", u, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package gui
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/history"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A transactionForm holds the fields of the transaction editor,
+// as strings so that invalid input can be shown back to the user.
+type transactionForm struct {
+	Id          types.GUID // Empty for a new transaction.
+	Date        string
+	Number      string
+	Description string
+	Currency    string
+	Notes       string
+	Splits      []splitRow
+	Void        bool // The transaction is voided and cannot be edited.
+	VoidReason  string
+	Back        string // Name of the account page to return to.
+	Error       string
+}
+
+// A splitRow is a line of the split table of the transaction editor.
+type splitRow struct {
+	Id       types.GUID
+	Account  string
+	Memo     string
+	Amount   string // Value in the transaction currency.
+	Quantity string // Amount in the account commodity, if different.
+}
+
+// emptySplits is the number of blank split rows of the editor.
+const emptySplits = 2
+
+// pageTransaction shows the transaction editor: for the transaction
+// given by the id form value, for a copy of the transaction given by
+// the dup form value, or for a new transaction in the account given
+// by the back form value.
+func pageTransaction(book *types.Book, w io.Writer, req *http.Request) error {
+	req.ParseForm()
+	form := transactionForm{Back: req.Form.Get("back")}
+	switch {
+	case req.Form.Get("id") != "":
+		id := types.GUID(req.Form.Get("id"))
+		trn := book.Transactions[id]
+		if trn == nil {
+			return fmt.Errorf("no such transaction: %q", id)
+		}
+		form.fill(trn)
+	case req.Form.Get("dup") != "":
+		id := types.GUID(req.Form.Get("dup"))
+		trn := book.Transactions[id]
+		if trn == nil {
+			return fmt.Errorf("no such transaction: %q", id)
+		}
+		form.fill(trn)
+		form.Id = ""
+		form.Void, form.VoidReason = false, ""
+		form.Date = time.Now().Format("2006-01-02")
+		for i := range form.Splits {
+			form.Splits[i].Id = ""
+		}
+	default:
+		form.Date = time.Now().Format("2006-01-02")
+		form.Currency = book.DefaultCurrency()
+		if acct := book.AccountByName(form.Back); acct != nil {
+			form.Splits = append(form.Splits, splitRow{Account: acct.Name})
+			if acct.Unit != "" {
+				form.Currency = acct.Unit
+			}
+		}
+	}
+	return renderTransaction(book, w, &form)
+}
+
+func renderTransaction(book *types.Book, w io.Writer, form *transactionForm) error {
+	for i := 0; i < emptySplits; i++ {
+		form.Splits = append(form.Splits, splitRow{})
+	}
+	title := "New transaction"
+	switch {
+	case form.Void:
+		title = "Voided transaction"
+	case form.Id != "":
+		title = "Edit transaction"
+	}
+	return transactionTpl.Execute(w, templateData{
+		Title:       title,
+		Book:        book,
+		Transaction: form,
+	})
+}
+
+// fill sets the fields of the form from an existing transaction.
+// Voided transactions show the amounts they had before being voided.
+func (form *transactionForm) fill(trn *types.Transaction) {
+	form.Id = trn.Id
+	form.Date = trn.Date.Format("2006-01-02")
+	form.Number = trn.Number
+	form.Description = trn.Description
+	form.Currency = trn.Currency
+	form.Notes = trn.Notes
+	form.Void, form.VoidReason = trn.IsVoid(), trn.VoidReason
+	for _, f := range trn.Flows {
+		price, qty := f.Price, f.Quantity
+		if f.State == types.Voided && f.VoidPrice != nil {
+			price, qty = f.VoidPrice, f.VoidQuantity
+		}
+		row := splitRow{
+			Id:     f.Id,
+			Memo:   f.Memo,
+			Amount: price.Decimal(),
+		}
+		if f.Account != nil {
+			row.Account = f.Account.Name
+		}
+		if qty != nil && qty.Rat().Cmp(price.Rat()) != 0 {
+			row.Quantity = qty.Decimal()
+		}
+		form.Splits = append(form.Splits, row)
+	}
+}
+
+// pageSaveTransaction creates or updates a transaction from the
+// posted editor form. Invalid input is shown again with an error.
+func pageSaveTransaction(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	book := hist.Book
+	req.ParseForm()
+	form := transactionForm{
+		Id:          types.GUID(req.PostForm.Get("id")),
+		Date:        req.PostForm.Get("date"),
+		Number:      strings.TrimSpace(req.PostForm.Get("number")),
+		Description: strings.TrimSpace(req.PostForm.Get("description")),
+		Currency:    strings.TrimSpace(req.PostForm.Get("currency")),
+		Notes:       strings.TrimSpace(req.PostForm.Get("notes")),
+		Back:        req.PostForm.Get("back"),
+	}
+	ids, accts, memos := req.PostForm["split"], req.PostForm["account"], req.PostForm["memo"]
+	amounts, qtys := req.PostForm["amount"], req.PostForm["quantity"]
+	for i := range accts {
+		row := splitRow{Account: strings.TrimSpace(accts[i])}
+		if i < len(ids) {
+			row.Id = types.GUID(ids[i])
+		}
+		if i < len(memos) {
+			row.Memo = strings.TrimSpace(memos[i])
+		}
+		if i < len(amounts) {
+			row.Amount = strings.TrimSpace(amounts[i])
+		}
+		if i < len(qtys) {
+			row.Quantity = strings.TrimSpace(qtys[i])
+		}
+		if row.Account != "" || row.Amount != "" || row.Quantity != "" {
+			form.Splits = append(form.Splits, row)
+		}
+	}
+
+	var old *types.Transaction
+	if form.Id != "" {
+		old = book.Transactions[form.Id]
+		if old == nil {
+			return fmt.Errorf("no such transaction: %q", form.Id)
+		}
+		if old.IsVoid() {
+			return fmt.Errorf("transaction %s is voided and cannot be edited", form.Id)
+		}
+	}
+	trn, err := form.transaction(book, old)
+	if err == nil {
+		err = trn.Check()
+	}
+	if err == nil {
+		err = hist.Do(history.SaveTransaction(book, trn))
+	}
+	if err != nil {
+		form.Error = err.Error()
+		return renderTransaction(book, w, &form)
+	}
+	return redirect(form.backURL(trn))
+}
+
+// transaction builds a transaction from the form. Flows keep the
+// identifier and reconciliation state of the flows of old that
+// they replace.
+func (form *transactionForm) transaction(book *types.Book, old *types.Transaction) (*types.Transaction, error) {
+	date, err := time.ParseInLocation("2006-01-02", form.Date, time.Local)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date %q", form.Date)
+	}
+	trn := &types.Transaction{
+		Id:          types.NewGUID(),
+		Date:        date,
+		Stamp:       time.Now(),
+		Currency:    form.Currency,
+		Description: form.Description,
+		Notes:       form.Notes,
+		Number:      form.Number,
+	}
+	oldFlows := make(map[types.GUID]types.Flow)
+	if old != nil {
+		trn.Id, trn.Stamp, trn.ExternalId = old.Id, old.Stamp, old.ExternalId
+		// Keep the time of day of unchanged dates.
+		if old.Date.Format("2006-01-02") == form.Date {
+			trn.Date = old.Date
+		}
+		for _, f := range old.Flows {
+			oldFlows[f.Id] = f
+		}
+	}
+	for i, row := range form.Splits {
+		acct := book.AccountByName(row.Account)
+		if acct == nil {
+			return nil, fmt.Errorf("split %d: no such account %q", i+1, row.Account)
+		}
+		price, ok := new(big.Rat).SetString(row.Amount)
+		if !ok {
+			return nil, fmt.Errorf("split %d: invalid amount %q", i+1, row.Amount)
+		}
+		qty := new(big.Rat).Set(price)
+		if row.Quantity != "" {
+			if _, ok := qty.SetString(row.Quantity); !ok {
+				return nil, fmt.Errorf("split %d: invalid quantity %q", i+1, row.Quantity)
+			}
+		} else if acct.Unit != "" && acct.Unit != trn.Currency {
+			return nil, fmt.Errorf("split %d: a quantity in %s is required for account %s",
+				i+1, acct.Unit, acct.Name)
+		}
+		f := types.Flow{
+			Id:       types.NewGUID(),
+			Memo:     row.Memo,
+			Account:  acct,
+			Price:    (*types.Amount)(price),
+			Quantity: (*types.Amount)(qty),
+		}
+		if prev, ok := oldFlows[row.Id]; ok && row.Id != "" {
+			f.Id = prev.Id
+			f.State, f.ReconciledTime = prev.State, prev.ReconciledTime
+		}
+		trn.Flows = append(trn.Flows, f)
+	}
+	return trn, nil
+}
+
+// backURL returns the page to show after editing trn: the account
+// page given by the back field, or the page of its first account.
+func (form *transactionForm) backURL(trn *types.Transaction) string {
+	name := form.Back
+	if name == "" && trn != nil && len(trn.Flows) > 0 {
+		name = trn.Flows[0].Account.Name
+	}
+	if name == "" {
+		return "/"
+	}
+	return "/account/?name=" + url.QueryEscape(name)
+}
+
+// pageDeleteTransaction deletes the transaction given by the posted
+// id form value.
+func pageDeleteTransaction(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	req.ParseForm()
+	id := types.GUID(req.PostForm.Get("id"))
+	trn := hist.Book.Transactions[id]
+	if trn == nil {
+		return fmt.Errorf("no such transaction: %q", id)
+	}
+	if err := hist.Do(history.DeleteTransaction(hist.Book, id)); err != nil {
+		return err
+	}
+	form := transactionForm{Back: req.PostForm.Get("back")}
+	return redirect(form.backURL(trn))
+}
+
+// pageVoidTransaction voids the transaction given by the posted id
+// form value, with the reason form value, or restores it if the
+// action form value is unvoid.
+func pageVoidTransaction(hist *history.History, w io.Writer, req *http.Request) error {
+	if req.Method != "POST" {
+		return fmt.Errorf("method %s not allowed", req.Method)
+	}
+	req.ParseForm()
+	id := types.GUID(req.PostForm.Get("id"))
+	cmd := history.VoidTransaction(hist.Book, id, strings.TrimSpace(req.PostForm.Get("reason")), time.Now())
+	if req.PostForm.Get("action") == "unvoid" {
+		cmd = history.UnvoidTransaction(hist.Book, id)
+	}
+	if err := hist.Do(cmd); err != nil {
+		return err
+	}
+	return redirect("/transaction/?id=" + url.QueryEscape(string(id)) +
+		"&back=" + url.QueryEscape(req.PostForm.Get("back")))
+}
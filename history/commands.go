@@ -0,0 +1,129 @@
+package history
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/remyoudompheng/gocash/reconcile"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// SaveTransaction adds trn to the book, replacing the transaction
+// with the same identifier if any.
+func SaveTransaction(book *types.Book, trn *types.Transaction) Command {
+	verb := "Add"
+	if book.Transactions[trn.Id] != nil {
+		verb = "Edit"
+	}
+	return Command{
+		Description: fmt.Sprintf("%s transaction %q of %s", verb, trn.Description, trn.Date.Format("2006-01-02")),
+		Apply: func(e *Edit) error {
+			e.Transaction(trn.Id)
+			e.Book().Transactions[trn.Id] = trn
+			return nil
+		},
+	}
+}
+
+// DeleteTransaction removes transaction id from the book.
+func DeleteTransaction(book *types.Book, id types.GUID) Command {
+	return Command{
+		Description: "Delete " + describe(book, id),
+		Apply: func(e *Edit) error {
+			if e.Transaction(id) == nil {
+				return fmt.Errorf("no such transaction: %q", id)
+			}
+			delete(e.Book().Transactions, id)
+			return nil
+		},
+	}
+}
+
+// VoidTransaction voids transaction id with the given reason.
+func VoidTransaction(book *types.Book, id types.GUID, reason string, now time.Time) Command {
+	return Command{
+		Description: "Void " + describe(book, id),
+		Apply: func(e *Edit) error {
+			trn := e.Transaction(id)
+			if trn == nil {
+				return fmt.Errorf("no such transaction: %q", id)
+			}
+			return trn.Void(reason, now)
+		},
+	}
+}
+
+// UnvoidTransaction restores the amounts of voided transaction id.
+func UnvoidTransaction(book *types.Book, id types.GUID) Command {
+	return Command{
+		Description: "Unvoid " + describe(book, id),
+		Apply: func(e *Edit) error {
+			trn := e.Transaction(id)
+			if trn == nil {
+				return fmt.Errorf("no such transaction: %q", id)
+			}
+			return trn.Unvoid()
+		},
+	}
+}
+
+// EditAccount applies f, which may add, move or modify accounts
+// but not transactions.
+func EditAccount(description string, f func(book *types.Book) error) Command {
+	return Command{
+		Description: description,
+		Apply:       func(e *Edit) error { return f(e.Book()) },
+	}
+}
+
+// DeleteAccount deletes acct after assigning its flows to reassign.
+func DeleteAccount(acct, reassign *types.Account) Command {
+	return Command{
+		Description: "Delete account " + acct.Name,
+		Apply: func(e *Edit) error {
+			for id, trn := range e.Book().Transactions {
+				for _, f := range trn.Flows {
+					if f.Account == acct {
+						e.Transaction(id)
+						break
+					}
+				}
+			}
+			return e.Book().DeleteAccount(acct, reassign)
+		},
+	}
+}
+
+// Reconcile finishes the reconciliation of acct with the given
+// cleared flows if finish is set, or postpones it.
+func Reconcile(acct *types.Account, st reconcile.Statement, cleared []*types.Flow, finish bool) Command {
+	desc := "Postpone reconciliation of " + acct.Name
+	if finish {
+		desc = "Reconcile " + acct.Name
+	}
+	return Command{
+		Description: desc + " on " + st.Date.Format("2006-01-02"),
+		Apply: func(e *Edit) error {
+			for _, f := range reconcile.Candidates(e.Book(), acct, st.Date) {
+				e.Transaction(f.Parent.Id)
+			}
+			for _, f := range cleared {
+				e.Transaction(f.Parent.Id)
+			}
+			if finish {
+				return reconcile.Finish(e.Book(), acct, st, cleared)
+			}
+			reconcile.Postpone(e.Book(), acct, st, cleared)
+			return nil
+		},
+	}
+}
+
+// describe names transaction id in descriptions of commands.
+func describe(book *types.Book, id types.GUID) string {
+	trn := book.Transactions[id]
+	if trn == nil {
+		return "transaction " + string(id)
+	}
+	return fmt.Sprintf("transaction %q of %s", trn.Description, trn.Date.Format("2006-01-02"))
+}
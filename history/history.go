@@ -0,0 +1,262 @@
+// Package history implements reversible edits of a book, with
+// undo and redo, and an audit log of changes.
+//
+// Every edit is a Command applied through a History. While it is
+// applied, the command declares the transactions it modifies to an
+// Edit, which keeps their previous state; accounts are compared
+// before and after the edit. The changed objects are recorded so that
+// the edit can be undone and redone.
+package history
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Command is a reversible edit of a book.
+type Command struct {
+	Description string
+	// Apply performs the edit. It must call Edit.Transaction before
+	// modifying, adding or removing a transaction.
+	Apply func(e *Edit) error
+}
+
+// An Edit records the state of a book before a command is applied.
+type Edit struct {
+	book     *types.Book
+	accounts map[*types.Account]*types.Account
+	trns     map[types.GUID]*types.Transaction
+}
+
+// Book returns the book being edited.
+func (e *Edit) Book() *types.Book { return e.book }
+
+// Transaction records the state of transaction id before it is
+// modified, and returns it, or nil if it does not exist yet.
+func (e *Edit) Transaction(id types.GUID) *types.Transaction {
+	trn := e.book.Transactions[id]
+	if _, ok := e.trns[id]; !ok {
+		e.trns[id] = cloneTransaction(trn)
+	}
+	return trn
+}
+
+// A state is the state of some accounts and transactions of a book.
+// Nil values stand for objects that are not in the book.
+type state struct {
+	accounts map[*types.Account]*types.Account
+	trns     map[types.GUID]*types.Transaction
+}
+
+// changes returns the states before and after the edit of the
+// objects it changed.
+func (e *Edit) changes() (before, after state) {
+	before = state{make(map[*types.Account]*types.Account), e.trns}
+	after = state{make(map[*types.Account]*types.Account), make(map[types.GUID]*types.Transaction)}
+	for acct, old := range e.accounts {
+		if e.book.Accounts[acct.Id] != acct {
+			before.accounts[acct], after.accounts[acct] = old, nil
+		} else if !sameAccount(old, acct) {
+			before.accounts[acct], after.accounts[acct] = old, cloneAccount(acct)
+		}
+	}
+	for _, acct := range e.book.Accounts {
+		if _, ok := e.accounts[acct]; !ok {
+			before.accounts[acct], after.accounts[acct] = nil, cloneAccount(acct)
+		}
+	}
+	for id := range e.trns {
+		after.trns[id] = cloneTransaction(e.book.Transactions[id])
+	}
+	return before, after
+}
+
+// restore sets the objects of s in the book and recomputes it.
+func (s state) restore(book *types.Book) {
+	for acct, a := range s.accounts {
+		if a == nil {
+			delete(book.Accounts, acct.Id)
+			continue
+		}
+		*acct = *cloneAccount(a)
+		book.Accounts[acct.Id] = acct
+	}
+	for id, trn := range s.trns {
+		if trn == nil {
+			delete(book.Transactions, id)
+		} else {
+			book.Transactions[id] = cloneTransaction(trn)
+		}
+	}
+	book.Recompute()
+}
+
+// An Entry is an edit of the book that can be undone.
+type Entry struct {
+	Time        time.Time
+	Description string
+	before      state
+	after       state
+}
+
+// A Record is a line of the audit log.
+type Record struct {
+	Time        time.Time
+	Action      string // Edit, Undo, Redo or Reload.
+	Description string
+}
+
+// MaxUndo is the number of edits that can be undone.
+const MaxUndo = 100
+
+// A History applies commands to a book and keeps the list of edits
+// that can be undone and redone. It is not safe for concurrent use.
+type History struct {
+	Book *types.Book
+
+	undo []*Entry
+	redo []*Entry
+	log  []Record
+}
+
+// New returns an empty history of book.
+func New(book *types.Book) *History {
+	return &History{Book: book}
+}
+
+// Reload returns a history of book, which replaces the book of h,
+// for example after its file changed. The audit log is kept, but the
+// edits of h can no longer be undone nor redone.
+func (h *History) Reload(book *types.Book, description string) *History {
+	n := &History{Book: book, log: h.Log()}
+	n.log = append(n.log, Record{Time: time.Now(), Action: "Reload", Description: description})
+	return n
+}
+
+// Do applies cmd to the book. If cmd fails, the book is left
+// unchanged and the error is returned.
+func (h *History) Do(cmd Command) error {
+	e := &Edit{
+		book:     h.Book,
+		accounts: make(map[*types.Account]*types.Account, len(h.Book.Accounts)),
+		trns:     make(map[types.GUID]*types.Transaction),
+	}
+	for _, acct := range h.Book.Accounts {
+		e.accounts[acct] = cloneAccount(acct)
+	}
+	err := cmd.Apply(e)
+	before, after := e.changes()
+	if err != nil {
+		before.restore(h.Book)
+		return err
+	}
+	h.Book.Recompute()
+	entry := &Entry{Time: time.Now(), Description: cmd.Description, before: before, after: after}
+	h.undo = append(h.undo, entry)
+	if len(h.undo) > MaxUndo {
+		h.undo = h.undo[len(h.undo)-MaxUndo:]
+	}
+	h.redo = nil
+	h.record("Edit", entry)
+	return nil
+}
+
+// Undo reverts the last edit.
+func (h *History) Undo() error {
+	if len(h.undo) == 0 {
+		return errors.New("nothing to undo")
+	}
+	entry := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	entry.before.restore(h.Book)
+	h.redo = append(h.redo, entry)
+	h.record("Undo", entry)
+	return nil
+}
+
+// Redo applies again the last undone edit.
+func (h *History) Redo() error {
+	if len(h.redo) == 0 {
+		return errors.New("nothing to redo")
+	}
+	entry := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	entry.after.restore(h.Book)
+	h.undo = append(h.undo, entry)
+	h.record("Redo", entry)
+	return nil
+}
+
+// NextUndo returns the edit that Undo would revert, or nil.
+func (h *History) NextUndo() *Entry {
+	if len(h.undo) == 0 {
+		return nil
+	}
+	return h.undo[len(h.undo)-1]
+}
+
+// NextRedo returns the edit that Redo would apply, or nil.
+func (h *History) NextRedo() *Entry {
+	if len(h.redo) == 0 {
+		return nil
+	}
+	return h.redo[len(h.redo)-1]
+}
+
+// Log returns the audit log, oldest first.
+func (h *History) Log() []Record {
+	return append([]Record(nil), h.log...)
+}
+
+func (h *History) record(action string, entry *Entry) {
+	h.log = append(h.log, Record{Time: time.Now(), Action: action, Description: entry.Description})
+}
+
+// cloneAccount returns a copy of acct with its own list of children.
+func cloneAccount(acct *types.Account) *types.Account {
+	a := *acct
+	a.Children = append([]*types.Account(nil), acct.Children...)
+	return &a
+}
+
+// sameAccount reports whether two states of an account are equal.
+func sameAccount(a, b *types.Account) bool {
+	x, y := *a, *b
+	x.Children, y.Children = nil, nil
+	if !reflect.DeepEqual(x, y) || len(a.Children) != len(b.Children) {
+		return false
+	}
+	for i := range a.Children {
+		if a.Children[i] != b.Children[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// cloneTransaction returns a deep copy of trn, or nil.
+func cloneTransaction(trn *types.Transaction) *types.Transaction {
+	if trn == nil {
+		return nil
+	}
+	t := *trn
+	t.Flows = make([]types.Flow, len(trn.Flows))
+	for i, f := range trn.Flows {
+		f.Price = cloneAmount(f.Price)
+		f.Quantity = cloneAmount(f.Quantity)
+		f.VoidPrice = cloneAmount(f.VoidPrice)
+		f.VoidQuantity = cloneAmount(f.VoidQuantity)
+		t.Flows[i] = f
+	}
+	return &t
+}
+
+func cloneAmount(x *types.Amount) *types.Amount {
+	if x == nil {
+		return nil
+	}
+	return new(types.Amount).SetRat(x.Rat())
+}
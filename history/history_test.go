@@ -0,0 +1,229 @@
+package history
+
+import (
+	"bytes"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/reconcile"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func amount(s string) *types.Amount {
+	x, _ := new(big.Rat).SetString(s)
+	return (*types.Amount)(x)
+}
+
+func testBook() *types.Book {
+	root := &types.Account{Id: "root", Name: "/", Type: "ROOT"}
+	bank := &types.Account{Id: "bank", Name: "/Bank", Type: "BANK", Unit: "EUR"}
+	food := &types.Account{Id: "food", Name: "/Food", Type: "EXPENSE", Unit: "EUR"}
+	root.Children = []*types.Account{bank, food}
+	book := &types.Book{
+		Accounts:     map[types.GUID]*types.Account{"root": root, "bank": bank, "food": food},
+		Transactions: make(map[types.GUID]*types.Transaction),
+	}
+	book.Transactions["t1"] = transaction("t1", "2013-01-10", "Bakery", "8.50", bank, food)
+	book.Recompute()
+	return book
+}
+
+func transaction(id, day, desc, amt string, from, to *types.Account) *types.Transaction {
+	return &types.Transaction{
+		Id: types.GUID(id), Date: date(day), Currency: "EUR", Description: desc,
+		Flows: []types.Flow{
+			{Id: types.GUID(id + "a"), Account: from, Price: amount("-" + amt), Quantity: amount("-" + amt)},
+			{Id: types.GUID(id + "b"), Account: to, Price: amount(amt), Quantity: amount(amt)},
+		},
+	}
+}
+
+func balance(book *types.Book, id types.GUID) string {
+	return book.Balance[book.Accounts[id]].String()
+}
+
+func TestUndoRedo(t *testing.T) {
+	book := testBook()
+	h := New(book)
+	bank, food := book.Accounts["bank"], book.Accounts["food"]
+
+	steps := []struct {
+		cmd  Command
+		bank string
+	}{
+		{SaveTransaction(book, transaction("t2", "2013-01-12", "Market", "20", bank, food)), "-28.50"},
+		{VoidTransaction(book, "t1", "Entered twice", date("2013-01-15")), "-20.00"},
+		{DeleteTransaction(book, "t2"), "0.00"},
+	}
+	for i, s := range steps {
+		if err := h.Do(s.cmd); err != nil {
+			t.Fatalf("step %d: %s", i, err)
+		}
+		if b := balance(book, "bank"); b != s.bank {
+			t.Errorf("step %d: got balance %s, expected %s", i, b, s.bank)
+		}
+	}
+	for i := len(steps) - 1; i >= 0; i-- {
+		if err := h.Undo(); err != nil {
+			t.Fatal(err)
+		}
+		expected := "-8.50"
+		if i > 0 {
+			expected = steps[i-1].bank
+		}
+		if b := balance(book, "bank"); b != expected {
+			t.Errorf("undo %d: got balance %s, expected %s", i, b, expected)
+		}
+	}
+	if err := h.Undo(); err == nil {
+		t.Errorf("undo succeeded with an empty history")
+	}
+	if book.Transactions["t1"].IsVoid() || len(book.Transactions) != 1 {
+		t.Errorf("book not restored after undo")
+	}
+
+	for i, s := range steps {
+		if err := h.Redo(); err != nil {
+			t.Fatal(err)
+		}
+		if b := balance(book, "bank"); b != s.bank {
+			t.Errorf("redo %d: got balance %s, expected %s", i, b, s.bank)
+		}
+	}
+	if h.NextRedo() != nil || h.NextUndo().Description != steps[2].cmd.Description {
+		t.Errorf("unexpected next undo %v and redo %v", h.NextUndo(), h.NextRedo())
+	}
+
+	// A new edit clears the edits to redo.
+	h.Undo()
+	h.Do(SaveTransaction(book, transaction("t3", "2013-01-20", "Butcher", "12", bank, food)))
+	if h.NextRedo() != nil {
+		t.Errorf("edits to redo remain after a new edit")
+	}
+	if log := h.Log(); len(log) != 11 || log[10].Action != "Edit" || log[9].Action != "Undo" {
+		t.Errorf("got log %+v", log)
+	}
+}
+
+func TestAccounts(t *testing.T) {
+	book := testBook()
+	h := New(book)
+	food := book.Accounts["food"]
+
+	var groceries *types.Account
+	err := h.Do(EditAccount("Add account /Food/Groceries", func(book *types.Book) (err error) {
+		groceries, err = book.AddAccount(food, "Groceries", "EXPENSE", "EUR", "")
+		return err
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Do(DeleteAccount(food, groceries)); err == nil {
+		t.Fatalf("deleted an account with subaccounts")
+	}
+	if err := h.Do(EditAccount("Rename", func(book *types.Book) error {
+		return book.MoveAccount(food, nil, "Meals")
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if groceries.Name != "/Meals/Groceries" {
+		t.Errorf("got name %s after renaming the parent", groceries.Name)
+	}
+	h.Undo()
+	if groceries.Name != "/Food/Groceries" || food.Name != "/Food" {
+		t.Errorf("got names %s, %s after undo", food.Name, groceries.Name)
+	}
+	h.Undo()
+	if book.Accounts[groceries.Id] != nil || len(food.Children) != 0 {
+		t.Errorf("account still exists after undo")
+	}
+	h.Redo()
+	if book.Accounts[groceries.Id] != groceries || len(food.Children) != 1 {
+		t.Errorf("account does not exist after redo")
+	}
+
+	// Deleting an account reassigns its flows.
+	bank := book.Accounts["bank"]
+	var cash *types.Account
+	h.Do(EditAccount("Add account /Cash", func(book *types.Book) (err error) {
+		cash, err = book.AddAccount(nil, "Cash", "CASH", "EUR", "")
+		return err
+	}))
+	if err := h.Do(DeleteAccount(bank, cash)); err != nil {
+		t.Fatal(err)
+	}
+	if book.Accounts["bank"] != nil || book.Balance[cash].String() != "-8.50" {
+		t.Errorf("flows were not reassigned")
+	}
+	h.Undo()
+	if book.Accounts["bank"] != bank || balance(book, "bank") != "-8.50" || book.Balance[cash].String() != "0.00" {
+		t.Errorf("got balance %s after undoing the deletion", balance(book, "bank"))
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	book := testBook()
+	h := New(book)
+	bank := book.Accounts["bank"]
+	st := reconcile.Statement{Date: date("2013-01-31"), Balance: big.NewRat(-85, 10)}
+	flows, _ := reconcile.Lookup(book, bank, []types.GUID{"t1a"})
+	if err := h.Do(Reconcile(bank, st, flows, true)); err != nil {
+		t.Fatal(err)
+	}
+	if s := book.Transactions["t1"].Flows[0].State; s != types.Reconciled || bank.LastReconcile.IsZero() {
+		t.Errorf("got state %s, last reconciliation %s", s, bank.LastReconcile)
+	}
+	h.Undo()
+	if s := book.Transactions["t1"].Flows[0].State; s != types.NotCleared || !bank.LastReconcile.IsZero() {
+		t.Errorf("got state %s, last reconciliation %s after undo", s, bank.LastReconcile)
+	}
+
+	// Failed commands leave the book unchanged.
+	st.Balance = big.NewRat(1, 1)
+	flows, _ = reconcile.Lookup(book, bank, []types.GUID{"t1a"})
+	if err := h.Do(Reconcile(bank, st, flows, true)); err == nil {
+		t.Errorf("finished an unbalanced reconciliation")
+	}
+	if h.NextUndo() != nil {
+		t.Errorf("failed command was recorded")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	book := testBook()
+	h := New(book)
+	h.Do(DeleteTransaction(book, "t1"))
+	h.Undo()
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, h.Log()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasSuffix(lines[2], `,Undo,"Delete transaction ""Bakery"" of 2013-01-10"`) {
+		t.Errorf("got CSV:\n%s", buf.String())
+	}
+}
+
+func TestReload(t *testing.T) {
+	book := testBook()
+	h := New(book)
+	h.Do(DeleteTransaction(book, "t1"))
+	other := testBook()
+	n := h.Reload(other, "Reload book.gnucash")
+	if n.Book != other || n.NextUndo() != nil || h.NextUndo() == nil {
+		t.Errorf("unexpected state after reload")
+	}
+	if log := n.Log(); len(log) != 2 || log[1].Action != "Reload" || len(h.Log()) != 1 {
+		t.Errorf("got log %+v", log)
+	}
+	if err := n.Undo(); err == nil {
+		t.Errorf("undid an edit of the previous book")
+	}
+}
@@ -0,0 +1,18 @@
+package history
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+)
+
+// WriteCSV writes audit log records as CSV with a header row.
+func WriteCSV(w io.Writer, log []Record) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Time", "Action", "Description"})
+	for _, r := range log {
+		cw.Write([]string{r.Time.Format(time.RFC3339), r.Action, r.Description})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/remyoudompheng/gocash/camtimport"
+	"github.com/remyoudompheng/gocash/csvimport"
+	"github.com/remyoudompheng/gocash/gui"
+	"github.com/remyoudompheng/gocash/match"
+	"github.com/remyoudompheng/gocash/mt940"
+	"github.com/remyoudompheng/gocash/ofximport"
+	"github.com/remyoudompheng/gocash/qif"
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func cmdImportOFX(args []string) {
+	flags := flag.NewFlagSet("import-ofx", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	acctName := flags.String("account", "", "name of the imported account")
+	counterName := flags.String("counter", "", "name of the counter-account (default: /Imbalance-CUR)")
+	httpAddr := flags.String("http", "", "address of HTTP server to browse the merged book")
+	var merge mergeOptions
+	merge.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gocash import-ofx -f book.xml -account NAME [flags] FILE.ofx...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *acctName == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	book := loadBook(*filename)
+	acct := book.AccountByName(*acctName)
+	if acct == nil {
+		log.Fatalf("ERROR: no such account %q", *acctName)
+	}
+	counter := *counterName
+	if counter == "" {
+		counter = "/Imbalance-" + acct.Unit
+	}
+
+	for _, name := range flags.Args() {
+		stmts, err := ofximport.ReadFile(name)
+		if err != nil {
+			log.Fatalf("ERROR: failed to read %q: %s", name, err)
+		}
+		for _, st := range stmts {
+			cacct := book.EnsureAccount(counter, "BANK", acct.Unit)
+			added := mergeTransactions(book, acct, st.Import(acct, cacct), merge)
+			log.Printf("%s: statement %s %s: %d entries, %d new",
+				name, st.Type, st.AccountId, len(st.Transactions), added)
+		}
+	}
+	book.Recompute()
+	fmt.Printf("Balance of %s: %s %s\n", acct.Name, book.Balance[acct], acct.Unit)
+
+	if *httpAddr != "" {
+		if err := gui.StartServer(*httpAddr, book, nil); err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+}
+
+// mergeOptions controls how imported transactions are compared
+// with the transactions already in the book, and categorized.
+type mergeOptions struct {
+	window   int
+	probable string // What to do with probable matches: skip, add or ask.
+	categorizeOptions
+}
+
+func (o *mergeOptions) register(flags *flag.FlagSet) {
+	flags.IntVar(&o.window, "window", match.DefaultOptions.Window, "maximum date difference (days) of matching transactions")
+	flags.StringVar(&o.probable, "probable", "skip", "action for probable duplicates: skip, add or ask")
+	o.categorizeOptions.register(flags)
+}
+
+// mergeTransactions adds transactions to the book, skipping those
+// already recorded in acct: transactions with a known external
+// identifier, or similar ones in the date window. Probable matches are
+// handled according to opts.
+func mergeTransactions(book *types.Book, acct *types.Account, trns []*types.Transaction, opts mergeOptions) (added int) {
+	book.Recompute()
+	m := match.NewMatcher(book, acct, match.Options{Window: opts.window, Duplicate: match.DefaultOptions.Duplicate})
+	categorize := opts.categorizer(book, acct)
+	stdin := bufio.NewReader(os.Stdin)
+	for _, trn := range trns {
+		res := m.Match(trn)
+		switch res.Status {
+		case match.Duplicate:
+			continue
+		case match.Probable:
+			old := res.Existing
+			msg := fmt.Sprintf("%s %10s %s\n  probably matches\n%s %10s %s (score %.2f)",
+				trn.Date.Format("2006-01-02"), trn.Flows[0].Price, trn.Description,
+				old.Date.Format("2006-01-02"), m.Amount(old), old.Description, res.Score)
+			switch opts.probable {
+			case "add":
+			case "ask":
+				fmt.Printf("%s\nAdd anyway? [y/N] ", msg)
+				answer, _ := stdin.ReadString('\n')
+				if a := strings.TrimSpace(answer); a != "y" && a != "Y" {
+					continue
+				}
+			default:
+				log.Printf("skipped: %s", msg)
+				continue
+			}
+		}
+		how := categorize(trn)
+		book.Transactions[trn.Id] = trn
+		m.Add(trn)
+		fmt.Printf("%s %10s %s%s\n", trn.Date.Format("2006-01-02"), trn.Flows[0].Price, trn.Description, how)
+		added++
+	}
+	return added
+}
+
+func cmdImportQIF(args []string) {
+	flags := flag.NewFlagSet("import-qif", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	currency := flags.String("currency", "", "currency of QIF amounts (default: most used)")
+	dayFirst := flags.Bool("dayfirst", false, "dates are DD/MM/YY")
+	httpAddr := flags.String("http", "", "address of HTTP server to browse the merged book")
+	var merge mergeOptions
+	merge.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gocash import-qif -f book.xml [flags] FILE.qif...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	book := loadBook(*filename)
+	if *currency == "" {
+		*currency = book.DefaultCurrency()
+	}
+	for _, name := range flags.Args() {
+		f, err := qif.ReadFile(name, qif.Options{DayFirst: *dayFirst})
+		if err != nil {
+			log.Fatalf("ERROR: failed to read %q: %s", name, err)
+		}
+		// Accounts are created in the book, transactions in a scratch
+		// book, and merged by account.
+		scratch := &types.Book{Accounts: book.Accounts, Transactions: make(map[types.GUID]*types.Transaction)}
+		f.Import(scratch, *currency)
+		byAccount := make(map[*types.Account][]*types.Transaction)
+		var accounts []*types.Account
+		for _, trn := range scratch.Transactions {
+			acct := trn.Flows[0].Account
+			if byAccount[acct] == nil {
+				accounts = append(accounts, acct)
+			}
+			byAccount[acct] = append(byAccount[acct], trn)
+		}
+		sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+		for _, acct := range accounts {
+			trns := byAccount[acct]
+			sort.SliceStable(trns, func(i, j int) bool { return importedBefore(trns[i], trns[j]) })
+			added := mergeTransactions(book, acct, trns, merge)
+			log.Printf("%s: account %s: %d entries, %d new", name, acct.Name, len(trns), added)
+		}
+	}
+	book.Recompute()
+
+	if *httpAddr != "" {
+		if err := gui.StartServer(*httpAddr, book, nil); err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+}
+
+// importedBefore orders imported transactions by date, description,
+// amount and identifier, so that entries of the same date are merged
+// in the same order on every run.
+func importedBefore(t, u *types.Transaction) bool {
+	switch {
+	case !t.Date.Equal(u.Date):
+		return t.Date.Before(u.Date)
+	case t.Description != u.Description:
+		return t.Description < u.Description
+	}
+	if c := t.Flows[0].Price.Rat().Cmp(u.Flows[0].Price.Rat()); c != 0 {
+		return c < 0
+	}
+	return t.Id < u.Id
+}
+
+func cmdImportCSV(args []string) {
+	flags := flag.NewFlagSet("import-csv", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	profile := flags.String("profile", "", "path to the CSV profile (JSON)")
+	acctName := flags.String("account", "", "name of the imported account")
+	counterName := flags.String("counter", "", "name of the counter-account (default: /Imbalance-CUR)")
+	commit := flags.Bool("commit", false, "merge the parsed rows into the book (default: preview)")
+	httpAddr := flags.String("http", "", "address of HTTP server to browse the merged book")
+	var merge mergeOptions
+	merge.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gocash import-csv -profile PROFILE.json [-f book.xml -account NAME -commit] FILE.csv...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *profile == "" || flags.NArg() == 0 || *commit && *acctName == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	p, err := csvimport.LoadProfile(*profile)
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+
+	var rows []csvimport.Row
+	for _, name := range flags.Args() {
+		r, err := p.ReadFile(name)
+		if err != nil {
+			log.Fatalf("ERROR: failed to read %q: %s", name, err)
+		}
+		rows = append(rows, r...)
+	}
+	if !*commit {
+		// Preview.
+		for _, row := range rows {
+			if row.Err != nil {
+				fmt.Printf("%4d  ERROR: %s\n", row.Line, row.Err)
+				continue
+			}
+			fmt.Printf("%4d  %s %12s  %-8s %s", row.Line, row.Date.Format("2006-01-02"),
+				row.Amount, row.Number, row.Description)
+			if row.Memo != "" {
+				fmt.Printf(" (%s)", row.Memo)
+			}
+			fmt.Println()
+		}
+		return
+	}
+
+	book := loadBook(*filename)
+	acct := book.AccountByName(*acctName)
+	if acct == nil {
+		log.Fatalf("ERROR: no such account %q", *acctName)
+	}
+	counter := *counterName
+	if counter == "" {
+		counter = "/Imbalance-" + acct.Unit
+	}
+	cacct := book.EnsureAccount(counter, "BANK", acct.Unit)
+	added := mergeTransactions(book, acct, csvimport.Import(rows, acct, cacct), merge)
+	log.Printf("%d rows, %d new transactions", len(rows), added)
+	book.Recompute()
+	fmt.Printf("Balance of %s: %s %s\n", acct.Name, book.Balance[acct], acct.Unit)
+
+	if *httpAddr != "" {
+		if err := gui.StartServer(*httpAddr, book, nil); err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+}
+
+func cmdImportCAMT(args []string) {
+	flags := flag.NewFlagSet("import-camt", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	acctName := flags.String("account", "", "name of the imported account")
+	counterName := flags.String("counter", "", "name of the counter-account (default: /Imbalance-CUR)")
+	httpAddr := flags.String("http", "", "address of HTTP server to browse the merged book")
+	var merge mergeOptions
+	merge.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gocash import-camt -f book.xml -account NAME [flags] FILE.xml...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *acctName == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	book := loadBook(*filename)
+	acct := book.AccountByName(*acctName)
+	if acct == nil {
+		log.Fatalf("ERROR: no such account %q", *acctName)
+	}
+	counter := *counterName
+	if counter == "" {
+		counter = "/Imbalance-" + acct.Unit
+	}
+	cacct := book.EnsureAccount(counter, "BANK", acct.Unit)
+
+	var stmts []*camtimport.Report
+	for _, name := range flags.Args() {
+		doc, err := camtimport.ReadFile(name)
+		if err != nil {
+			log.Fatalf("ERROR: failed to read %q: %s", name, err)
+		}
+		reports := append(doc.Statements, doc.Notifications...)
+		for i := range reports {
+			r := &reports[i]
+			if err := r.Check(); err != nil {
+				log.Printf("WARNING: %s: %s", name, err)
+			}
+			trns, err := r.Import(acct, cacct)
+			if err != nil {
+				log.Fatalf("ERROR: %s: %s", name, err)
+			}
+			added := mergeTransactions(book, acct, trns, merge)
+			log.Printf("%s: report %s: %d entries, %d new", name, r.Id, len(r.Entries), added)
+		}
+		for i := range doc.Statements {
+			stmts = append(stmts, &doc.Statements[i])
+		}
+	}
+	book.Recompute()
+	for _, st := range stmts {
+		if err := st.CheckBook(book, acct); err != nil {
+			log.Printf("WARNING: %s", err)
+		}
+	}
+	fmt.Printf("Balance of %s: %s %s\n", acct.Name, book.Balance[acct], acct.Unit)
+
+	if *httpAddr != "" {
+		if err := gui.StartServer(*httpAddr, book, nil); err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+}
+
+func cmdImportMT940(args []string) {
+	flags := flag.NewFlagSet("import-mt940", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	acctName := flags.String("account", "", "name of the imported account")
+	counterName := flags.String("counter", "", "name of the counter-account (default: /Imbalance-CUR)")
+	httpAddr := flags.String("http", "", "address of HTTP server to browse the merged book")
+	var merge mergeOptions
+	merge.register(flags)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: gocash import-mt940 -f book.xml -account NAME [flags] FILE.sta...\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if *acctName == "" || flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	book := loadBook(*filename)
+	acct := book.AccountByName(*acctName)
+	if acct == nil {
+		log.Fatalf("ERROR: no such account %q", *acctName)
+	}
+	counter := *counterName
+	if counter == "" {
+		counter = "/Imbalance-" + acct.Unit
+	}
+
+	for _, name := range flags.Args() {
+		stmts, err := mt940.ReadFile(name)
+		if err != nil {
+			log.Fatalf("ERROR: failed to read %q: %s", name, err)
+		}
+		for _, st := range stmts {
+			if err := st.Check(); err != nil {
+				log.Printf("WARNING: %s: %s", name, err)
+			}
+			cacct := book.EnsureAccount(counter, "BANK", acct.Unit)
+			added := mergeTransactions(book, acct, st.Import(acct, cacct), merge)
+			log.Printf("%s: statement %s %s: %d entries, %d new",
+				name, st.Reference, st.Account, len(st.Entries), added)
+		}
+	}
+	book.Recompute()
+	fmt.Printf("Balance of %s: %s %s\n", acct.Name, book.Balance[acct], acct.Unit)
+
+	if *httpAddr != "" {
+		if err := gui.StartServer(*httpAddr, book, nil); err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package ledger
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/types"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func TestRead(t *testing.T) {
+	book, err := ReadFile("testdata/sample.journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Transactions) != 4 || len(book.Prices) != 2 {
+		t.Fatalf("got %d transactions, %d prices", len(book.Transactions), len(book.Prices))
+	}
+	checking := book.AccountByName("/Assets/Checking")
+	if checking == nil || checking.Type != "BANK" || checking.Unit != "USD" || checking.Description != "Main account" {
+		t.Fatalf("unexpected account %+v", checking)
+	}
+	if acme := book.AccountByName("/Assets/Broker/ACME"); acme == nil || acme.Unit != "ACME" ||
+		units(book, acme) != "10.00" || book.Balance[acme].String() != "500.00" {
+		t.Errorf("unexpected ACME account %+v", acme)
+	}
+	if food := book.AccountByName("/Expenses/Food"); food == nil || food.Type != "EXPENSE" {
+		t.Errorf("unexpected food account %+v", food)
+	}
+	if got := units(book, checking); got != "1324.90" {
+		t.Errorf("checking balance is %s, expected 1324.90", got)
+	}
+	if travel := book.AccountByName("/Expenses/Travel"); units(book, travel) != "100.00" || travel.Unit != "EUR" {
+		t.Errorf("travel balance is %s %s, expected 100 EUR", units(book, travel), travel.Unit)
+	}
+	for _, f := range book.Flows[checking] {
+		trn := f.Parent
+		switch trn.Description {
+		case "Paycheck":
+			if trn.Number != "101" || trn.Notes != "January" || trn.ExternalId != "PAY-1" || f.State != types.Reconciled {
+				t.Errorf("unexpected transaction %+v", trn)
+			}
+		case "Groceries":
+			if f.State != types.Reconciled || trn.Flows[1].State != types.Cleared || trn.Flows[1].Memo != "bread\nand milk" {
+				t.Errorf("unexpected transaction %+v", trn)
+			}
+		}
+	}
+	if p := book.Prices.Lookup("ACME", "USD", book.Prices[1].Time); p.String() != "60.00" {
+		t.Errorf("ACME price is %s, expected 60", p)
+	}
+}
+
+// units returns the balance of acct in its own commodity.
+func units(book *types.Book, acct *types.Account) string {
+	sum := new(types.Amount)
+	for _, f := range book.Flows[acct] {
+		sum.Add(f.Units())
+	}
+	return sum.String()
+}
+
+func TestReadErrors(t *testing.T) {
+	for _, s := range []string{
+		"2013-01-01 x\n    A  1 USD\n    B  -2 USD\n",
+		"2013-01-01 x\n    A\n    B\n",
+		"2013-13-01 x\n    A  1 USD\n    B\n",
+		"include other.journal\n",
+		"2013-01-01 x\n    A  1 USD\n    B  1 EUR\n    C  -1 USD\n",
+	} {
+		if _, err := Read(strings.NewReader(s)); err == nil {
+			t.Errorf("no error for %q", s)
+		}
+	}
+}
+
+// canonical returns a description of the book that does not depend
+// on identifiers or on the order of maps.
+func canonical(book *types.Book) []string {
+	var lines []string
+	for _, a := range book.Accounts {
+		lines = append(lines, fmt.Sprintf("A %s %s %s %q", a.Name, a.Type, a.Unit, a.Description))
+	}
+	for _, trn := range book.Transactions {
+		s := fmt.Sprintf("T %s %q %q %q %q %s %q", trn.Date.Format("2006-01-02"), trn.Description,
+			trn.Notes, trn.Number, trn.ExternalId, trn.Currency, trn.VoidReason)
+		var flows []string
+		for _, f := range trn.Flows {
+			flows = append(flows, fmt.Sprintf("%s %s %s %q %q %v", f.Account.Name,
+				f.Price.Rat().RatString(), f.Units().Rat().RatString(), f.Memo, stateMark(f.State), f.VoidPrice))
+		}
+		sort.Strings(flows)
+		lines = append(lines, s+"\n  "+strings.Join(flows, "\n  "))
+	}
+	for _, p := range book.Prices {
+		lines = append(lines, fmt.Sprintf("P %s %s %s %s", p.Time.Format("2006-01-02"),
+			p.Commodity, p.Currency, p.Value.Rat().RatString()))
+	}
+	for _, c := range book.Commodities {
+		if c.Space != "template" {
+			lines = append(lines, fmt.Sprintf("C %s %s %q %d", c.Space, c.Id, c.Name, c.Fraction))
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func TestRoundTrip(t *testing.T) {
+	files, _ := filepath.Glob("../xmlimport/testdata/*.gml2")
+	if len(files) == 0 {
+		t.Fatal("no test files")
+	}
+	for _, name := range files {
+		orig, err := xmlimport.ImportFile(name)
+		if err != nil {
+			t.Errorf("%s: %s", name, err)
+			continue
+		}
+		orig.Recompute()
+		var buf bytes.Buffer
+		if err := Write(&buf, orig); err != nil {
+			t.Fatal(err)
+		}
+		book, err := Read(&buf)
+		if err != nil {
+			t.Errorf("%s: %s\n%s", name, err, buf.Bytes())
+			continue
+		}
+		a, b := canonical(orig), canonical(book)
+		if strings.Join(a, "\n") != strings.Join(b, "\n") {
+			for i := 0; i < len(a) && i < len(b); i++ {
+				if a[i] != b[i] {
+					t.Errorf("%s: round trip differs:\n%s\n%s", name, a[i], b[i])
+					break
+				}
+			}
+			if len(a) != len(b) {
+				t.Errorf("%s: %d items, got %d after round trip", name, len(a), len(b))
+			}
+		}
+		for acct, bal := range orig.Balance {
+			other := book.AccountByName(acct.Name)
+			if other == nil || book.Balance[other].Rat().Cmp(bal.Rat()) != 0 {
+				t.Errorf("%s: balance of %s differs", name, acct.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,644 @@
+package ledger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Read parses a journal into a new book. It understands transactions,
+// postings with costs (@ and @@), at most one posting with an elided
+// amount per transaction, and the account, commodity and P directives.
+// Other directives are ignored, except include which is unsupported.
+func Read(r io.Reader) (*types.Book, error) {
+	p := &parser{
+		accounts:    make(map[string]*accountDecl),
+		commodities: make(map[string]*types.Commodity),
+		declared:    make(map[string]bool),
+	}
+	if err := p.parse(r); err != nil {
+		return nil, err
+	}
+	return p.build()
+}
+
+// ReadFile reads the named journal.
+func ReadFile(name string) (*types.Book, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+type accountDecl struct {
+	name        string // Colon separated.
+	typ         string
+	unit        string
+	description string
+	placeholder bool
+	hidden      bool
+}
+
+type transaction struct {
+	line        int
+	date        time.Time
+	state       types.ReconcileState
+	code        string
+	description string
+	notes       []string
+	externalId  string
+	voided      bool
+	voidReason  string
+	voidTime    time.Time
+	postings    []*posting
+}
+
+type posting struct {
+	line     int
+	account  string
+	state    types.ReconcileState
+	amount   *big.Rat // Nil if elided.
+	unit     string
+	cost     *big.Rat // Total cost, nil if none.
+	currency string
+	memo     []string
+
+	voidValue, voidQuantity *big.Rat // Former amounts of a voided posting.
+}
+
+type parser struct {
+	accounts    map[string]*accountDecl
+	commodities map[string]*types.Commodity
+	list        []*types.Commodity // Commodities in order of declaration.
+	declared    map[string]bool
+	prices      types.Prices
+	trns        []*transaction
+}
+
+func (p *parser) parse(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	var (
+		lineno    int
+		trn       *transaction
+		acct      *accountDecl
+		commodity *types.Commodity
+	)
+	for s.Scan() {
+		lineno++
+		line := strings.TrimRight(s.Text(), "\r\t ")
+		if line == "" {
+			trn, acct, commodity = nil, nil, nil
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			// Indented line in the current block.
+			line = strings.TrimSpace(line)
+			var err error
+			switch {
+			case trn != nil:
+				err = p.parsePosting(trn, lineno, line)
+			case acct != nil:
+				if key, val, ok := commentTag(line); ok {
+					switch key {
+					case "type":
+						acct.typ = val
+					case "commodity":
+						acct.unit = val
+					case "description":
+						acct.description = val
+					case "placeholder":
+						acct.placeholder = val == "true"
+					case "hidden":
+						acct.hidden = val == "true"
+					}
+				}
+			case commodity != nil:
+				if key, val, ok := commentTag(line); ok {
+					switch key {
+					case "name":
+						commodity.Name = val
+					case "namespace":
+						commodity.Space = val
+					case "fraction":
+						commodity.Fraction, err = strconv.Atoi(val)
+					}
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("line %d: %s", lineno, err)
+			}
+			continue
+		}
+		trn, acct, commodity = nil, nil, nil
+		word := line
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			word = line[:i]
+		}
+		rest := strings.TrimSpace(line[len(word):])
+		var err error
+		switch {
+		case strings.ContainsRune(";#*%|", rune(line[0])):
+			// Comment.
+		case line[0] >= '0' && line[0] <= '9':
+			trn, err = parseHeader(line)
+			if trn != nil {
+				trn.line = lineno
+				p.trns = append(p.trns, trn)
+			}
+		case word == "account":
+			name := stripComment(rest)
+			acct = p.accounts[name]
+			if acct == nil {
+				acct = &accountDecl{name: name}
+				p.accounts[name] = acct
+			}
+		case word == "commodity":
+			id, _ := parseSymbol(stripComment(rest))
+			if p.declared[id] {
+				// The same symbol in another namespace.
+				delete(p.commodities, id)
+			}
+			p.declared[id] = true
+			commodity = p.commodity(id)
+		case word == "P":
+			err = p.parsePrice(rest)
+		case word == "include" || word == "!include":
+			err = fmt.Errorf("include directives are not supported")
+		}
+		if err != nil {
+			return fmt.Errorf("line %d: %s", lineno, err)
+		}
+	}
+	return s.Err()
+}
+
+// commodity returns the commodity with the given symbol, declaring
+// it if necessary.
+func (p *parser) commodity(id string) *types.Commodity {
+	c := p.commodities[id]
+	if c == nil {
+		c = &types.Commodity{Id: id}
+		if len(id) == 3 && strings.ToUpper(id) == id {
+			c.Space = "ISO4217"
+		}
+		p.commodities[id] = c
+		p.list = append(p.list, c)
+	}
+	return c
+}
+
+func parseDate(s string) (time.Time, error) {
+	if i := strings.IndexByte(s, '='); i >= 0 {
+		// Secondary date.
+		s = s[:i]
+	}
+	for _, layout := range []string{"2006-1-2", "2006/1/2", "2006.1.2"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseHeader parses the first line of a transaction:
+//
+//	DATE[=DATE2] [*|!] [(CODE)] DESCRIPTION [; COMMENT]
+func parseHeader(line string) (*transaction, error) {
+	trn := new(transaction)
+	date, rest := line, ""
+	if i := strings.IndexAny(line, " \t"); i >= 0 {
+		date, rest = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	var err error
+	trn.date, err = parseDate(date)
+	if err != nil {
+		return nil, err
+	}
+	if i := strings.IndexByte(rest, ';'); i >= 0 {
+		trn.addComment(rest[i+1:])
+		rest = strings.TrimSpace(rest[:i])
+	}
+	switch {
+	case strings.HasPrefix(rest, "*"):
+		trn.state = types.Reconciled
+		rest = strings.TrimSpace(rest[1:])
+	case strings.HasPrefix(rest, "!"):
+		trn.state = types.Cleared
+		rest = strings.TrimSpace(rest[1:])
+	}
+	if strings.HasPrefix(rest, "(") {
+		if i := strings.IndexByte(rest, ')'); i > 0 {
+			trn.code = rest[1:i]
+			rest = strings.TrimSpace(rest[i+1:])
+		}
+	}
+	trn.description = rest
+	return trn, nil
+}
+
+// addComment records a transaction comment, recognizing the
+// external-id, void and void-time tags.
+func (trn *transaction) addComment(c string) {
+	c = strings.TrimSpace(c)
+	switch {
+	case strings.HasPrefix(c, "external-id:"):
+		trn.externalId = strings.TrimSpace(c[len("external-id:"):])
+	case strings.HasPrefix(c, "void:"):
+		trn.voided = true
+		trn.voidReason = strings.TrimSpace(c[len("void:"):])
+	case strings.HasPrefix(c, "void-time:"):
+		trn.voidTime, _ = time.Parse(time.RFC3339, strings.TrimSpace(c[len("void-time:"):]))
+	default:
+		trn.notes = append(trn.notes, c)
+	}
+}
+
+// addComment records a posting comment, recognizing the void-value
+// and void-quantity tags.
+func (post *posting) addComment(c string) {
+	c = strings.TrimSpace(c)
+	var tag **big.Rat
+	switch {
+	case strings.HasPrefix(c, "void-value:"):
+		tag, c = &post.voidValue, c[len("void-value:"):]
+	case strings.HasPrefix(c, "void-quantity:"):
+		tag, c = &post.voidQuantity, c[len("void-quantity:"):]
+	default:
+		post.memo = append(post.memo, c)
+		return
+	}
+	if x, ok := new(big.Rat).SetString(strings.TrimSpace(c)); ok {
+		*tag = x
+	}
+}
+
+// parsePosting parses an indented line of a transaction:
+//
+//	[*|!] ACCOUNT  [AMOUNT [@ PRICE | @@ TOTAL]] [= ASSERTION] [; MEMO]
+func (p *parser) parsePosting(trn *transaction, lineno int, line string) error {
+	if line[0] == ';' || line[0] == '#' {
+		if n := len(trn.postings); n > 0 {
+			trn.postings[n-1].addComment(line[1:])
+		} else {
+			trn.addComment(line[1:])
+		}
+		return nil
+	}
+	post := &posting{line: lineno, state: trn.state}
+	switch line[0] {
+	case '*':
+		post.state = types.Reconciled
+		line = strings.TrimSpace(line[1:])
+	case '!':
+		post.state = types.Cleared
+		line = strings.TrimSpace(line[1:])
+	}
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		post.memo = append(post.memo, strings.TrimSpace(line[i+1:]))
+		line = strings.TrimSpace(line[:i])
+	}
+	end := len(line)
+	if i := strings.Index(line, "  "); i >= 0 {
+		end = i
+	}
+	if i := strings.IndexByte(line, '\t'); i >= 0 && i < end {
+		end = i
+	}
+	post.account = strings.Trim(line[:end], "()[]")
+	amount := strings.TrimSpace(line[end:])
+	if i := strings.IndexByte(amount, '='); i >= 0 {
+		// Balance assertion.
+		amount = strings.TrimSpace(amount[:i])
+	}
+	if amount != "" {
+		var cost string
+		total := false
+		if i := strings.Index(amount, "@@"); i >= 0 {
+			amount, cost, total = amount[:i], amount[i+2:], true
+		} else if i := strings.IndexByte(amount, '@'); i >= 0 {
+			amount, cost = amount[:i], amount[i+1:]
+		}
+		var err error
+		post.amount, post.unit, err = parseAmount(amount)
+		if err != nil {
+			return err
+		}
+		if cost != "" {
+			post.cost, post.currency, err = parseAmount(cost)
+			if err != nil {
+				return err
+			}
+			post.cost.Abs(post.cost)
+			if !total {
+				post.cost.Mul(post.cost, new(big.Rat).Abs(post.amount))
+			}
+		}
+	}
+	trn.postings = append(trn.postings, post)
+	return nil
+}
+
+// parsePrice parses the arguments of a P directive:
+//
+//	P DATE [TIME] COMMODITY AMOUNT
+func (p *parser) parsePrice(s string) error {
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return fmt.Errorf("invalid price directive %q", s)
+	}
+	t, err := parseDate(fields[0])
+	if err != nil {
+		return err
+	}
+	rest := strings.TrimSpace(s[len(fields[0]):])
+	if strings.Contains(fields[1], ":") {
+		rest = strings.TrimSpace(rest[len(fields[1]):])
+	}
+	id, rest := parseSymbol(rest)
+	value, currency, err := parseAmount(stripComment(rest))
+	if err != nil {
+		return err
+	}
+	p.prices = append(p.prices, &types.Price{
+		Id:        types.NewGUID(),
+		Commodity: p.commodity(id).Id,
+		Currency:  p.commodity(currency).Id,
+		Time:      t,
+		Value:     (*types.Amount)(value),
+	})
+	return nil
+}
+
+// parseSymbol returns the commodity symbol at the start of s,
+// and the rest of s.
+func parseSymbol(s string) (id, rest string) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, `"`) {
+		if i := strings.IndexByte(s[1:], '"'); i >= 0 {
+			return s[1 : i+1], strings.TrimSpace(s[i+2:])
+		}
+	}
+	i := strings.IndexAny(s, " \t")
+	if i < 0 {
+		return currencySymbol(s), ""
+	}
+	return currencySymbol(s[:i]), strings.TrimSpace(s[i:])
+}
+
+// currencySymbols maps common currency signs to ISO codes.
+var currencySymbols = map[string]string{"$": "USD", "€": "EUR", "£": "GBP", "¥": "JPY"}
+
+func currencySymbol(s string) string {
+	if code := currencySymbols[s]; code != "" {
+		return code
+	}
+	return s
+}
+
+// parseAmount parses an amount such as 10.00 EUR, $-5, -$5,
+// 1,000.50 USD or 3 "ACME 2".
+func parseAmount(s string) (*big.Rat, string, error) {
+	orig := s
+	s = strings.TrimSpace(s)
+	var commodity string
+	if i := strings.IndexByte(s, '"'); i >= 0 {
+		j := strings.IndexByte(s[i+1:], '"')
+		if j < 0 {
+			return nil, "", fmt.Errorf("invalid amount %q", orig)
+		}
+		commodity = s[i+1 : i+1+j]
+		s = s[:i] + s[i+j+2:]
+	}
+	var num, sym []rune
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9', r == '.', r == '-', r == '+':
+			num = append(num, r)
+		case r == ',', unicode.IsSpace(r):
+			// Thousands separator.
+		default:
+			sym = append(sym, r)
+		}
+	}
+	if commodity == "" {
+		commodity = currencySymbol(string(sym))
+	} else if len(sym) > 0 {
+		return nil, "", fmt.Errorf("invalid amount %q", orig)
+	}
+	x, ok := new(big.Rat).SetString(strings.TrimPrefix(string(num), "+"))
+	if !ok {
+		return nil, "", fmt.Errorf("invalid amount %q", orig)
+	}
+	return x, commodity, nil
+}
+
+// commentTag parses a comment line of the form "; key: value".
+func commentTag(line string) (key, value string, ok bool) {
+	if !strings.HasPrefix(line, ";") {
+		return "", "", false
+	}
+	line = strings.TrimSpace(line[1:])
+	i := strings.IndexByte(line, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	return line[:i], strings.TrimSpace(line[i+1:]), true
+}
+
+func stripComment(s string) string {
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+// build converts the parsed journal into a book.
+func (p *parser) build() (*types.Book, error) {
+	book := &types.Book{
+		Accounts:     make(map[types.GUID]*types.Account),
+		Transactions: make(map[types.GUID]*types.Transaction),
+		Prices:       p.prices,
+	}
+	root := &types.Account{Id: types.NewGUID(), Name: "Root Account", Type: "ROOT"}
+	book.Accounts[root.Id] = root
+
+	// Declared accounts, parents first.
+	names := make([]string, 0, len(p.accounts))
+	for name := range p.accounts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p.account(book, name, "")
+	}
+
+	now := time.Now()
+	for _, t := range p.trns {
+		trn := &types.Transaction{
+			Id:          types.NewGUID(),
+			Date:        t.date,
+			Stamp:       now,
+			Description: t.description,
+			Notes:       strings.Join(t.notes, "\n"),
+			Number:      t.code,
+			ExternalId:  t.externalId,
+			VoidReason:  t.voidReason,
+			VoidTime:    t.voidTime,
+		}
+		if err := p.balance(book, t, trn); err != nil {
+			return nil, fmt.Errorf("line %d: %s", t.line, err)
+		}
+		book.Transactions[trn.Id] = trn
+	}
+
+	book.Commodities = p.list
+	book.Recompute()
+	return book, nil
+}
+
+// account returns the book account for a journal account name,
+// creating it if necessary.
+func (p *parser) account(book *types.Book, name, unit string) *types.Account {
+	path := "/" + strings.Replace(name, ":", "/", -1)
+	if acct := book.AccountByName(path); acct != nil {
+		if acct.Unit == "" {
+			acct.Unit = unit
+		}
+		return acct
+	}
+	if i := strings.LastIndexByte(name, ':'); i > 0 {
+		// Undeclared parents get their own type.
+		p.account(book, name[:i], "")
+	}
+	typ := ""
+	if decl := p.accounts[name]; decl != nil {
+		typ = decl.typ
+		if decl.unit != "" {
+			unit = decl.unit
+		}
+	}
+	if typ == "" {
+		typ = guessType(name)
+	}
+	acct := book.EnsureAccount(path, typ, unit)
+	if decl := p.accounts[name]; decl != nil {
+		acct.Description = decl.description
+		acct.Placeholder, acct.Hidden = decl.placeholder, decl.hidden
+	}
+	if unit != "" {
+		p.commodity(unit)
+	}
+	return acct
+}
+
+// guessType returns the account type of undeclared accounts,
+// from the usual names of top-level accounts.
+func guessType(name string) string {
+	top := strings.ToLower(strings.SplitN(name, ":", 2)[0])
+	switch top {
+	case "assets", "asset":
+		return "ASSET"
+	case "liabilities", "liability":
+		return "LIABILITY"
+	case "income", "revenue", "revenues":
+		return "INCOME"
+	case "expenses", "expense":
+		return "EXPENSE"
+	case "equity":
+		return "EQUITY"
+	}
+	return "ASSET"
+}
+
+// balance converts the postings of t into flows of trn, inferring
+// elided amounts and conversions between two commodities.
+func (p *parser) balance(book *types.Book, t *transaction, trn *types.Transaction) error {
+	type value struct {
+		x        *big.Rat
+		currency string
+	}
+	values := make([]*value, len(t.postings))
+	var elided *posting
+	for i, post := range t.postings {
+		switch {
+		case post.amount == nil:
+			if elided != nil {
+				return fmt.Errorf("several postings without amount")
+			}
+			elided = post
+		case post.cost != nil:
+			x := new(big.Rat).Set(post.cost)
+			if post.amount.Sign() < 0 {
+				x.Neg(x)
+			}
+			values[i] = &value{x, post.currency}
+		default:
+			values[i] = &value{new(big.Rat).Set(post.amount), post.unit}
+		}
+		if values[i] != nil && trn.Currency == "" {
+			trn.Currency = values[i].currency
+		}
+	}
+	// Implicit conversion between two postings.
+	if len(values) == 2 && values[0] != nil && values[1] != nil &&
+		values[0].currency != values[1].currency && t.postings[1].cost == nil {
+		values[1] = &value{new(big.Rat).Neg(values[0].x), values[0].currency}
+	}
+	sum := new(big.Rat)
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if v.currency != trn.Currency {
+			return fmt.Errorf("posting to %s in %s, transaction in %s",
+				t.postings[i].account, v.currency, trn.Currency)
+		}
+		sum.Add(sum, v.x)
+	}
+	if elided != nil {
+		for i := range values {
+			if values[i] == nil {
+				values[i] = &value{new(big.Rat).Neg(sum), trn.Currency}
+				t.postings[i].amount = values[i].x
+				t.postings[i].unit = trn.Currency
+			}
+		}
+		sum.SetInt64(0)
+	}
+	if sum.Sign() != 0 {
+		return fmt.Errorf("transaction does not balance (off by %s %s)", sum.FloatString(2), trn.Currency)
+	}
+	for i, post := range t.postings {
+		acct := p.account(book, post.account, post.unit)
+		f := types.Flow{
+			Id:       types.NewGUID(),
+			Memo:     strings.Join(post.memo, "\n"),
+			Account:  acct,
+			Price:    (*types.Amount)(values[i].x),
+			Quantity: new(types.Amount).SetRat(post.amount),
+			State:    post.state,
+			Parent:   trn,
+		}
+		if t.voided {
+			f.State = types.Voided
+			f.VoidPrice = (*types.Amount)(post.voidValue)
+			f.VoidQuantity = (*types.Amount)(post.voidQuantity)
+			if f.VoidQuantity == nil {
+				f.VoidQuantity = f.VoidPrice
+			}
+		}
+		trn.Flows = append(trn.Flows, f)
+	}
+	return nil
+}
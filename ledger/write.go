@@ -0,0 +1,207 @@
+// Package ledger implements reading and writing of plain text journals
+// in the format of Ledger and hledger.
+package ledger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Write writes book as a journal. Account types, units and
+// descriptions, and commodity names are written as comments of
+// the account and commodity directives, so that Read can restore them.
+func Write(w io.Writer, book *types.Book) error {
+	bw := bufio.NewWriter(w)
+
+	for _, c := range book.Commodities {
+		if c.Space == "template" {
+			continue
+		}
+		fmt.Fprintf(bw, "commodity %s\n", symbol(c.Id))
+		if c.Name != "" {
+			fmt.Fprintf(bw, "    ; name: %s\n", c.Name)
+		}
+		if c.Space != "" {
+			fmt.Fprintf(bw, "    ; namespace: %s\n", c.Space)
+		}
+		if c.Fraction > 0 {
+			fmt.Fprintf(bw, "    ; fraction: %d\n", c.Fraction)
+		}
+	}
+	if len(book.Commodities) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	var accts []*types.Account
+	for _, acct := range book.Accounts {
+		if acct.Type != "ROOT" {
+			accts = append(accts, acct)
+		}
+	}
+	sort.Slice(accts, func(i, j int) bool { return accts[i].Name < accts[j].Name })
+	for _, acct := range accts {
+		fmt.Fprintf(bw, "account %s\n", AccountName(acct.Name))
+		fmt.Fprintf(bw, "    ; type: %s\n", acct.Type)
+		if acct.Unit != "" {
+			fmt.Fprintf(bw, "    ; commodity: %s\n", acct.Unit)
+		}
+		if acct.Description != "" {
+			fmt.Fprintf(bw, "    ; description: %s\n", acct.Description)
+		}
+		if acct.Placeholder {
+			fmt.Fprintf(bw, "    ; placeholder: true\n")
+		}
+		if acct.Hidden {
+			fmt.Fprintf(bw, "    ; hidden: true\n")
+		}
+	}
+	if len(accts) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	for _, p := range book.Prices {
+		fmt.Fprintf(bw, "P %s %s %s\n", p.Time.Format("2006-01-02"),
+			symbol(p.Commodity), formatAmount(p.Value.Rat(), p.Currency))
+	}
+	if len(book.Prices) > 0 {
+		fmt.Fprintln(bw)
+	}
+
+	trns := make([]*types.Transaction, 0, len(book.Transactions))
+	for _, trn := range book.Transactions {
+		trns = append(trns, trn)
+	}
+	sort.Slice(trns, func(i, j int) bool {
+		a, b := trns[i], trns[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if !a.Stamp.Equal(b.Stamp) {
+			return a.Stamp.Before(b.Stamp)
+		}
+		return a.Id < b.Id
+	})
+	for _, trn := range trns {
+		writeTransaction(bw, trn)
+	}
+	return bw.Flush()
+}
+
+// stateMark returns the journal mark of a flow state: * for
+// reconciled flows and ! for cleared (pending) flows.
+func stateMark(s types.ReconcileState) string {
+	switch {
+	case s.IsReconciled():
+		return "*"
+	case s == types.Cleared:
+		return "!"
+	}
+	return ""
+}
+
+func writeTransaction(w io.Writer, trn *types.Transaction) {
+	// Use a transaction mark when all flows have the same state.
+	mark := ""
+	if len(trn.Flows) > 0 {
+		mark = stateMark(trn.Flows[0].State)
+	}
+	for _, f := range trn.Flows {
+		if stateMark(f.State) != mark {
+			mark = ""
+		}
+	}
+	fmt.Fprint(w, trn.Date.Format("2006-01-02"))
+	if mark != "" {
+		fmt.Fprint(w, " "+mark)
+	}
+	if trn.Number != "" {
+		fmt.Fprintf(w, " (%s)", trn.Number)
+	}
+	if trn.Description != "" {
+		fmt.Fprintf(w, " %s", oneLine(trn.Description))
+	}
+	fmt.Fprintln(w)
+	if trn.Notes != "" {
+		for _, line := range strings.Split(trn.Notes, "\n") {
+			fmt.Fprintf(w, "    ; %s\n", line)
+		}
+	}
+	if trn.ExternalId != "" {
+		fmt.Fprintf(w, "    ; external-id: %s\n", trn.ExternalId)
+	}
+	if trn.IsVoid() {
+		// Voided flows have zero amounts: keep the former ones.
+		fmt.Fprintf(w, "    ; void: %s\n", oneLine(trn.VoidReason))
+		if !trn.VoidTime.IsZero() {
+			fmt.Fprintf(w, "    ; void-time: %s\n", trn.VoidTime.Format(time.RFC3339))
+		}
+	}
+	for _, f := range trn.Flows {
+		flowMark := ""
+		if m := stateMark(f.State); mark == "" && m != "" {
+			flowMark = m + " "
+		}
+		var amount string
+		unit := f.Account.Unit
+		if unit == "" || unit == trn.Currency || f.Quantity == nil {
+			amount = formatAmount(f.Price.Rat(), trn.Currency)
+		} else {
+			// Total cost in the transaction currency.
+			cost := new(big.Rat).Abs(f.Price.Rat())
+			amount = formatAmount(f.Quantity.Rat(), unit) + " @@ " + formatAmount(cost, trn.Currency)
+		}
+		line := fmt.Sprintf("    %s%-40s  %16s", flowMark, AccountName(f.Account.Name), amount)
+		if f.Memo != "" {
+			line += "  ; " + oneLine(f.Memo)
+		}
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+		if f.VoidPrice != nil {
+			fmt.Fprintf(w, "        ; void-value: %s\n", f.VoidPrice.Rat().RatString())
+		}
+		if f.VoidQuantity != nil && (f.VoidPrice == nil || f.VoidQuantity.Rat().Cmp(f.VoidPrice.Rat()) != 0) {
+			fmt.Fprintf(w, "        ; void-quantity: %s\n", f.VoidQuantity.Rat().RatString())
+		}
+	}
+	fmt.Fprintln(w)
+}
+
+// AccountName converts a slash separated account name to
+// a colon separated journal account name.
+func AccountName(name string) string {
+	name = strings.TrimPrefix(name, "/")
+	name = strings.Replace(name, ":", "_", -1)
+	return strings.Replace(name, "/", ":", -1)
+}
+
+// symbol returns a commodity symbol, quoted if it contains
+// characters other than letters.
+func symbol(s string) string {
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !strings.ContainsRune("$€£¥", r) {
+			return `"` + s + `"`
+		}
+	}
+	return s
+}
+
+// formatAmount formats an amount exactly if it is a decimal number,
+// with at least 2 decimals.
+func formatAmount(x *big.Rat, commodity string) string {
+	s := (*types.Amount)(x).Decimal()
+	if commodity == "" {
+		return s
+	}
+	return s + " " + symbol(commodity)
+}
+
+func oneLine(s string) string {
+	return strings.Replace(s, "\n", " ", -1)
+}
@@ -5,9 +5,13 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/remyoudompheng/gocash/gui"
+	"github.com/remyoudompheng/gocash/ledger"
 	"github.com/remyoudompheng/gocash/reports"
 	"github.com/remyoudompheng/gocash/types"
 	"github.com/remyoudompheng/gocash/xmlimport"
@@ -18,31 +22,71 @@ func init() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 }
 
+// Subcommands are selected by the first command-line argument.
+var commands = map[string]func(args []string){
+	"import-ofx":       cmdImportOFX,
+	"import-qif":       cmdImportQIF,
+	"import-csv":       cmdImportCSV,
+	"import-camt":      cmdImportCAMT,
+	"import-mt940":     cmdImportMT940,
+	"export-qif":       cmdExportQIF,
+	"export-ledger":    cmdExportLedger,
+	"export-beancount": cmdExportBeancount,
+	"export-register":  cmdExportRegister,
+	"categorize":       cmdCategorize,
+	"propose-rules":    cmdProposeRules,
+	"query":            cmdQuery,
+	"search":           cmdSearch,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd := commands[os.Args[1]]; cmd != nil {
+			cmd(os.Args[2:])
+			return
+		}
+	}
+
 	var (
 		filename string
 		httpAddr string
+		watch    time.Duration
 
-		report string
+		report   string
+		period   string
+		fiscal   int
+		from, to string
+		currency string
+		depth    int
+		top      int
 	)
 	flag.StringVar(&filename, "f", "", "path to GNucash XML file")
 	flag.StringVar(&httpAddr, "http", "localhost:8099", "address of HTTP server")
+	flag.DurationVar(&watch, "watch", 2*time.Second, "interval of checks for changes of the book file (0 to disable)")
 	flag.StringVar(&gui.StaticDir, "static", "static/", "path to static files")
 	flag.StringVar(&report, "report", "", "make a report")
+	flag.StringVar(&period, "period", "monthly", "report period (daily, weekly, monthly, quarterly, yearly, fiscal)")
+	flag.IntVar(&fiscal, "fiscal-start", 1, "first month of the fiscal year")
+	flag.StringVar(&from, "from", "", "start date of report (YYYY-MM-DD)")
+	flag.StringVar(&to, "to", "", "end date of report (YYYY-MM-DD)")
+	flag.StringVar(&currency, "currency", "", "currency of reports (default: most used)")
+	flag.IntVar(&depth, "depth", 2, "depth of expense categories in reports")
+	flag.IntVar(&top, "top", 10, "number of ranked items in reports")
 	flag.Parse()
 
-	t0 := time.Now()
-	book, err := xmlimport.ImportFile(filename)
-	if err != nil {
-		log.Fatalf("ERROR: failed to load %q: %s", filename, err)
-	}
-	log.Printf("Loaded %q: %d accounts, %d transactions, in %s",
-		filename, len(book.Accounts), len(book.Transactions), time.Since(t0))
-	book.Recompute()
+	book := loadBook(filename)
+	var err error
 
 	switch {
 	case report != "":
 		// make a report.
+		periods, err := reports.ParsePeriods(period, fiscal, from, to)
+		if err != nil {
+			log.Fatalf("ERROR: %s", err)
+		}
+		if currency == "" {
+			currency = book.DefaultCurrency()
+		}
 		switch report {
 		case "totalassets":
 			var assetFlows [][]*types.Flow
@@ -52,15 +96,71 @@ func main() {
 					assetFlows = append(assetFlows, flows)
 				}
 			}
-			r := reports.Balance(assetFlows)
+			r := reports.Balance(assetFlows, periods)
 			for i := range r.T {
-				fmt.Printf("%s,%s\n", r.T[i].Format("Jan 2006"), (*types.Amount)(r.Values[i]))
+				fmt.Printf("%s,%s,%s\n", r.T[i].Format("2006-01-02"),
+					(*types.Amount)(r.Values[i]), (*types.Amount)(r.Changes[i]))
+			}
+		case "networth":
+			r := reports.NetWorth(book, currency, periods)
+			fmt.Printf("date,%s,assets,liabilities,networth\n", strings.Join(r.Groups, ","))
+			for i := range r.T {
+				fmt.Print(r.T[i].Format("2006-01-02"))
+				for _, series := range r.Series {
+					fmt.Printf(",%s", (*types.Amount)(series[i]))
+				}
+				fmt.Printf(",%s,%s,%s\n", (*types.Amount)(r.Assets[i]),
+					(*types.Amount)(r.Liabilities[i]), (*types.Amount)(r.NetWorth[i]))
+			}
+			for _, c := range r.Missing {
+				log.Printf("WARNING: no price for %s in %s", c, currency)
+			}
+		case "portfolio":
+			r := reports.Portfolio(book, currency, periods)
+			fmt.Println("security,quantity,cost,price,value,gain,share,twr,irr")
+			for _, h := range r.Holdings {
+				fmt.Printf("%s,%s,%s,%s,%s,%s,%.4f,%.4f,%.4f\n", h.Account.Name,
+					h.Quantity.FloatString(4), (*types.Amount)(h.Cost), h.Price.FloatString(4),
+					(*types.Amount)(h.Value), (*types.Amount)(h.Gain), h.Share, h.TWR, h.IRR)
+				if h.Skipped > 0 {
+					log.Printf("WARNING: %d flows of %s have no exchange rate to %s", h.Skipped, h.Account.Name, currency)
+				}
+			}
+			fmt.Printf("total,,%s,,%s,%s,1,,%.4f\n", (*types.Amount)(r.Cost),
+				(*types.Amount)(r.Value), (*types.Amount)(r.Gain), r.IRR)
+		case "spending":
+			opts := reports.DefaultSpendingOptions
+			opts.Depth, opts.Top = depth, top
+			r := reports.Spending(book, periods, opts)
+			fmt.Printf("Spending from %s to %s: %s\n", r.Start.Format("2006-01-02"),
+				r.End.Format("2006-01-02"), (*types.Amount)(r.Total))
+			fmt.Println("\nTop categories:")
+			for _, c := range r.Categories {
+				fmt.Printf("%12s %5.1f%%  %s\n", (*types.Amount)(c.Amount), 100*c.Share, c.Name)
+			}
+			fmt.Println("\nTop payees:")
+			for _, c := range r.Payees {
+				fmt.Printf("%12s %5.1f%%  %s\n", (*types.Amount)(c.Amount), 100*c.Share, c.Name)
+			}
+			fmt.Println("\nTrends:")
+			for _, tr := range r.Trends {
+				mark := ""
+				if tr.Flagged {
+					mark = " (!)"
+				}
+				fmt.Printf("%s %12s avg %12s MoM %+6.1f%% YoY %+6.1f%%  %s%s\n",
+					tr.Month.Format("Jan 2006"), (*types.Amount)(tr.Current),
+					(*types.Amount)(tr.Average), 100*tr.MoM, 100*tr.YoY, tr.Category, mark)
 			}
 		default:
 			flag.Usage()
 		}
 	case httpAddr != "":
-		err = gui.StartServer(httpAddr, book)
+		var w *gui.Watch
+		if watch > 0 {
+			w = &gui.Watch{Filename: filename, Interval: watch, Load: readBook}
+		}
+		err = gui.StartServer(httpAddr, book, w)
 		if err != nil {
 			log.Fatalf("ERROR: %s", err)
 		}
@@ -68,3 +168,34 @@ func main() {
 		flag.Usage()
 	}
 }
+
+// loadBook loads the named Gnucash XML file, or Ledger journal if the
+// name ends with .journal, .ledger or .hledger, or exits.
+func loadBook(filename string) *types.Book {
+	book, err := readBook(filename)
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+	return book
+}
+
+// readBook loads and recomputes the named Gnucash XML file or Ledger
+// journal.
+func readBook(filename string) (*types.Book, error) {
+	t0 := time.Now()
+	var book *types.Book
+	var err error
+	switch filepath.Ext(filename) {
+	case ".journal", ".ledger", ".hledger":
+		book, err = ledger.ReadFile(filename)
+	default:
+		book, err = xmlimport.ImportFile(filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %q: %s", filename, err)
+	}
+	log.Printf("Loaded %q: %d accounts, %d transactions, in %s",
+		filename, len(book.Accounts), len(book.Transactions), time.Since(t0))
+	book.Recompute()
+	return book, nil
+}
@@ -0,0 +1,227 @@
+// Package match detects imported transactions that are already
+// recorded in a book.
+package match
+
+import (
+	"math"
+	"math/big"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Status is the classification of an incoming transaction.
+type Status int
+
+const (
+	New       Status = iota
+	Duplicate        // Already recorded: must not be added.
+	Probable         // Possibly recorded: needs confirmation.
+)
+
+func (s Status) String() string {
+	switch s {
+	case New:
+		return "new"
+	case Duplicate:
+		return "duplicate"
+	case Probable:
+		return "probable"
+	}
+	return "invalid"
+}
+
+// Options controls the matching.
+type Options struct {
+	Window    int     // Maximum date difference in days.
+	Duplicate float64 // Minimum score of duplicates.
+}
+
+// DefaultOptions are the options used when none are given.
+var DefaultOptions = Options{Window: 3, Duplicate: 0.9}
+
+// A Result is the classification of an incoming transaction.
+type Result struct {
+	Incoming *types.Transaction
+	Status   Status
+	Existing *types.Transaction // The matched transaction, if any.
+	Score    float64            // Between 0 and 1, 1 for identical transactions.
+}
+
+// A Matcher compares incoming transactions with the transactions
+// of an account. Each existing transaction matches at most one
+// incoming transaction.
+type Matcher struct {
+	acct    *types.Account
+	opts    Options
+	byId    map[string]*types.Transaction
+	entries []*entry
+}
+
+type entry struct {
+	trn     *types.Transaction
+	amount  *big.Rat
+	matched bool
+}
+
+// NewMatcher returns a Matcher against the flows of acct in book,
+// which must have been recomputed.
+func NewMatcher(book *types.Book, acct *types.Account, opts Options) *Matcher {
+	if opts.Window == 0 && opts.Duplicate == 0 {
+		opts = DefaultOptions
+	}
+	m := &Matcher{acct: acct, opts: opts, byId: make(map[string]*types.Transaction)}
+	seen := make(map[*types.Transaction]bool)
+	for _, f := range book.Flows[acct] {
+		if !seen[f.Parent] {
+			seen[f.Parent] = true
+			m.Add(f.Parent)
+		}
+	}
+	return m
+}
+
+// Add records trn as an existing transaction, for example after
+// it has been accepted into the book.
+func (m *Matcher) Add(trn *types.Transaction) {
+	if trn.ExternalId != "" {
+		m.byId[trn.ExternalId] = trn
+	}
+	m.entries = append(m.entries, &entry{trn: trn, amount: m.amount(trn)})
+}
+
+// Match classifies trn. Transactions with a known external identifier
+// are duplicates. Otherwise, the existing transaction with the same
+// amount within the date window and the best score is chosen: the score
+// combines the date difference and the similarity of descriptions.
+// Transactions with different external identifiers never match.
+func (m *Matcher) Match(trn *types.Transaction) Result {
+	res := Result{Incoming: trn}
+	if old := m.byId[trn.ExternalId]; trn.ExternalId != "" && old != nil {
+		res.Status, res.Existing, res.Score = Duplicate, old, 1
+		m.setMatched(old)
+		return res
+	}
+	amount := m.amount(trn)
+	var best *entry
+	for _, e := range m.entries {
+		if e.matched || e.amount.Cmp(amount) != 0 {
+			continue
+		}
+		if e.trn.ExternalId != "" && trn.ExternalId != "" {
+			continue
+		}
+		d := days(trn.Date, e.trn.Date)
+		if d > float64(m.opts.Window) {
+			continue
+		}
+		score := Similarity(trn.Description, e.trn.Description)/2 +
+			(1-d/float64(m.opts.Window+1))/2
+		if best == nil || score > res.Score {
+			best, res.Score = e, score
+		}
+	}
+	if best == nil {
+		return res
+	}
+	best.matched = true
+	res.Existing = best.trn
+	if res.Score >= m.opts.Duplicate {
+		res.Status = Duplicate
+	} else {
+		res.Status = Probable
+	}
+	return res
+}
+
+func (m *Matcher) setMatched(trn *types.Transaction) {
+	for _, e := range m.entries {
+		if e.trn == trn {
+			e.matched = true
+		}
+	}
+}
+
+// Amount returns the total of flows of trn in the account.
+func (m *Matcher) Amount(trn *types.Transaction) *types.Amount {
+	return (*types.Amount)(m.amount(trn))
+}
+
+// amount returns the total of flows of trn in the account.
+func (m *Matcher) amount(trn *types.Transaction) *big.Rat {
+	sum := new(big.Rat)
+	for i := range trn.Flows {
+		if f := &trn.Flows[i]; f.Account == m.acct {
+			sum.Add(sum, f.Units().Rat())
+		}
+	}
+	return sum
+}
+
+// Match classifies incoming transactions against the flows of acct in
+// book. New transactions are considered as accepted for the following
+// ones, so that overlapping statements are detected.
+func Match(book *types.Book, acct *types.Account, trns []*types.Transaction, opts Options) []Result {
+	m := NewMatcher(book, acct, opts)
+	results := make([]Result, 0, len(trns))
+	for _, trn := range trns {
+		res := m.Match(trn)
+		if res.Status == New {
+			m.Add(trn)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// Similarity returns the Dice coefficient of the character bigrams
+// of two descriptions, ignoring case, punctuation and digits.
+func Similarity(a, b string) float64 {
+	ba, bb := bigrams(a), bigrams(b)
+	if len(ba) == 0 && len(bb) == 0 {
+		return 1
+	}
+	if len(ba) == 0 || len(bb) == 0 {
+		return 0
+	}
+	common := 0
+	for g, n := range ba {
+		if m := bb[g]; m < n {
+			common += m
+		} else {
+			common += n
+		}
+	}
+	return 2 * float64(common) / float64(count(ba)+count(bb))
+}
+
+func bigrams(s string) map[string]int {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	grams := make(map[string]int)
+	for _, w := range words {
+		r := []rune(w)
+		if len(r) == 1 {
+			grams[w]++
+		}
+		for i := 0; i+1 < len(r); i++ {
+			grams[string(r[i:i+2])]++
+		}
+	}
+	return grams
+}
+
+func count(m map[string]int) (n int) {
+	for _, c := range m {
+		n += c
+	}
+	return n
+}
+
+// days returns the number of days between two dates.
+func days(a, b time.Time) float64 {
+	return math.Abs(a.Sub(b).Hours() / 24)
+}
@@ -0,0 +1,89 @@
+package match
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func TestSimilarity(t *testing.T) {
+	for _, c := range []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"ACME GmbH", "acme gmbh", 1, 1},
+		{"CARTE 12/03 SUPERMARCHE", "Supermarche", 0.5, 0.9},
+		{"Stadtwerke", "Lidl", 0, 0.1},
+		{"", "", 1, 1},
+	} {
+		if s := Similarity(c.a, c.b); s < c.min || s > c.max {
+			t.Errorf("Similarity(%q, %q) = %.2f, expected in [%.2f, %.2f]", c.a, c.b, s, c.min, c.max)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	bank := &types.Account{Id: "bank", Name: "/Bank", Unit: "EUR"}
+	other := &types.Account{Id: "other", Name: "/Expenses", Unit: "EUR"}
+	trn := func(id, date, desc, amount, ext string) *types.Transaction {
+		x, _ := new(big.Rat).SetString(amount)
+		d, _ := time.Parse("2006-01-02", date)
+		t := &types.Transaction{Id: types.GUID(id), Date: d, Description: desc, ExternalId: ext}
+		t.Flows = []types.Flow{
+			{Account: bank, Price: (*types.Amount)(new(big.Rat).Set(x))},
+			{Account: other, Price: (*types.Amount)(new(big.Rat).Neg(x))},
+		}
+		return t
+	}
+	book := &types.Book{
+		Accounts:     map[types.GUID]*types.Account{"bank": bank, "other": other},
+		Transactions: make(map[types.GUID]*types.Transaction),
+	}
+	for _, t := range []*types.Transaction{
+		trn("a", "2013-03-01", "ACME GmbH", "1500", "REF1"),
+		trn("b", "2013-03-02", "Stadtwerke", "-55.50", ""),
+		trn("c", "2013-03-05", "Bakery", "-4", ""),
+		trn("d", "2013-03-06", "Bakery", "-4", ""),
+		trn("e", "2013-03-10", "Card payment", "-20", "CARD1"),
+	} {
+		book.Transactions[t.Id] = t
+	}
+	book.Recompute()
+
+	incoming := []*types.Transaction{
+		trn("1", "2013-03-01", "Salary", "1500", "REF1"),       // same id
+		trn("2", "2013-03-02", "STADTWERKE", "-55.50", "X"),    // same date and payee
+		trn("3", "2013-03-04", "Boulangerie", "-4", ""),        // same amount, different payee
+		trn("4", "2013-03-06", "BAKERY", "-4", ""),             // second bakery
+		trn("5", "2013-03-06", "BAKERY", "-4", ""),             // third one is new
+		trn("6", "2013-03-10", "Card payment", "-20", "CARD2"), // different ids
+		trn("7", "2013-03-20", "Bakery", "-4", ""),             // outside window
+		trn("8", "2013-03-20", "Bakery", "-4", ""),             // same as previous incoming
+	}
+	expected := []struct {
+		status   Status
+		existing types.GUID
+	}{
+		{Duplicate, "a"},
+		{Duplicate, "b"},
+		{Probable, "c"},
+		{Duplicate, "d"},
+		{New, ""},
+		{New, ""},
+		{New, ""},
+		{Duplicate, "7"},
+	}
+	results := Match(book, bank, incoming, DefaultOptions)
+	for i, res := range results {
+		var id types.GUID
+		if res.Existing != nil {
+			id = res.Existing.Id
+		}
+		if res.Status != expected[i].status || id != expected[i].existing {
+			t.Errorf("transaction %s: got %s (%q, score %.2f), expected %s (%q)",
+				res.Incoming.Id, res.Status, id, res.Score, expected[i].status, expected[i].existing)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package mt940
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Import converts the entries of the statement into transactions of
+// acct balanced against counter. The bank reference of each entry,
+// or its customer reference, is kept as the transaction ExternalId.
+func (st *Statement) Import(acct, counter *types.Account) []*types.Transaction {
+	currency := st.Opening.Currency
+	if currency == "" {
+		currency = acct.Unit
+	}
+	now := time.Now()
+	trns := make([]*types.Transaction, 0, len(st.Entries))
+	for _, e := range st.Entries {
+		info := e.Info
+		desc := info.Name
+		if desc == "" {
+			desc = info.Purpose
+		}
+		if desc == "" {
+			desc = info.Text
+		}
+		trn := &types.Transaction{
+			Id:          types.NewGUID(),
+			Date:        e.ValueDate,
+			Stamp:       now,
+			Currency:    currency,
+			Description: desc,
+			ExternalId:  e.reference(),
+		}
+		var notes []string
+		if !e.EntryDate.Equal(e.ValueDate) {
+			notes = append(notes, "Booked: "+e.EntryDate.Format("2006-01-02"))
+		}
+		for _, s := range []string{info.Purpose, info.IBAN, e.Details} {
+			if s != "" && s != desc {
+				notes = append(notes, s)
+			}
+		}
+		if info.EndToEnd != "" {
+			notes = append(notes, "E2E "+info.EndToEnd)
+		}
+		trn.Notes = strings.Join(notes, "\n")
+		neg := new(big.Rat).Neg(e.Amount.Rat())
+		trn.Flows = []types.Flow{
+			{
+				Id:       types.NewGUID(),
+				Account:  acct,
+				Memo:     info.Text,
+				Price:    new(types.Amount).SetRat(e.Amount.Rat()),
+				Quantity: new(types.Amount).SetRat(e.Amount.Rat()),
+				Parent:   trn,
+			},
+			{
+				Id:       types.NewGUID(),
+				Account:  counter,
+				Price:    new(types.Amount).SetRat(neg),
+				Quantity: new(types.Amount).SetRat(neg),
+				Parent:   trn,
+			},
+		}
+		trns = append(trns, trn)
+	}
+	return trns
+}
+
+// reference returns an identifier of the entry, if the bank
+// provides one.
+func (e *Entry) reference() string {
+	for _, ref := range []string{e.BankRef, e.CustomerRef, e.Info.EndToEnd} {
+		if ref != "" && ref != "NONREF" {
+			return ref
+		}
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package mt940
+
+import (
+	"sort"
+	"strings"
+)
+
+// Info is the information to account owner of an entry (field :86:).
+// Banks structure this field in different ways: German banks use
+// a 3-digit transaction code followed by ?NN subfields, Dutch and
+// Belgian banks use /KEY/value pairs. Unstructured fields are only
+// kept as Purpose.
+type Info struct {
+	Code     string // Business transaction code (GVC).
+	Text     string // Booking text, e.g. GUTSCHRIFT.
+	Purpose  string // Remittance information.
+	Name     string // Counterparty name.
+	IBAN     string // Counterparty account.
+	BIC      string // Counterparty bank.
+	EndToEnd string // SEPA end to end reference.
+	Mandate  string // SEPA direct debit mandate.
+	Raw      string
+}
+
+// ParseInfo parses the contents of a :86: field.
+func ParseInfo(s string) Info {
+	info := Info{Raw: s}
+	switch {
+	case len(s) > 4 && isDigits(s[:3]) && s[3] == '?':
+		info.parseSubfields(s[3:], '?')
+	case len(s) > 4 && isDigits(s[:3]) && strings.IndexByte("/+>", s[3]) >= 0 &&
+		strings.Count(s, s[3:4]) > 2:
+		// Same layout with another separator.
+		info.parseSubfields(s[3:], s[3])
+	case strings.HasPrefix(s, "/"):
+		info.parseKeywords(s)
+	default:
+		info.Purpose = s
+	}
+	if info.Code == "" && len(s) >= 3 && isDigits(s[:3]) {
+		info.Code = s[:3]
+	}
+	info.parseSEPA()
+	if info.EndToEnd == "NOTPROVIDED" {
+		info.EndToEnd = ""
+	}
+	return info
+}
+
+// parseSubfields parses German ?NN subfields: ?00 booking text,
+// ?10 primanota, ?20-?29 and ?60-?63 purpose, ?30 BIC, ?31 IBAN,
+// ?32-?33 name.
+func (info *Info) parseSubfields(s string, sep byte) {
+	var purpose, name []string
+	for _, sub := range strings.Split(s, string(sep)) {
+		if len(sub) < 2 || !isDigits(sub[:2]) {
+			continue
+		}
+		key, val := sub[:2], sub[2:]
+		switch {
+		case key == "00":
+			info.Text = val
+		case key >= "20" && key <= "29", key >= "60" && key <= "63":
+			purpose = append(purpose, val)
+		case key == "30":
+			info.BIC = val
+		case key == "31":
+			info.IBAN = val
+		case key == "32", key == "33":
+			name = append(name, val)
+		}
+	}
+	info.Purpose = strings.Join(purpose, "")
+	info.Name = strings.Join(name, "")
+}
+
+// parseKeywords parses /KEY/value subfields, such as
+// /TRTP/SEPA OVERBOEKING/IBAN/NL12.../NAME/X/REMI/Y.
+func (info *Info) parseKeywords(s string) {
+	values := make(map[string]string)
+	parts := strings.Split(s[1:], "/")
+	for i := 0; i+1 < len(parts); {
+		key := parts[i]
+		if !isKeyword(key) {
+			i++
+			continue
+		}
+		// Values may contain slashes: extend them up to the next keyword.
+		j := i + 1
+		for j+1 < len(parts) && !isKeyword(parts[j+1]) {
+			j++
+		}
+		values[key] = strings.Join(parts[i+1:j+1], "/")
+		i = j + 1
+	}
+	info.Text = first(values, "TRTP", "TRCD")
+	info.Name = first(values, "NAME")
+	info.IBAN = first(values, "IBAN")
+	info.BIC = first(values, "BIC")
+	info.EndToEnd = first(values, "EREF")
+	info.Mandate = first(values, "MARF")
+	info.Purpose = strings.TrimPrefix(first(values, "REMI"), "USTD//")
+	for _, k := range []string{"BENM", "ORDP", "CNTP"} {
+		if v, ok := values[k]; ok && info.Name == "" {
+			// Counterparty: account/BIC/name/city.
+			sub := strings.Split(v, "/")
+			if len(sub) >= 3 {
+				info.IBAN, info.BIC, info.Name = sub[0], sub[1], sub[2]
+			}
+		}
+	}
+}
+
+// keywords are the subfield names of /KEY/value structured fields.
+var keywords = []string{
+	"ADDR", "BENM", "BIC", "BUSP", "CNTP", "CSID", "EREF", "IBAN",
+	"ID", "MARF", "NAME", "ORDP", "PREF", "PURP", "REMI", "RTRN",
+	"SVCL", "TRCD", "TRTP", "ULTB", "ULTD",
+}
+
+func isKeyword(s string) bool {
+	i := sort.SearchStrings(keywords, s)
+	return i < len(keywords) && keywords[i] == s
+}
+
+func first(m map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(m[k]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// parseSEPA extracts SEPA keywords (EREF+, MREF+, SVWZ+...) found
+// in the purpose of German statements.
+func (info *Info) parseSEPA() {
+	p := info.Purpose
+	if !strings.Contains(p, "+") {
+		return
+	}
+	var keys []int
+	for _, k := range sepaKeys {
+		if i := strings.Index(p, k+"+"); i >= 0 {
+			keys = append(keys, i)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Ints(keys)
+	keys = append(keys, len(p))
+	for n := 0; n+1 < len(keys); n++ {
+		kv := p[keys[n]:keys[n+1]]
+		key, val := kv[:4], strings.TrimSpace(kv[5:])
+		switch key {
+		case "EREF":
+			if info.EndToEnd == "" && val != "NOTPROVIDED" {
+				info.EndToEnd = val
+			}
+		case "MREF":
+			info.Mandate = val
+		case "SVWZ":
+			info.Purpose = val
+		case "ABWA":
+			if info.Name == "" {
+				info.Name = val
+			}
+		}
+	}
+	if keys[0] > 0 && !strings.Contains(p, "SVWZ+") {
+		info.Purpose = strings.TrimSpace(p[:keys[0]])
+	}
+}
+
+var sepaKeys = []string{"EREF", "KREF", "MREF", "CRED", "DEBT", "SVWZ", "ABWA", "ABWE"}
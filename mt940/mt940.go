@@ -0,0 +1,312 @@
+// Package mt940 implements reading of SWIFT MT940 customer statements.
+package mt940
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Statement is a MT940 statement message.
+type Statement struct {
+	Reference string // Field :20:.
+	Account   string // Field :25:, usually BLZ/account or an IBAN.
+	Number    string // Field :28C:.
+	Opening   Balance
+	Closing   Balance
+	Entries   []Entry
+	Info      string // Field :86: following the closing balance.
+}
+
+// A Balance is an opening or closing balance (fields :60: and :62:).
+type Balance struct {
+	Date     time.Time
+	Currency string
+	Amount   *types.Amount // Negative for debit balances.
+}
+
+// An Entry is a statement line (field :61:) and its information
+// to account owner (field :86:).
+type Entry struct {
+	ValueDate   time.Time
+	EntryDate   time.Time
+	Amount      *types.Amount // Credits are positive, debits negative.
+	Reversal    bool
+	Type        string // Transaction type identification code, e.g. NTRF.
+	CustomerRef string
+	BankRef     string
+	Details     string // Supplementary details.
+	Info        Info
+}
+
+// Read reads all statements of a MT940 file. SWIFT block headers
+// ({1:...}{2:...}{4:) and trailers (-}) are accepted.
+func Read(r io.Reader) ([]*Statement, error) {
+	fields, err := readFields(r)
+	if err != nil {
+		return nil, err
+	}
+	var stmts []*Statement
+	var st *Statement
+	var entry *Entry
+	for _, f := range fields {
+		if f.tag == "" {
+			// End of message.
+			st, entry = nil, nil
+			continue
+		}
+		if st == nil {
+			if f.tag != "20" && f.tag != "25" && f.tag != "28C" && f.tag != "13D" && f.tag != "21" {
+				return nil, fmt.Errorf("line %d: field :%s: outside of a statement", f.line, f.tag)
+			}
+			st = new(Statement)
+			stmts = append(stmts, st)
+		}
+		switch f.tag {
+		case "20":
+			if st.Reference != "" {
+				// Statements are not always separated by "-".
+				st = new(Statement)
+				stmts = append(stmts, st)
+			}
+			st.Reference = f.value
+			entry = nil
+		case "25":
+			st.Account = f.value
+		case "28", "28C":
+			st.Number = f.value
+		case "60F", "60M":
+			st.Opening, err = parseBalance(f.value)
+		case "62F", "62M":
+			st.Closing, err = parseBalance(f.value)
+			entry = nil
+		case "61":
+			var e Entry
+			e, err = parseEntry(f.value)
+			st.Entries = append(st.Entries, e)
+			entry = &st.Entries[len(st.Entries)-1]
+		case "86":
+			if entry != nil {
+				entry.Info = ParseInfo(f.value)
+			} else {
+				st.Info = f.value
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: field :%s: %s", f.line, f.tag, err)
+		}
+	}
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("no MT940 statement found")
+	}
+	return stmts, nil
+}
+
+// ReadFile reads the named MT940 file.
+func ReadFile(name string) ([]*Statement, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// Check verifies that the opening balance plus all entries equals
+// the closing balance.
+func (st *Statement) Check() error {
+	if st.Opening.Amount == nil || st.Closing.Amount == nil {
+		return fmt.Errorf("statement %s: missing opening or closing balance", st.Reference)
+	}
+	if st.Opening.Currency != st.Closing.Currency {
+		return fmt.Errorf("statement %s: opening balance in %s, closing balance in %s",
+			st.Reference, st.Opening.Currency, st.Closing.Currency)
+	}
+	sum := new(big.Rat).Set(st.Opening.Amount.Rat())
+	for _, e := range st.Entries {
+		sum.Add(sum, e.Amount.Rat())
+	}
+	if sum.Cmp(st.Closing.Amount.Rat()) != 0 {
+		return fmt.Errorf("statement %s: opening balance %s plus entries is %s, closing balance is %s",
+			st.Reference, st.Opening.Amount, (*types.Amount)(sum), st.Closing.Amount)
+	}
+	return nil
+}
+
+type field struct {
+	line  int
+	tag   string // Empty for end of message.
+	value string
+}
+
+// readFields splits a MT940 file into fields. Continuation lines
+// of :86: are concatenated, other continuations are kept on
+// separate lines.
+func readFields(r io.Reader) ([]field, error) {
+	var fields []field
+	s := bufio.NewScanner(r)
+	lineno := 0
+	for s.Scan() {
+		lineno++
+		line := strings.TrimRight(s.Text(), "\r ")
+		if i := strings.Index(line, "{4:"); i >= 0 {
+			// SWIFT envelope: the text block starts after {4:.
+			line = line[i+3:]
+		}
+		switch {
+		case line == "":
+			continue
+		case line == "-" || line == "-}" || strings.HasPrefix(line, "-}{"):
+			fields = append(fields, field{line: lineno})
+			continue
+		case line[0] == ':':
+			end := strings.IndexByte(line[1:], ':')
+			if end > 0 {
+				fields = append(fields, field{line: lineno, tag: line[1 : end+1], value: line[end+2:]})
+				continue
+			}
+		}
+		if len(fields) == 0 || fields[len(fields)-1].tag == "" {
+			// Header or garbage outside of statements.
+			continue
+		}
+		last := &fields[len(fields)-1]
+		if last.tag == "86" {
+			last.value += line
+		} else {
+			last.value += "\n" + line
+		}
+	}
+	return fields, s.Err()
+}
+
+// parseBalance parses a balance such as C130301EUR1000,00.
+func parseBalance(s string) (b Balance, err error) {
+	if len(s) < 11 {
+		return b, fmt.Errorf("invalid balance %q", s)
+	}
+	b.Date, err = parseDate(s[1:7])
+	if err != nil {
+		return b, err
+	}
+	b.Currency = s[7:10]
+	b.Amount, err = parseAmount(s[10:])
+	if err != nil {
+		return b, err
+	}
+	switch s[0] {
+	case 'C':
+	case 'D':
+		b.Amount.Rat().Neg(b.Amount.Rat())
+	default:
+		return b, fmt.Errorf("invalid debit/credit mark in %q", s)
+	}
+	return b, nil
+}
+
+// parseEntry parses a statement line:
+//
+//	YYMMDD[MMDD](C|D|RC|RD)[funds code]amount(S|N|F)typeref[//bankref][\ndetails]
+func parseEntry(s string) (e Entry, err error) {
+	orig := s
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s, e.Details = s[:i], strings.Replace(s[i+1:], "\n", "", -1)
+	}
+	if len(s) < 6 {
+		return e, fmt.Errorf("invalid statement line %q", orig)
+	}
+	e.ValueDate, err = parseDate(s[:6])
+	if err != nil {
+		return e, err
+	}
+	s = s[6:]
+	if len(s) >= 4 && isDigits(s[:4]) {
+		e.EntryDate, err = time.Parse("20060102", e.ValueDate.Format("2006")+s[:4])
+		if err != nil {
+			return e, fmt.Errorf("invalid entry date in %q", orig)
+		}
+		// Entries booked across the year end.
+		switch {
+		case e.EntryDate.Sub(e.ValueDate) > 180*24*time.Hour:
+			e.EntryDate = e.EntryDate.AddDate(-1, 0, 0)
+		case e.ValueDate.Sub(e.EntryDate) > 180*24*time.Hour:
+			e.EntryDate = e.EntryDate.AddDate(1, 0, 0)
+		}
+		s = s[4:]
+	} else {
+		e.EntryDate = e.ValueDate
+	}
+	if strings.HasPrefix(s, "R") {
+		e.Reversal = true
+		s = s[1:]
+	}
+	if s == "" || (s[0] != 'C' && s[0] != 'D') {
+		return e, fmt.Errorf("invalid debit/credit mark in %q", orig)
+	}
+	debit := s[0] == 'D'
+	if e.Reversal {
+		// A reversal of a debit is a credit.
+		debit = !debit
+	}
+	s = s[1:]
+	if s != "" && s[0] >= 'A' && s[0] <= 'Z' {
+		// Third letter of the currency code.
+		s = s[1:]
+	}
+	n := strings.IndexAny(s, "NSF")
+	if n <= 0 {
+		return e, fmt.Errorf("invalid amount in %q", orig)
+	}
+	e.Amount, err = parseAmount(s[:n])
+	if err != nil {
+		return e, err
+	}
+	if debit {
+		e.Amount.Rat().Neg(e.Amount.Rat())
+	}
+	s = s[n:]
+	if len(s) < 4 {
+		return e, fmt.Errorf("invalid transaction type in %q", orig)
+	}
+	e.Type, s = s[:4], s[4:]
+	if i := strings.Index(s, "//"); i >= 0 {
+		s, e.BankRef = s[:i], s[i+2:]
+	}
+	e.CustomerRef = s
+	return e, nil
+}
+
+// parseDate parses a YYMMDD date.
+func parseDate(s string) (time.Time, error) {
+	t, err := time.Parse("060102", s)
+	if err != nil {
+		return t, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// parseAmount parses amounts with a decimal comma, such as 1500,
+// or 55,50.
+func parseAmount(s string) (*types.Amount, error) {
+	v := strings.TrimSuffix(strings.Replace(s, ",", ".", 1), ".")
+	x, ok := new(big.Rat).SetString(v)
+	if !ok || v == "" || v[0] == '-' || v[0] == '+' {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return (*types.Amount)(x), nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
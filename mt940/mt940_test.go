@@ -0,0 +1,96 @@
+package mt940
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func TestRead(t *testing.T) {
+	stmts, err := ReadFile("testdata/sample.sta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, expected 2", len(stmts))
+	}
+	for _, st := range stmts {
+		if err := st.Check(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	st := stmts[0]
+	if st.Reference != "STARTUMSE" || st.Account != "10020030/1234567" || len(st.Entries) != 3 {
+		t.Fatalf("unexpected statement %+v", st)
+	}
+	salary := st.Entries[0]
+	if salary.Amount.String() != "1500.00" || salary.Type != "NTRF" || salary.BankRef != "REF0001" {
+		t.Errorf("unexpected entry %+v", salary)
+	}
+	info := salary.Info
+	if info.Code != "166" || info.Text != "GUTSCHRIFT" || info.Name != "ACME GmbH" ||
+		info.IBAN != "DE02100100100006820101" || info.EndToEnd != "SALARY-201303" ||
+		info.Purpose != "Gehalt Maerz 2013" {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	bill := st.Entries[1]
+	if bill.Amount.String() != "-55.50" || bill.EntryDate.Format("2006-01-02") != "2013-03-04" ||
+		bill.CustomerRef != "STADTWERKE" || bill.Details != "/OCMT/EUR55,50/" {
+		t.Errorf("unexpected entry %+v", bill)
+	}
+	info = bill.Info
+	if info.Text != "SEPA INCASSO" || info.Name != "Stadtwerke" || info.Purpose != "Strom 03/2013" ||
+		info.Mandate != "M-123" || info.BIC != "COBADEFFXXX" || info.EndToEnd != "" {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	if fee := st.Entries[2]; !fee.Reversal || fee.Amount.String() != "10.00" ||
+		fee.Info.Purpose != "Ruecklastschrift Gebuehr" {
+		t.Errorf("unexpected entry %+v", fee)
+	}
+	// Entry date in the following year.
+	if e := stmts[1].Entries[0]; e.EntryDate.Format("2006-01-02") != "2014-01-02" {
+		t.Errorf("entry date is %s, expected 2014-01-02", e.EntryDate)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	const data = ":20:X\n:25:ACCT\n:60F:D130101EUR10,00\n:61:130102C5,NMSCNONREF\n:62F:C130102EUR5,00\n-\n"
+	stmts, err := Read(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stmts[0].Check(); err == nil {
+		t.Errorf("expected balance mismatch (-10 + 5 != 5)")
+	}
+	if _, err := Read(strings.NewReader(":20:X\n:61:13010XC5,NMSC\n")); err == nil {
+		t.Errorf("expected error for invalid date")
+	}
+}
+
+func TestImport(t *testing.T) {
+	stmts, err := ReadFile("testdata/sample.sta")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank := &types.Account{Name: "/Bank", Unit: "EUR"}
+	imb := &types.Account{Name: "/Imbalance-EUR", Unit: "EUR"}
+	trns := stmts[0].Import(bank, imb)
+	if len(trns) != 3 {
+		t.Fatalf("got %d transactions, expected 3", len(trns))
+	}
+	if trn := trns[0]; trn.Description != "ACME GmbH" || trn.ExternalId != "REF0001" ||
+		trn.Currency != "EUR" || !strings.Contains(trn.Notes, "Gehalt Maerz 2013") {
+		t.Errorf("unexpected transaction %+v", trn)
+	}
+	if trn := trns[1]; trn.ExternalId != "STADTWERKE" || !strings.Contains(trn.Notes, "Booked: 2013-03-04") ||
+		trn.Flows[1].Price.String() != "55.50" {
+		t.Errorf("unexpected transaction %+v", trn)
+	}
+	if trn := trns[2]; trn.ExternalId != "" || trn.Description != "Ruecklastschrift Gebuehr" {
+		t.Errorf("unexpected transaction %+v", trn)
+	}
+}
@@ -0,0 +1,102 @@
+package ofximport
+
+import (
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Import reads an OFX document from r and converts its statements
+// into transactions of acct balanced against counter.
+func Import(r io.Reader, acct, counter *types.Account) ([]*types.Transaction, error) {
+	stmts, err := Read(r)
+	if err != nil {
+		return nil, err
+	}
+	var trns []*types.Transaction
+	for _, st := range stmts {
+		trns = append(trns, st.Import(acct, counter)...)
+	}
+	return trns, nil
+}
+
+// Import converts the statement into transactions of acct, balanced
+// by flows in counter. Security purchases and sales are balanced by
+// the child account of acct holding the security, if it exists.
+// The FITID of each entry is kept as the transaction ExternalId.
+func (st *Statement) Import(acct, counter *types.Account) []*types.Transaction {
+	currency := st.Currency
+	if currency == "" {
+		currency = acct.Unit
+	}
+	now := time.Now()
+	trns := make([]*types.Transaction, 0, len(st.Transactions))
+	for _, entry := range st.Transactions {
+		desc := entry.Name
+		if desc == "" {
+			desc = entry.Memo
+		}
+		trn := &types.Transaction{
+			Id:          types.NewGUID(),
+			Date:        entry.Posted,
+			Stamp:       now,
+			Currency:    currency,
+			Description: desc,
+			Number:      entry.CheckNum,
+			ExternalId:  entry.FITID,
+		}
+		if entry.Memo != desc {
+			trn.Notes = entry.Memo
+		}
+		other, units := counter, entry.Amount
+		if entry.Security != "" && entry.Units != nil {
+			if sec := securityAccount(acct, entry.Security); sec != nil {
+				other = sec
+				units = entry.Units
+			}
+		}
+		trn.Flows = []types.Flow{
+			{
+				Id:       types.NewGUID(),
+				Account:  acct,
+				Price:    copyAmount(entry.Amount, false),
+				Quantity: copyAmount(entry.Amount, false),
+				Parent:   trn,
+			},
+			{
+				Id:       types.NewGUID(),
+				Account:  other,
+				Price:    copyAmount(entry.Amount, true),
+				Quantity: copyAmount(units, units == entry.Amount),
+				Parent:   trn,
+			},
+		}
+		trns = append(trns, trn)
+	}
+	return trns
+}
+
+// securityAccount looks for a descendant of acct holding the
+// given security.
+func securityAccount(acct *types.Account, security string) *types.Account {
+	for _, c := range acct.Children {
+		if strings.EqualFold(c.Unit, security) {
+			return c
+		}
+		if sec := securityAccount(c, security); sec != nil {
+			return sec
+		}
+	}
+	return nil
+}
+
+func copyAmount(x *types.Amount, neg bool) *types.Amount {
+	y := new(big.Rat).Set(x.Rat())
+	if neg {
+		y.Neg(y)
+	}
+	return (*types.Amount)(y)
+}
@@ -0,0 +1,309 @@
+// Package ofximport implements reading of OFX and QFX bank,
+// credit card and investment statements.
+package ofximport
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Statement is the list of transactions of a bank, credit card or
+// investment account over a period.
+type Statement struct {
+	Type      string // BANK, CREDITCARD or INVESTMENT.
+	Currency  string
+	BankId    string // Bank or broker identifier.
+	AccountId string
+	Start     time.Time
+	End       time.Time
+	// Ledger balance at BalanceDate (nil if absent).
+	Balance     *types.Amount
+	BalanceDate time.Time
+
+	Transactions []Transaction
+}
+
+// A Transaction is a statement entry.
+type Transaction struct {
+	FITID    string // Financial institution's unique identifier.
+	Type     string // CREDIT, DEBIT, CHECK, BUYSTOCK, INCOME...
+	Posted   time.Time
+	Amount   *types.Amount // Signed amount in the statement currency.
+	Name     string        // Payee.
+	Memo     string
+	CheckNum string
+
+	// Investment transactions only.
+	Security  string // Ticker or CUSIP of the security.
+	Units     *types.Amount
+	UnitPrice *types.Amount
+}
+
+// Read reads all statements of an OFX document.
+func Read(r io.Reader) ([]*Statement, error) {
+	ofx, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+	if code := ofx.Get("SIGNONMSGSRSV1/SONRS/STATUS/CODE"); code != "" && code != "0" {
+		return nil, fmt.Errorf("server error %s: %s", code,
+			ofx.Get("SIGNONMSGSRSV1/SONRS/STATUS/MESSAGE"))
+	}
+	securities := readSecurities(ofx)
+
+	var stmts []*Statement
+	for _, rs := range ofx.FindAll("STMTRS") {
+		st, err := readBankStatement(rs, "BANK", "BANKACCTFROM")
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, st)
+	}
+	for _, rs := range ofx.FindAll("CCSTMTRS") {
+		st, err := readBankStatement(rs, "CREDITCARD", "CCACCTFROM")
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, st)
+	}
+	for _, rs := range ofx.FindAll("INVSTMTRS") {
+		st, err := readInvStatement(rs, securities)
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, st)
+	}
+	if len(stmts) == 0 {
+		return nil, fmt.Errorf("no statement found")
+	}
+	return stmts, nil
+}
+
+// ReadFile reads the statements of the named OFX file.
+func ReadFile(name string) ([]*Statement, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file)
+}
+
+func readBankStatement(rs *node, typ, acctfrom string) (st *Statement, err error) {
+	st = &Statement{
+		Type:      typ,
+		Currency:  rs.Get("CURDEF"),
+		BankId:    rs.Get(acctfrom + "/BANKID"),
+		AccountId: rs.Get(acctfrom + "/ACCTID"),
+	}
+	if err = st.readCommon(rs, "BANKTRANLIST"); err != nil {
+		return st, err
+	}
+	if list := rs.Find("BANKTRANLIST"); list != nil {
+		for _, n := range list.FindAll("STMTTRN") {
+			trn, err := readStmtTrn(n)
+			if err != nil {
+				return st, err
+			}
+			st.Transactions = append(st.Transactions, trn)
+		}
+	}
+	return st, nil
+}
+
+func (st *Statement) readCommon(rs *node, list string) (err error) {
+	if s := rs.Get(list + "/DTSTART"); s != "" {
+		if st.Start, err = parseDate(s); err != nil {
+			return err
+		}
+	}
+	if s := rs.Get(list + "/DTEND"); s != "" {
+		if st.End, err = parseDate(s); err != nil {
+			return err
+		}
+	}
+	if s := rs.Get("LEDGERBAL/BALAMT"); s != "" {
+		if st.Balance, err = parseAmount(s); err != nil {
+			return err
+		}
+		if st.BalanceDate, err = parseDate(rs.Get("LEDGERBAL/DTASOF")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readStmtTrn(n *node) (trn Transaction, err error) {
+	trn = Transaction{
+		FITID:    n.Get("FITID"),
+		Type:     n.Get("TRNTYPE"),
+		Name:     n.Get("NAME"),
+		Memo:     n.Get("MEMO"),
+		CheckNum: n.Get("CHECKNUM"),
+	}
+	if trn.Name == "" {
+		trn.Name = n.Get("PAYEE/NAME")
+	}
+	if trn.Posted, err = parseDate(n.Get("DTPOSTED")); err != nil {
+		return trn, fmt.Errorf("transaction %s: %s", trn.FITID, err)
+	}
+	if trn.Amount, err = parseAmount(n.Get("TRNAMT")); err != nil {
+		return trn, fmt.Errorf("transaction %s: %s", trn.FITID, err)
+	}
+	return trn, nil
+}
+
+// readSecurities maps security identifiers to ticker symbols.
+func readSecurities(ofx *node) map[string]string {
+	secs := make(map[string]string)
+	for _, info := range ofx.FindAll("SECINFO") {
+		id := info.Get("SECID/UNIQUEID")
+		ticker := info.Get("TICKER")
+		if ticker == "" {
+			ticker = info.Get("SECNAME")
+		}
+		if ticker == "" {
+			ticker = id
+		}
+		secs[id] = ticker
+	}
+	return secs
+}
+
+func readInvStatement(rs *node, securities map[string]string) (st *Statement, err error) {
+	st = &Statement{
+		Type:      "INVESTMENT",
+		Currency:  rs.Get("CURDEF"),
+		BankId:    rs.Get("INVACCTFROM/BROKERID"),
+		AccountId: rs.Get("INVACCTFROM/ACCTID"),
+	}
+	if err = st.readCommon(rs, "INVTRANLIST"); err != nil {
+		return st, err
+	}
+	if s := rs.Get("INVBAL/AVAILCASH"); s != "" {
+		if st.Balance, err = parseAmount(s); err != nil {
+			return st, err
+		}
+		if st.BalanceDate, err = parseDate(rs.Get("DTASOF")); err != nil {
+			return st, err
+		}
+	}
+	list := rs.Find("INVTRANLIST")
+	if list == nil {
+		return st, nil
+	}
+	for _, n := range list.Children {
+		var trn Transaction
+		switch n.Name {
+		case "DTSTART", "DTEND":
+			continue
+		case "INVBANKTRAN":
+			stmt := n.Find("STMTTRN")
+			if stmt == nil {
+				return st, fmt.Errorf("INVBANKTRAN without STMTTRN")
+			}
+			trn, err = readStmtTrn(stmt)
+			if err != nil {
+				return st, err
+			}
+			st.Transactions = append(st.Transactions, trn)
+			continue
+		}
+		typ := n.Name
+		inv := n.Find("INVTRAN")
+		if inv == nil {
+			// Buy and sell transactions wrap INVTRAN in INVBUY or INVSELL.
+			for _, c := range n.Children {
+				if inv = c.Find("INVTRAN"); inv != nil {
+					n = c
+					break
+				}
+			}
+		}
+		if inv == nil {
+			continue
+		}
+		trn = Transaction{
+			FITID: inv.Get("FITID"),
+			Type:  typ,
+			Memo:  inv.Get("MEMO"),
+		}
+		if trn.Posted, err = parseDate(inv.Get("DTTRADE")); err != nil {
+			return st, fmt.Errorf("transaction %s: %s", trn.FITID, err)
+		}
+		id := n.Get("SECID/UNIQUEID")
+		trn.Security = securities[id]
+		if trn.Security == "" {
+			trn.Security = id
+		}
+		if s := n.Get("UNITS"); s != "" {
+			if trn.Units, err = parseAmount(s); err != nil {
+				return st, err
+			}
+		}
+		if s := n.Get("UNITPRICE"); s != "" {
+			if trn.UnitPrice, err = parseAmount(s); err != nil {
+				return st, err
+			}
+		}
+		if trn.Amount, err = parseAmount(n.Get("TOTAL")); err != nil {
+			return st, fmt.Errorf("transaction %s: %s", trn.FITID, err)
+		}
+		trn.Name = trn.Type + " " + trn.Security
+		st.Transactions = append(st.Transactions, trn)
+	}
+	return st, nil
+}
+
+// parseDate parses an OFX date: YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]].
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	loc := time.UTC
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		tz := strings.TrimSuffix(s[i+1:], "]")
+		s = s[:i]
+		name := ""
+		if j := strings.IndexByte(tz, ':'); j >= 0 {
+			tz, name = tz[:j], tz[j+1:]
+		}
+		hours, err := strconv.ParseFloat(tz, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid time zone in date %q", s)
+		}
+		loc = time.FixedZone(name, int(hours*3600))
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	var layout string
+	switch len(s) {
+	case 8:
+		layout = "20060102"
+	case 12:
+		layout = "200601021504"
+	case 14:
+		layout = "20060102150405"
+	default:
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return time.ParseInLocation(layout, s, loc)
+}
+
+// parseAmount parses a decimal amount. Some banks use a comma
+// as the decimal separator.
+func parseAmount(s string) (*types.Amount, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", ".", 1)
+	s = strings.TrimPrefix(s, "+")
+	x, ok := new(big.Rat).SetString(s)
+	if !ok || s == "" {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return (*types.Amount)(x), nil
+}
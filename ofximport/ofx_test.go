@@ -0,0 +1,111 @@
+package ofximport
+
+import (
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/types"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func TestReadSGML(t *testing.T) {
+	stmts, err := ReadFile("testdata/bank1.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 {
+		t.Fatalf("got %d statements, expected 1", len(stmts))
+	}
+	st := stmts[0]
+	if st.Type != "BANK" || st.Currency != "USD" || st.AccountId != "999988" {
+		t.Errorf("unexpected statement header %+v", st)
+	}
+	if st.Balance.String() != "2299.50" {
+		t.Errorf("got balance %s, expected 2299.50", st.Balance)
+	}
+	if len(st.Transactions) != 2 {
+		t.Fatalf("got %d transactions, expected 2", len(st.Transactions))
+	}
+	trn := st.Transactions[0]
+	if trn.FITID != "20130302001" || trn.Memo != "Salary & bonus" || trn.Amount.String() != "1500.00" {
+		t.Errorf("unexpected transaction %+v", trn)
+	}
+	trn = st.Transactions[1]
+	if trn.Name != "Café du Coin" || trn.CheckNum != "1042" || trn.Amount.String() != "-200.50" {
+		t.Errorf("unexpected transaction %+v", trn)
+	}
+	if _, off := trn.Posted.Zone(); off != -5*3600 || trn.Posted.Hour() != 12 {
+		t.Errorf("wrong date %s", trn.Posted)
+	}
+}
+
+func TestReadXML(t *testing.T) {
+	stmts, err := ReadFile("testdata/invest2.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, expected 2", len(stmts))
+	}
+	cc, inv := stmts[0], stmts[1]
+	if cc.Type != "CREDITCARD" || len(cc.Transactions) != 1 || cc.Transactions[0].FITID != "CC-1" {
+		t.Errorf("unexpected credit card statement %+v", cc)
+	}
+	if inv.Type != "INVESTMENT" || inv.BankId != "broker.example.com" || len(inv.Transactions) != 3 {
+		t.Fatalf("unexpected investment statement %+v", inv)
+	}
+	buy := inv.Transactions[0]
+	if buy.Type != "BUYSTOCK" || buy.Security != "ACME" || buy.Units.String() != "5.00" || buy.Amount.String() != "-300.00" {
+		t.Errorf("unexpected buy %+v", buy)
+	}
+	if div := inv.Transactions[1]; div.Type != "INCOME" || div.Amount.String() != "20.00" {
+		t.Errorf("unexpected income %+v", div)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	data, err := os.ReadFile("testdata/invest2.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A bank transaction of the investment statement lacks its details.
+	bad := regexp.MustCompile(`(?s)<INVBANKTRAN>.*</STMTTRN>`).ReplaceAllString(string(data), "<INVBANKTRAN>")
+	if _, err := Read(strings.NewReader(bad)); err == nil || !strings.Contains(err.Error(), "STMTTRN") {
+		t.Errorf("got error %v, expected missing STMTTRN", err)
+	}
+}
+
+func TestImport(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := book.AccountByName("/Assets/Broker")
+	counter := book.EnsureAccount("/Imbalance-USD", "BANK", "USD")
+	stmts, err := ReadFile("testdata/invest2.ofx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	trns := stmts[1].Import(broker, counter)
+	if len(trns) != 3 {
+		t.Fatalf("got %d transactions, expected 3", len(trns))
+	}
+	for _, trn := range trns {
+		total := new(types.Amount)
+		for _, f := range trn.Flows {
+			total.Add(f.Price)
+		}
+		if total.Rat().Sign() != 0 {
+			t.Errorf("transaction %q is not balanced", trn.Description)
+		}
+	}
+	buy := trns[0]
+	if buy.ExternalId != "INV-1" || buy.Flows[1].Account.Unit != "ACME" || buy.Flows[1].Quantity.String() != "5.00" {
+		t.Errorf("unexpected buy transaction %+v", buy.Flows[1])
+	}
+	if trns[1].Flows[1].Account != counter {
+		t.Errorf("income should be balanced by counter account")
+	}
+}
@@ -0,0 +1,183 @@
+package ofximport
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// A node is an element of an OFX document. Aggregates have children,
+// elements have a value.
+type node struct {
+	Name     string
+	Value    string
+	Children []*node
+}
+
+// Get returns the value of the descendant element at the given
+// slash-separated path.
+func (n *node) Get(path string) string {
+	if c := n.Find(path); c != nil {
+		return c.Value
+	}
+	return ""
+}
+
+// Find returns the descendant at the given slash-separated path.
+func (n *node) Find(path string) *node {
+	cur := n
+	for _, name := range strings.Split(path, "/") {
+		var next *node
+		for _, c := range cur.Children {
+			if c.Name == name {
+				next = c
+				break
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		cur = next
+	}
+	return cur
+}
+
+// FindAll returns all descendants with the given name, in document order.
+func (n *node) FindAll(name string) (nodes []*node) {
+	for _, c := range n.Children {
+		if c.Name == name {
+			nodes = append(nodes, c)
+		} else {
+			nodes = append(nodes, c.FindAll(name)...)
+		}
+	}
+	return nodes
+}
+
+// parse reads an OFX document. The same parser accepts OFX 1.x (SGML,
+// where element end tags are optional) and OFX 2.x (XML).
+func parse(r io.Reader) (*node, error) {
+	br := bufio.NewReader(r)
+	header, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(br)
+	if err != nil {
+		return nil, err
+	}
+	if cs := header["CHARSET"]; cs == "1252" || cs == "ISO-8859-1" || header["ENCODING"] == "USASCII" && !utf8.Valid(body) {
+		body = latin1ToUTF8(body)
+	}
+
+	root := &node{}
+	stack := []*node{root}
+	for len(body) > 0 {
+		lt := bytes.IndexByte(body, '<')
+		if lt < 0 {
+			break
+		}
+		body = body[lt:]
+		gt := bytes.IndexByte(body, '>')
+		if gt < 0 {
+			return nil, fmt.Errorf("unterminated tag %.20q", body)
+		}
+		tag := string(body[1:gt])
+		body = body[gt+1:]
+		switch {
+		case strings.HasPrefix(tag, "?"), strings.HasPrefix(tag, "!"):
+			// Processing instruction or comment.
+			if strings.HasPrefix(tag, "!--") && !strings.HasSuffix(tag, "--") {
+				end := bytes.Index(body, []byte("-->"))
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated comment")
+				}
+				body = body[end+3:]
+			}
+		case strings.HasPrefix(tag, "/"):
+			name := strings.ToUpper(strings.TrimSpace(tag[1:]))
+			// Close the matching aggregate, if any.
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].Name == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			name := strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(tag, "/")))
+			if i := strings.IndexAny(name, " \t\r\n"); i >= 0 {
+				name = name[:i]
+			}
+			n := &node{Name: name}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, n)
+			if strings.HasSuffix(tag, "/") {
+				break
+			}
+			next := bytes.IndexByte(body, '<')
+			if next < 0 {
+				next = len(body)
+			}
+			text := strings.TrimSpace(string(body[:next]))
+			if text == "" {
+				// An aggregate.
+				stack = append(stack, n)
+				break
+			}
+			n.Value = unescape(text)
+			body = body[next:]
+			// Skip the optional end tag of the element.
+			if end := "</" + name + ">"; len(body) >= len(end) &&
+				strings.EqualFold(string(body[:len(end)]), end) {
+				body = body[len(end):]
+			}
+		}
+	}
+	ofx := root.Find("OFX")
+	if ofx == nil {
+		return nil, fmt.Errorf("no OFX element found")
+	}
+	return ofx, nil
+}
+
+// readHeader reads the OFX 1.x colon-separated header, if present.
+func readHeader(br *bufio.Reader) (map[string]string, error) {
+	header := make(map[string]string)
+	for {
+		b, err := br.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return header, nil
+			}
+			return nil, err
+		}
+		if b[0] == '<' {
+			return header, nil
+		}
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if i := strings.IndexByte(line, ':'); i > 0 {
+			header[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+		if err == io.EOF {
+			return header, nil
+		}
+	}
+}
+
+var entities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+func unescape(s string) string { return entities.Replace(s) }
+
+func latin1ToUTF8(b []byte) []byte {
+	buf := make([]byte, 0, len(b))
+	for _, c := range b {
+		buf = utf8.AppendRune(buf, rune(c))
+	}
+	return buf
+}
@@ -0,0 +1,289 @@
+package qif
+
+import (
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// accountTypes maps QIF account types to Gnucash account types.
+var accountTypes = map[string]string{
+	"Bank":  "BANK",
+	"Cash":  "CASH",
+	"CCard": "CREDIT",
+	"Invst": "ASSET",
+	"Port":  "ASSET",
+	"Oth A": "ASSET",
+	"Oth L": "LIABILITY",
+}
+
+// Import reads a QIF file from r and adds its accounts and
+// transactions to book.
+func Import(r io.Reader, book *types.Book, currency string, opts Options) error {
+	f, err := Read(r, opts)
+	if err != nil {
+		return err
+	}
+	f.Import(book, currency)
+	return nil
+}
+
+// Import adds the accounts, categories and transactions of f to book.
+// Accounts are created under /Assets or /Liabilities, and categories
+// under /Expenses or /Income, unless an account with the same name
+// already exists. Transfers between two accounts of the file appear
+// on both sides and are imported once.
+func (f *File) Import(book *types.Book, currency string) {
+	imp := &importer{
+		book:      book,
+		currency:  currency,
+		accounts:  make(map[string]*types.Account),
+		income:    make(map[string]bool),
+		transfers: make(map[transferKey]int),
+	}
+	for _, a := range f.Accounts {
+		imp.account(a.Name, a.Type).Description = a.Description
+	}
+	for _, c := range f.Categories {
+		imp.income[c.Name] = c.Income
+		acct := imp.category(c.Name)
+		if acct.Description == "" {
+			acct.Description = c.Description
+		}
+	}
+	for _, a := range f.Accounts {
+		acct := imp.account(a.Name, a.Type)
+		for _, e := range a.Entries {
+			if trn := imp.entry(acct, a.Type, e); trn != nil {
+				book.Transactions[trn.Id] = trn
+			}
+		}
+	}
+}
+
+type importer struct {
+	book      *types.Book
+	currency  string
+	accounts  map[string]*types.Account
+	income    map[string]bool
+	transfers map[transferKey]int
+}
+
+// A transferKey identifies the side of a transfer already imported.
+type transferKey struct {
+	date     time.Time
+	from, to *types.Account
+	amount   string
+}
+
+// account returns the book account for a QIF account name.
+func (imp *importer) account(name, qifType string) *types.Account {
+	if acct := imp.accounts[name]; acct != nil {
+		return acct
+	}
+	path := strings.Replace(name, ":", "/", -1)
+	acct := imp.book.AccountByName("/" + path)
+	if acct == nil {
+		// Use the first account, by name, whose name ends with path.
+		for _, a := range imp.book.Accounts {
+			if strings.HasSuffix(a.Name, "/"+path) && a.Type != "EXPENSE" && a.Type != "INCOME" &&
+				(acct == nil || a.Name < acct.Name) {
+				acct = a
+			}
+		}
+	}
+	if acct == nil {
+		typ := accountTypes[qifType]
+		if typ == "" {
+			typ = "BANK"
+		}
+		group := "/Assets/"
+		if typ == "CREDIT" || typ == "LIABILITY" {
+			group = "/Liabilities/"
+		}
+		acct = imp.book.EnsureAccount(group+path, typ, imp.currency)
+	}
+	imp.accounts[name] = acct
+	return acct
+}
+
+// category returns the book account for a category name, which may
+// also designate an account in brackets. Classes after a slash are ignored.
+func (imp *importer) category(name string) *types.Account {
+	if i := strings.IndexByte(name, '/'); i >= 0 && !IsTransfer(name) {
+		name = name[:i]
+	}
+	if IsTransfer(name) {
+		return imp.account(name[1:len(name)-1], "")
+	}
+	if name == "" {
+		return imp.book.EnsureAccount("/Imbalance-"+imp.currency, "BANK", imp.currency)
+	}
+	path := strings.Replace(name, ":", "/", -1)
+	if imp.income[name] || imp.income[strings.SplitN(name, ":", 2)[0]] {
+		return imp.book.EnsureAccount("/Income/"+path, "INCOME", imp.currency)
+	}
+	if acct := imp.book.AccountByName("/Income/" + path); acct != nil {
+		return acct
+	}
+	return imp.book.EnsureAccount("/Expenses/"+path, "EXPENSE", imp.currency)
+}
+
+// entry converts a QIF entry of acct into a transaction. It returns nil
+// if the entry is the second side of an already imported transfer.
+func (imp *importer) entry(acct *types.Account, qifType string, e Entry) *types.Transaction {
+	trn := &types.Transaction{
+		Id:          types.NewGUID(),
+		Date:        e.Date,
+		Stamp:       time.Now(),
+		Currency:    imp.currency,
+		Description: e.Payee,
+		Number:      e.Number,
+	}
+	if trn.Description == "" {
+		trn.Description = e.Memo
+	}
+	state := types.NotCleared
+	switch e.Cleared {
+	case "*", "c":
+		state = types.Cleared
+	case "X", "R":
+		state = types.Reconciled
+	}
+	if qifType == "Invst" {
+		return imp.investment(acct, trn, e, state)
+	}
+
+	main := newFlow(acct, e.Amount, e.Memo, state)
+	trn.Flows = append(trn.Flows, main)
+	if len(e.Splits) == 0 {
+		other := imp.category(e.Category)
+		if IsTransfer(e.Category) && imp.seenTransfer(trn.Date, acct, other, e.Amount) {
+			return nil
+		}
+		trn.Flows = append(trn.Flows, newFlow(other, neg(e.Amount), "", types.NotCleared))
+	} else {
+		for _, s := range e.Splits {
+			amount := s.Amount
+			if amount == nil {
+				amount = new(types.Amount)
+			}
+			other := imp.category(s.Category)
+			if IsTransfer(s.Category) && imp.seenTransfer(trn.Date, acct, other, amount) {
+				// The other account has this part of the entry.
+				trn.Flows[0].Price = (*types.Amount)(new(big.Rat).Sub(trn.Flows[0].Price.Rat(), amount.Rat()))
+				trn.Flows[0].Quantity = new(types.Amount).SetRat(trn.Flows[0].Price.Rat())
+				continue
+			}
+			trn.Flows = append(trn.Flows, newFlow(other, neg(amount), s.Memo, types.NotCleared))
+		}
+		if len(trn.Flows) == 1 {
+			return nil
+		}
+	}
+	for i := range trn.Flows {
+		trn.Flows[i].Parent = trn
+	}
+	return trn
+}
+
+// investment converts an investment entry. Securities are held in
+// STOCK subaccounts of the investment account, cash in the account itself.
+func (imp *importer) investment(acct *types.Account, trn *types.Transaction, e Entry, state types.ReconcileState) *types.Transaction {
+	action := strings.ToLower(e.Action)
+	if trn.Description == "" {
+		trn.Description = strings.TrimSpace(e.Action + " " + e.Security)
+	}
+	cash := acct
+	if IsTransfer(e.Category) {
+		cash = imp.category(e.Category)
+	}
+	amount := e.Amount
+	switch {
+	case strings.HasPrefix(action, "buy"), strings.HasPrefix(action, "sell"),
+		strings.HasPrefix(action, "shrs"), strings.HasPrefix(action, "reinv"):
+		sec := imp.book.EnsureAccount(acct.Name+"/"+e.Security, "STOCK", e.Security)
+		qty := e.Quantity
+		if qty == nil {
+			qty = new(types.Amount)
+		}
+		value := amount
+		if strings.HasPrefix(action, "sell") || action == "shrsout" {
+			qty, value = neg(qty), neg(amount)
+		}
+		f := newFlow(sec, value, e.Memo, state)
+		f.Quantity = qty
+		trn.Flows = append(trn.Flows, f)
+		switch {
+		case strings.HasPrefix(action, "shrs"):
+			equity := imp.book.EnsureAccount("/Equity/Opening Balances", "EQUITY", imp.currency)
+			trn.Flows = append(trn.Flows, newFlow(equity, neg(value), "", types.NotCleared))
+		case strings.HasPrefix(action, "reinv"):
+			trn.Flows = append(trn.Flows, newFlow(imp.incomeAccount(e.Action), neg(value), "", types.NotCleared))
+		default:
+			trn.Flows = append(trn.Flows, newFlow(cash, neg(value), "", types.NotCleared))
+		}
+	case action == "xin" || action == "xout":
+		value := amount
+		if action == "xout" {
+			value = neg(amount)
+		}
+		other := imp.category(e.Category)
+		if imp.seenTransfer(trn.Date, acct, other, value) {
+			return nil
+		}
+		trn.Flows = append(trn.Flows, newFlow(acct, value, e.Memo, state),
+			newFlow(other, neg(value), "", types.NotCleared))
+	default:
+		// Income (Div, IntInc, CGLong...) or miscellaneous cash entries.
+		trn.Flows = append(trn.Flows, newFlow(cash, amount, e.Memo, state),
+			newFlow(imp.incomeAccount(e.Action), neg(amount), "", types.NotCleared))
+	}
+	for i := range trn.Flows {
+		trn.Flows[i].Parent = trn
+	}
+	return trn
+}
+
+func (imp *importer) incomeAccount(action string) *types.Account {
+	name := "Investment Income"
+	switch strings.TrimPrefix(strings.ToLower(action), "reinv") {
+	case "div":
+		name = "Dividends"
+	case "int", "intinc":
+		name = "Interest"
+	case "cglong", "cgshort", "cgmid", "lg", "sh", "md":
+		name = "Capital Gains"
+	}
+	return imp.book.EnsureAccount("/Income/"+name, "INCOME", imp.currency)
+}
+
+// seenTransfer records a transfer from one account to another and
+// reports whether its other side was already imported.
+func (imp *importer) seenTransfer(date time.Time, from, to *types.Account, amount *types.Amount) bool {
+	mirror := transferKey{date, to, from, neg(amount).Rat().RatString()}
+	if imp.transfers[mirror] > 0 {
+		imp.transfers[mirror]--
+		return true
+	}
+	imp.transfers[transferKey{date, from, to, amount.Rat().RatString()}]++
+	return false
+}
+
+func newFlow(acct *types.Account, amount *types.Amount, memo string, state types.ReconcileState) types.Flow {
+	return types.Flow{
+		Id:       types.NewGUID(),
+		Account:  acct,
+		Memo:     memo,
+		Price:    amount,
+		Quantity: new(types.Amount).SetRat(amount.Rat()),
+		State:    state,
+	}
+}
+
+func neg(x *types.Amount) *types.Amount {
+	return (*types.Amount)(new(big.Rat).Neg(x.Rat()))
+}
@@ -0,0 +1,169 @@
+package qif
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/types"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func TestRead(t *testing.T) {
+	f, err := ReadFile("testdata/sample.qif", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Accounts) != 4 || len(f.Categories) != 3 {
+		t.Fatalf("got %d accounts and %d categories", len(f.Accounts), len(f.Categories))
+	}
+	chk := f.Accounts[0]
+	if chk.Name != "Checking" || chk.Description != "Main checking account" || len(chk.Entries) != 4 {
+		t.Fatalf("unexpected account %+v", chk)
+	}
+	e := chk.Entries[1]
+	if e.Date.Format("2006-01-02") != "2013-01-05" || e.Amount.String() != "-120.50" ||
+		e.Number != "1001" || e.Category != "Food:Groceries" || e.Cleared != "*" {
+		t.Errorf("unexpected entry %+v", e)
+	}
+	if s := chk.Entries[3].Splits; len(s) != 2 || s[1].Memo != "Paper" || s[1].Amount.String() != "-30.00" {
+		t.Errorf("unexpected splits %+v", s)
+	}
+	buy := f.Accounts[3].Entries[1]
+	if buy.Action != "Buy" || buy.Security != "ACME" || buy.Quantity.String() != "10.00" {
+		t.Errorf("unexpected investment entry %+v", buy)
+	}
+}
+
+func TestImport(t *testing.T) {
+	f, err := ReadFile("testdata/sample.qif", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := &types.Book{Transactions: make(map[types.GUID]*types.Transaction)}
+	f.Import(book, "USD")
+	book.Recompute()
+
+	// The transfer to savings appears on both sides but is imported once.
+	if n := len(book.Transactions); n != 8 {
+		t.Errorf("got %d transactions, expected 8", n)
+	}
+	balances := map[string]string{
+		"/Assets/Checking":         "-0.50",
+		"/Assets/Savings":          "300.00",
+		"/Assets/Brokerage":        "812.00",
+		"/Assets/Brokerage/ACME":   "200.00",
+		"/Expenses/Food/Groceries": "170.50",
+		"/Expenses/Household":      "30.00",
+		"/Income/Salary":           "-1500.00",
+		"/Income/Dividends":        "-12.00",
+		"/Liabilities/Visa":        "0.00",
+	}
+	for name, exp := range balances {
+		acct := book.AccountByName(name)
+		if acct == nil {
+			t.Errorf("missing account %s", name)
+			continue
+		}
+		if got := book.Balance[acct].String(); got != exp {
+			t.Errorf("balance of %s: got %s, expected %s", name, got, exp)
+		}
+	}
+	if acct := book.AccountByName("/Liabilities/Visa"); acct.Type != "CREDIT" {
+		t.Errorf("Visa has type %s", acct.Type)
+	}
+	for _, trn := range book.Transactions {
+		total := new(types.Amount)
+		for _, f := range trn.Flows {
+			total.Add(f.Price)
+		}
+		if total.Rat().Sign() != 0 {
+			t.Errorf("transaction %q is not balanced", trn.Description)
+		}
+	}
+}
+
+func TestImportSplitTransfers(t *testing.T) {
+	f, err := ReadFile("testdata/splits.qif", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := &types.Book{Transactions: make(map[types.GUID]*types.Transaction)}
+	// Names matching several accounts resolve to the first one.
+	for _, name := range []string{"/Assets/Joint/Savings", "/Assets/Bank/Savings"} {
+		book.EnsureAccount(name, "BANK", "USD")
+	}
+	f.Import(book, "USD")
+	book.Recompute()
+
+	// Transfers in splits are imported once, whichever side comes first.
+	if n := len(book.Transactions); n != 4 {
+		t.Errorf("got %d transactions, expected 4", n)
+	}
+	balances := map[string]string{
+		"/Assets/Checking":         "-200.00",
+		"/Assets/Bank/Savings":     "145.00",
+		"/Assets/Joint/Savings":    "0.00",
+		"/Expenses/Food/Groceries": "50.00",
+		"/Expenses/Bank Fees":      "5.00",
+	}
+	for name, exp := range balances {
+		acct := book.AccountByName(name)
+		if acct == nil {
+			t.Errorf("missing account %s", name)
+			continue
+		}
+		if got := book.Balance[acct].String(); got != exp {
+			t.Errorf("balance of %s: got %s, expected %s", name, got, exp)
+		}
+	}
+	for _, trn := range book.Transactions {
+		total := new(types.Amount)
+		for _, f := range trn.Flows {
+			total.Add(f.Price)
+		}
+		if total.Rat().Sign() != 0 {
+			t.Errorf("transaction %q is not balanced", trn.Description)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/carols-data-file.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	buf := new(bytes.Buffer)
+	if err := Write(buf, book); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%.1024s", buf)
+
+	// Read it back.
+	f, err := Read(bytes.NewReader(buf.Bytes()), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy := &types.Book{Transactions: make(map[types.GUID]*types.Transaction)}
+	f.Import(copy, "USD")
+	copy.Recompute()
+	for _, acct := range book.Accounts {
+		if qifTypes[acct.Type] == "" {
+			continue
+		}
+		var other *types.Account
+		for _, a := range copy.Accounts {
+			if strings.HasSuffix(a.Name, "/"+qifName(acct)) && a.Type != "EXPENSE" && a.Type != "INCOME" {
+				other = a
+			}
+		}
+		if other == nil {
+			t.Errorf("account %s not found after round trip", acct.Name)
+			continue
+		}
+		if a, b := book.Balance[acct].String(), copy.Balance[other].String(); a != b {
+			t.Errorf("balance of %s: %s before, %s after round trip", acct.Name, a, b)
+		}
+	}
+}
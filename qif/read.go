@@ -0,0 +1,301 @@
+// Package qif implements reading and writing of the Quicken
+// Interchange Format.
+package qif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A File is the contents of a QIF file.
+type File struct {
+	Accounts   []*Account
+	Categories []Category
+}
+
+// An Account is a QIF account and its transactions.
+type Account struct {
+	Name        string
+	Type        string // Bank, Cash, CCard, Invst, Oth A, Oth L.
+	Description string
+	Entries     []Entry
+}
+
+// A Category is an income or expense category. Subcategories
+// are separated by colons.
+type Category struct {
+	Name        string
+	Description string
+	Income      bool
+}
+
+// An Entry is a QIF transaction.
+type Entry struct {
+	Date     time.Time
+	Amount   *types.Amount
+	Number   string
+	Payee    string
+	Memo     string
+	Cleared  string // "", "*" or "c" (cleared), "X" or "R" (reconciled).
+	Category string // A category, or an account name in brackets.
+	Splits   []Split
+
+	// Investment entries only.
+	Action   string // Buy, Sell, Div, ShrsIn, XIn...
+	Security string
+	Price    *types.Amount
+	Quantity *types.Amount
+}
+
+// A Split is a line of a split transaction.
+type Split struct {
+	Category string
+	Memo     string
+	Amount   *types.Amount
+}
+
+// IsTransfer reports whether a category denotes an account.
+func IsTransfer(category string) bool {
+	return strings.HasPrefix(category, "[") && strings.HasSuffix(category, "]")
+}
+
+// Options controls the interpretation of QIF files.
+type Options struct {
+	DayFirst bool // Dates are DD/MM/YY instead of MM/DD/YY.
+}
+
+// Read reads a QIF file from r.
+func Read(r io.Reader, opts Options) (*File, error) {
+	f := new(File)
+	s := bufio.NewScanner(r)
+	var (
+		section string
+		account *Account    // Current account.
+		fields  [][2]string // Fields of the current record.
+		lineno  int
+	)
+	for s.Scan() {
+		lineno++
+		line := strings.TrimRight(s.Text(), "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if line[0] == '!' {
+			header := strings.TrimSpace(line[1:])
+			switch {
+			case strings.HasPrefix(header, "Option:"), strings.HasPrefix(header, "Clear:"):
+				continue
+			case strings.HasPrefix(header, "Type:"):
+				section = header[5:]
+				if section != "Cat" && section != "Class" && section != "Memorized" &&
+					section != "Security" && section != "Prices" && account == nil {
+					account = &Account{Name: "QIF Import", Type: section}
+					f.Accounts = append(f.Accounts, account)
+				}
+			default:
+				section = header
+			}
+			continue
+		}
+		if line[0] != '^' {
+			fields = append(fields, [2]string{line[:1], strings.TrimSpace(line[1:])})
+			continue
+		}
+		// End of record.
+		var err error
+		switch section {
+		case "Account":
+			a := readAccount(fields)
+			account = f.account(a)
+		case "Cat":
+			f.Categories = append(f.Categories, readCategory(fields))
+		case "Class", "Memorized", "Security", "Prices":
+			// Ignored.
+		default:
+			if account == nil {
+				return nil, fmt.Errorf("line %d: transaction outside of account", lineno)
+			}
+			if account.Type == "" {
+				account.Type = section
+			}
+			var e Entry
+			e, err = readEntry(fields, section == "Invst", opts)
+			if err == nil {
+				account.Entries = append(account.Entries, e)
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineno, err)
+		}
+		fields = fields[:0]
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ReadFile reads the named QIF file.
+func ReadFile(name string, opts Options) (*File, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Read(file, opts)
+}
+
+// account returns the account of f with the same name as a,
+// adding it if necessary.
+func (f *File) account(a *Account) *Account {
+	for _, acct := range f.Accounts {
+		if acct.Name == a.Name {
+			if acct.Description == "" {
+				acct.Description = a.Description
+			}
+			return acct
+		}
+	}
+	f.Accounts = append(f.Accounts, a)
+	return a
+}
+
+func readAccount(fields [][2]string) *Account {
+	a := new(Account)
+	for _, fl := range fields {
+		switch fl[0] {
+		case "N":
+			a.Name = fl[1]
+		case "T":
+			a.Type = fl[1]
+		case "D":
+			a.Description = fl[1]
+		}
+	}
+	return a
+}
+
+func readCategory(fields [][2]string) Category {
+	var c Category
+	for _, fl := range fields {
+		switch fl[0] {
+		case "N":
+			c.Name = fl[1]
+		case "D":
+			c.Description = fl[1]
+		case "I":
+			c.Income = true
+		}
+	}
+	return c
+}
+
+// readEntry reads a transaction record. In investment accounts,
+// the N field is an action instead of a number.
+func readEntry(fields [][2]string, invst bool, opts Options) (e Entry, err error) {
+	for _, fl := range fields {
+		code, val := fl[0], fl[1]
+		switch code {
+		case "D":
+			e.Date, err = parseDate(val, opts.DayFirst)
+		case "T", "U":
+			e.Amount, err = parseAmount(val)
+		case "N":
+			if invst {
+				e.Action = val
+			} else {
+				e.Number = val
+			}
+		case "P":
+			e.Payee = val
+		case "M":
+			e.Memo = val
+		case "C":
+			e.Cleared = val
+		case "L":
+			e.Category = val
+		case "S":
+			e.Splits = append(e.Splits, Split{Category: val})
+		case "E":
+			if n := len(e.Splits); n > 0 {
+				e.Splits[n-1].Memo = val
+			}
+		case "$":
+			if n := len(e.Splits); n > 0 {
+				e.Splits[n-1].Amount, err = parseAmount(val)
+			}
+		case "Y":
+			e.Security = val
+		case "I":
+			e.Price, err = parseAmount(val)
+		case "Q":
+			e.Quantity, err = parseAmount(val)
+		}
+		if err != nil {
+			return e, err
+		}
+	}
+	if e.Date.IsZero() {
+		return e, fmt.Errorf("transaction without date")
+	}
+	if e.Amount == nil {
+		e.Amount = new(types.Amount)
+	}
+	return e, nil
+}
+
+// parseDate parses QIF dates such as 03/05/2013, 3/5/13, 3/ 5'13
+// or 2013-03-05.
+func parseDate(s string, dayFirst bool) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '/' || r == '\'' || r == '-' || r == '.' || r == ' '
+	})
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", s)
+		}
+		nums[i] = n
+	}
+	m, d, y := nums[0], nums[1], nums[2]
+	if dayFirst {
+		m, d = d, m
+	}
+	switch {
+	case y < 100 && strings.Contains(s, "'"):
+		y += 2000
+	case y < 70:
+		y += 2000
+	case y < 100:
+		y += 1900
+	}
+	if m < 1 || m > 12 || d < 1 || d > 31 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC), nil
+}
+
+// parseAmount parses amounts with optional thousands separators.
+func parseAmount(s string) (*types.Amount, error) {
+	s = strings.Replace(strings.TrimSpace(s), ",", "", -1)
+	x, ok := new(big.Rat).SetString(s)
+	if !ok || s == "" {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return (*types.Amount)(x), nil
+}
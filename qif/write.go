@@ -0,0 +1,156 @@
+package qif
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// qifTypes maps Gnucash account types to QIF account types.
+var qifTypes = map[string]string{
+	"BANK":       "Bank",
+	"CASH":       "Cash",
+	"CREDIT":     "CCard",
+	"ASSET":      "Oth A",
+	"RECEIVABLE": "Oth A",
+	"LIABILITY":  "Oth L",
+	"PAYABLE":    "Oth L",
+}
+
+// Write writes the categories, the account list and the registers
+// of all bank, cash, credit card, asset and liability accounts of book.
+// The book must have been recomputed.
+func Write(w io.Writer, book *types.Book) error {
+	bw := bufio.NewWriter(w)
+	var accts, cats []*types.Account
+	for _, acct := range book.Accounts {
+		switch {
+		case qifTypes[acct.Type] != "":
+			accts = append(accts, acct)
+		case acct.Type == "INCOME" || acct.Type == "EXPENSE":
+			cats = append(cats, acct)
+		}
+	}
+	sort.Sort(byName(accts))
+	sort.Sort(byName(cats))
+
+	fmt.Fprintln(bw, "!Option:AutoSwitch")
+	fmt.Fprintln(bw, "!Account")
+	for _, acct := range accts {
+		writeAccountHeader(bw, acct)
+	}
+	fmt.Fprintln(bw, "!Clear:AutoSwitch")
+	fmt.Fprintln(bw, "!Type:Cat")
+	for _, cat := range cats {
+		fmt.Fprintf(bw, "N%s\n", qifName(cat))
+		if cat.Description != "" {
+			fmt.Fprintf(bw, "D%s\n", cat.Description)
+		}
+		if cat.Type == "INCOME" {
+			fmt.Fprintln(bw, "I")
+		} else {
+			fmt.Fprintln(bw, "E")
+		}
+		fmt.Fprintln(bw, "^")
+	}
+	for _, acct := range accts {
+		writeRegister(bw, book, acct)
+	}
+	return bw.Flush()
+}
+
+// WriteAccount writes the register of a single account.
+func WriteAccount(w io.Writer, book *types.Book, acct *types.Account) error {
+	bw := bufio.NewWriter(w)
+	writeRegister(bw, book, acct)
+	return bw.Flush()
+}
+
+func writeAccountHeader(w io.Writer, acct *types.Account) {
+	typ := qifTypes[acct.Type]
+	if typ == "" {
+		typ = "Bank"
+	}
+	fmt.Fprintf(w, "N%s\nT%s\n", qifName(acct), typ)
+	if acct.Description != "" {
+		fmt.Fprintf(w, "D%s\n", acct.Description)
+	}
+	fmt.Fprintln(w, "^")
+}
+
+func writeRegister(w io.Writer, book *types.Book, acct *types.Account) {
+	typ := qifTypes[acct.Type]
+	if typ == "" {
+		typ = "Bank"
+	}
+	fmt.Fprintln(w, "!Account")
+	writeAccountHeader(w, acct)
+	fmt.Fprintf(w, "!Type:%s\n", typ)
+	for _, f := range book.Flows[acct] {
+		trn := f.Parent
+		fmt.Fprintf(w, "D%s\n", trn.Date.Format("01/02/2006"))
+		fmt.Fprintf(w, "T%s\n", f.Price)
+		if trn.Number != "" {
+			fmt.Fprintf(w, "N%s\n", trn.Number)
+		}
+		if trn.Description != "" {
+			fmt.Fprintf(w, "P%s\n", trn.Description)
+		}
+		if f.Memo != "" {
+			fmt.Fprintf(w, "M%s\n", f.Memo)
+		}
+		switch {
+		case f.State.IsReconciled():
+			fmt.Fprintln(w, "CX")
+		case f.State == types.Cleared:
+			fmt.Fprintln(w, "C*")
+		}
+		var others []*types.Flow
+		for i := range trn.Flows {
+			if o := &trn.Flows[i]; o != f {
+				others = append(others, o)
+			}
+		}
+		if len(others) == 1 {
+			fmt.Fprintf(w, "L%s\n", category(others[0].Account))
+		} else {
+			for _, o := range others {
+				fmt.Fprintf(w, "S%s\n", category(o.Account))
+				if o.Memo != "" {
+					fmt.Fprintf(w, "E%s\n", o.Memo)
+				}
+				fmt.Fprintf(w, "$%s\n", neg(o.Price))
+			}
+		}
+		fmt.Fprintln(w, "^")
+	}
+}
+
+// qifName returns the QIF name of an account: its path below the
+// top-level group, with colons as separators.
+func qifName(acct *types.Account) string {
+	name := strings.TrimPrefix(acct.Name, "/")
+	if i := strings.IndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Replace(name, "/", ":", -1)
+}
+
+// category returns the category of an account, or its name
+// in brackets for transfers.
+func category(acct *types.Account) string {
+	if acct.Type == "INCOME" || acct.Type == "EXPENSE" {
+		return qifName(acct)
+	}
+	return "[" + qifName(acct) + "]"
+}
+
+type byName []*types.Account
+
+func (s byName) Len() int           { return len(s) }
+func (s byName) Less(i, j int) bool { return s[i].Name < s[j].Name }
+func (s byName) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/remyoudompheng/gocash/query"
+)
+
+func cmdQuery(args []string) {
+	flags := flag.NewFlagSet("query", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	format := flags.String("format", "text", "output format: text or csv")
+	output := flags.String("o", "", "output file (default: standard output)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gocash query [flags] 'SELECT ...'")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *format != "text" && *format != "csv" {
+		log.Fatalf("ERROR: unknown format %q", *format)
+	}
+	q, err := query.Parse(strings.Join(flags.Args(), " "))
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+
+	book := loadBook(*filename)
+	res, err := q.Run(book)
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+	out := createOutput(*output)
+	if *format == "csv" {
+		err = res.WriteCSV(out)
+	} else {
+		err = res.WriteText(out)
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+}
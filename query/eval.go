@@ -0,0 +1,490 @@
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A row is a flow with the balance of its account after it.
+type row struct {
+	flow    *types.Flow
+	balance *big.Rat
+}
+
+// A group is a list of rows, over which aggregate functions are
+// computed. Columns take their value in the first row.
+type group []*row
+
+type expr interface {
+	eval(g group) (Value, error)
+}
+
+type literal struct{ value Value }
+
+type column struct {
+	name string
+	pos  int // Offset in the query, for errors.
+}
+
+type unary struct {
+	op string
+	x  expr
+}
+
+type binary struct {
+	op   string
+	x, y expr
+	re   *regexp.Regexp // For ~ with a literal pattern.
+}
+
+type call struct {
+	name string
+	args []expr
+}
+
+// columns are the columns of flows.
+var columns = map[string]func(r *row) Value{
+	"date":        func(r *row) Value { return day(r.flow.Parent.Date) },
+	"year":        func(r *row) Value { return int64(r.flow.Parent.Date.Year()) },
+	"month":       func(r *row) Value { return int64(r.flow.Parent.Date.Month()) },
+	"account":     func(r *row) Value { return r.flow.Account.Name },
+	"type":        func(r *row) Value { return r.flow.Account.Type },
+	"commodity":   func(r *row) Value { return r.flow.Account.Unit },
+	"description": func(r *row) Value { return r.flow.Parent.Description },
+	"memo":        func(r *row) Value { return r.flow.Memo },
+	"notes":       func(r *row) Value { return r.flow.Parent.Notes },
+	"number":      func(r *row) Value { return r.flow.Parent.Number },
+	"currency":    func(r *row) Value { return r.flow.Parent.Currency },
+	"amount":      func(r *row) Value { return new(big.Rat).Set(r.flow.Units().Rat()) },
+	"value":       func(r *row) Value { return new(big.Rat).Set(r.flow.Price.Rat()) },
+	"balance":     func(r *row) Value { return new(big.Rat).Set(r.balance) },
+	"state":       func(r *row) Value { return r.flow.State.String() },
+}
+
+// day returns the calendar date of t, in its own time zone, as
+// midnight UTC, so that Gnucash dates compare with date literals.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// ColumnNames lists the columns of flows, for documentation.
+var ColumnNames = []string{
+	"date", "year", "month", "account", "type", "commodity", "description", "memo",
+	"notes", "number", "currency", "amount", "value", "balance", "state",
+}
+
+// functions are the scalar functions. They return nil if an argument
+// is nil.
+var functions = map[string]func(args []Value) (Value, error){
+	"year":  dateFunc(func(d time.Time) int { return d.Year() }),
+	"month": dateFunc(func(d time.Time) int { return int(d.Month()) }),
+	"day":   dateFunc(func(d time.Time) int { return d.Day() }),
+	"abs": func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("abs expects 1 argument")
+		}
+		x, err := toRat(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Rat).Abs(x), nil
+	},
+	// root(account, n) is the ancestor of account at depth n.
+	"root": func(args []Value) (Value, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("root expects 2 arguments")
+		}
+		name, ok := args[0].(string)
+		n, ok2 := args[1].(int64)
+		if !ok || !ok2 {
+			return nil, fmt.Errorf("root expects an account name and a depth")
+		}
+		return (&types.Account{Name: name}).Ancestor(int(n)), nil
+	},
+}
+
+func dateFunc(f func(d time.Time) int) func(args []Value) (Value, error) {
+	return func(args []Value) (Value, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("expected 1 argument")
+		}
+		d, ok := args[0].(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("expected a date, got %s", describe(args[0]))
+		}
+		return int64(f(d)), nil
+	}
+}
+
+// aggregates are the aggregate functions, computed over the non-nil
+// values of their argument.
+var aggregates = map[string]func(values []Value) (Value, error){
+	"count": func(values []Value) (Value, error) { return int64(len(values)), nil },
+	"sum": func(values []Value) (Value, error) {
+		var sum Value = int64(0)
+		for _, v := range values {
+			var err error
+			if sum, err = arith("+", sum, v); err != nil {
+				return nil, err
+			}
+		}
+		return sum, nil
+	},
+	"avg": func(values []Value) (Value, error) {
+		if len(values) == 0 {
+			return nil, nil
+		}
+		sum := new(big.Rat)
+		for _, v := range values {
+			x, err := toRat(v)
+			if err != nil {
+				return nil, err
+			}
+			sum.Add(sum, x)
+		}
+		return sum.Quo(sum, big.NewRat(int64(len(values)), 1)), nil
+	},
+	"min": func(values []Value) (Value, error) { return extremum(values, -1) },
+	"max": func(values []Value) (Value, error) { return extremum(values, 1) },
+}
+
+func extremum(values []Value, sign int) (Value, error) {
+	var best Value
+	for _, v := range values {
+		if best == nil {
+			best = v
+			continue
+		}
+		c, err := compare(v, best)
+		if err != nil {
+			return nil, err
+		}
+		if c*sign > 0 {
+			best = v
+		}
+	}
+	return best, nil
+}
+
+func (e *literal) eval(g group) (Value, error) { return e.value, nil }
+
+func (e *column) eval(g group) (Value, error) {
+	if len(g) == 0 {
+		return nil, nil
+	}
+	return columns[e.name](g[0]), nil
+}
+
+func (e *unary) eval(g group) (Value, error) {
+	x, err := e.x.eval(g)
+	if err != nil || x == nil {
+		return nil, err
+	}
+	switch e.op {
+	case "NOT":
+		b, ok := x.(bool)
+		if !ok {
+			return nil, fmt.Errorf("NOT expects a boolean, got %s", describe(x))
+		}
+		return !b, nil
+	default:
+		return arith("-", int64(0), x)
+	}
+}
+
+func (e *binary) eval(g group) (Value, error) {
+	x, err := e.x.eval(g)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "AND", "OR":
+		// Nil is false.
+		b, ok := x.(bool)
+		if x != nil && !ok {
+			return nil, fmt.Errorf("%s expects booleans, got %s", e.op, describe(x))
+		}
+		if b == (e.op == "OR") {
+			return b, nil
+		}
+		y, err := e.y.eval(g)
+		if err != nil {
+			return nil, err
+		}
+		b, ok = y.(bool)
+		if y != nil && !ok {
+			return nil, fmt.Errorf("%s expects booleans, got %s", e.op, describe(y))
+		}
+		return b, nil
+	}
+	y, err := e.y.eval(g)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "+", "-", "*", "/":
+		if x == nil || y == nil {
+			return nil, nil
+		}
+		return arith(e.op, x, y)
+	case "~":
+		s, ok := x.(string)
+		if x == nil {
+			return false, nil
+		} else if !ok {
+			return nil, fmt.Errorf("~ expects a string, got %s", describe(x))
+		}
+		re := e.re
+		if re == nil {
+			pat, ok := y.(string)
+			if !ok {
+				return nil, fmt.Errorf("~ expects a regular expression, got %s", describe(y))
+			}
+			if re, err = regexp.Compile("(?i)" + pat); err != nil {
+				return nil, err
+			}
+		}
+		return re.MatchString(s), nil
+	}
+	// Comparisons with nil are false.
+	if x == nil || y == nil {
+		return false, nil
+	}
+	if _, ok := x.(bool); ok && e.op != "=" && e.op != "!=" {
+		return nil, fmt.Errorf("cannot compare booleans with %s", e.op)
+	}
+	c, err := compare(x, y)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "=":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func (e *call) eval(g group) (Value, error) {
+	if agg := aggregates[e.name]; agg != nil {
+		if len(e.args) != 1 {
+			return nil, fmt.Errorf("%s expects 1 argument", e.name)
+		}
+		var values []Value
+		for _, r := range g {
+			v, err := e.args[0].eval(group{r})
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				values = append(values, v)
+			}
+		}
+		v, err := agg(values)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", e.name, err)
+		}
+		return v, nil
+	}
+	args := make([]Value, len(e.args))
+	for i, a := range e.args {
+		v, err := a.eval(g)
+		if err != nil || v == nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := functions[e.name](args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", e.name, err)
+	}
+	return v, nil
+}
+
+// walk calls f on e and its subexpressions.
+func walk(e expr, f func(expr)) {
+	if e == nil {
+		return
+	}
+	f(e)
+	switch e := e.(type) {
+	case *unary:
+		walk(e.x, f)
+	case *binary:
+		walk(e.x, f)
+		walk(e.y, f)
+	case *call:
+		for _, a := range e.args {
+			walk(a, f)
+		}
+	}
+}
+
+func hasAggregate(e expr) bool {
+	found := false
+	walk(e, func(e expr) {
+		if c, ok := e.(*call); ok && aggregates[c.name] != nil {
+			found = true
+		}
+	})
+	return found
+}
+
+// describe names the type of a value in error messages.
+func describe(v Value) string {
+	switch v.(type) {
+	case string:
+		return "a string"
+	case int64, *big.Rat:
+		return "a number"
+	case time.Time:
+		return "a date"
+	case bool:
+		return "a boolean"
+	}
+	return "null"
+}
+
+func toRat(v Value) (*big.Rat, error) {
+	switch v := v.(type) {
+	case int64:
+		return big.NewRat(v, 1), nil
+	case *big.Rat:
+		return v, nil
+	}
+	return nil, fmt.Errorf("expected a number, got %s", describe(v))
+}
+
+// arith applies an arithmetic operator to numbers. Integers stay
+// integers, except for division.
+func arith(op string, x, y Value) (Value, error) {
+	if a, ok := x.(int64); ok && op != "/" {
+		if b, ok := y.(int64); ok {
+			switch op {
+			case "+":
+				return a + b, nil
+			case "-":
+				return a - b, nil
+			default:
+				return a * b, nil
+			}
+		}
+	}
+	a, err := toRat(x)
+	if err != nil {
+		return nil, err
+	}
+	b, err := toRat(y)
+	if err != nil {
+		return nil, err
+	}
+	z := new(big.Rat)
+	switch op {
+	case "+":
+		return z.Add(a, b), nil
+	case "-":
+		return z.Sub(a, b), nil
+	case "*":
+		return z.Mul(a, b), nil
+	}
+	if b.Sign() == 0 {
+		return nil, fmt.Errorf("division by zero")
+	}
+	return z.Quo(a, b), nil
+}
+
+// compare compares two non-nil values of the same type. Numbers
+// compare with numbers, and dates with strings holding dates.
+func compare(x, y Value) (int, error) {
+	switch a := x.(type) {
+	case string:
+		if b, ok := y.(time.Time); ok {
+			d, err := time.Parse("2006-01-02", a)
+			if err != nil {
+				return 0, fmt.Errorf("cannot compare a date with %q", a)
+			}
+			return -compareDates(b, d), nil
+		}
+		if b, ok := y.(string); ok {
+			return strings.Compare(a, b), nil
+		}
+	case int64, *big.Rat:
+		if b, err := toRat(y); err == nil {
+			a, _ := toRat(a)
+			return a.Cmp(b), nil
+		}
+	case time.Time:
+		switch b := y.(type) {
+		case time.Time:
+			return compareDates(a, b), nil
+		case string:
+			c, err := compare(b, a)
+			return -c, err
+		}
+	case bool:
+		if b, ok := y.(bool); ok {
+			if a == b {
+				return 0, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", describe(x), describe(y))
+}
+
+func compareDates(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+	return 0
+}
+
+// compareValues orders values of any type for ORDER BY: nil first,
+// then booleans, numbers, dates and strings.
+func compareValues(x, y Value) int {
+	rank := func(v Value) int {
+		switch v.(type) {
+		case bool:
+			return 1
+		case int64, *big.Rat:
+			return 2
+		case time.Time:
+			return 3
+		case string:
+			return 4
+		}
+		return 0
+	}
+	if rx, ry := rank(x), rank(y); rx != ry || rx == 0 {
+		return rx - ry
+	}
+	if a, ok := x.(bool); ok {
+		b := y.(bool)
+		switch {
+		case a == b:
+			return 0
+		case b:
+			return -1
+		}
+		return 1
+	}
+	c, _ := compare(x, y)
+	return c
+}
@@ -0,0 +1,110 @@
+package query
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Format formats a value of a result. Numbers with a fractional part
+// have at least 2 decimals, and at most 6.
+func Format(v Value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case int64:
+		return fmt.Sprint(v)
+	case *big.Rat:
+		return (*types.Amount)(v).DecimalRound(6)
+	case time.Time:
+		return v.Format("2006-01-02")
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return fmt.Sprint(v)
+}
+
+// IsNumber reports whether v is a number, which is right-aligned
+// in tables.
+func IsNumber(v Value) bool {
+	switch v.(type) {
+	case int64, *big.Rat:
+		return true
+	}
+	return false
+}
+
+// WriteText writes the result as a table aligned with spaces.
+func (res *Result) WriteText(w io.Writer) error {
+	widths := make([]int, len(res.Columns))
+	right := make([]bool, len(res.Columns))
+	cells := make([][]string, len(res.Rows))
+	for j, c := range res.Columns {
+		widths[j] = utf8.RuneCountInString(c)
+	}
+	for i, line := range res.Rows {
+		for j, v := range line {
+			s := Format(v)
+			cells[i] = append(cells[i], s)
+			if v != nil {
+				right[j] = IsNumber(v)
+			}
+			if n := utf8.RuneCountInString(s); n > widths[j] {
+				widths[j] = n
+			}
+		}
+	}
+	pad := func(s string, j int, right bool) string {
+		fill := strings.Repeat(" ", widths[j]-utf8.RuneCountInString(s))
+		if right {
+			return fill + s
+		}
+		return s + fill
+	}
+	var buf strings.Builder
+	writeLine := func(cells []string) {
+		buf.WriteString(strings.TrimRight(strings.Join(cells, "  "), " ") + "\n")
+	}
+	header := make([]string, len(widths))
+	rules := make([]string, len(widths))
+	for j, c := range res.Columns {
+		header[j] = pad(c, j, right[j])
+		rules[j] = strings.Repeat("-", widths[j])
+	}
+	writeLine(header)
+	writeLine(rules)
+	for i, line := range res.Rows {
+		for j, v := range line {
+			cells[i][j] = pad(cells[i][j], j, IsNumber(v))
+		}
+		writeLine(cells[i])
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// WriteCSV writes the result as CSV with a header row.
+func (res *Result) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(res.Columns)
+	for _, line := range res.Rows {
+		cells := make([]string, len(line))
+		for j, v := range line {
+			cells[j] = Format(v)
+		}
+		cw.Write(cells)
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,392 @@
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// A token is a lexical item of a query.
+type token struct {
+	kind  tokenKind
+	text  string // Upper case for keywords.
+	value Value  // For literals.
+	pos   int    // Byte offsets of the token in the query.
+	end   int
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokKeyword
+	tokLiteral
+	tokOp // Operators and punctuation.
+)
+
+var keywords = map[string]bool{
+	"SELECT": true, "WHERE": true, "GROUP": true, "ORDER": true, "BY": true,
+	"ASC": true, "DESC": true, "LIMIT": true, "AS": true,
+	"AND": true, "OR": true, "NOT": true, "TRUE": true, "FALSE": true,
+}
+
+var datePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}`)
+
+// lex splits a query into tokens, ending with an EOF token.
+func lex(s string) ([]token, error) {
+	var toks []token
+	for i := 0; ; {
+		for i < len(s) && unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		if i == len(s) {
+			return append(toks, token{kind: tokEOF, pos: i, end: i}), nil
+		}
+		tok := token{pos: i}
+		c := s[i]
+		switch {
+		case c == '_' || unicode.IsLetter(rune(c)):
+			j := i
+			for j < len(s) && (s[j] == '_' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tok.kind, tok.text, i = tokIdent, strings.ToLower(s[i:j]), j
+			if up := strings.ToUpper(tok.text); keywords[up] {
+				tok.kind, tok.text = tokKeyword, up
+			}
+		case datePattern.MatchString(s[i:]):
+			d, err := time.Parse("2006-01-02", s[i:i+10])
+			if err != nil {
+				return nil, fmt.Errorf("invalid date at offset %d: %s", i, err)
+			}
+			tok.kind, tok.text, tok.value, i = tokLiteral, s[i:i+10], d, i+10
+		case c >= '0' && c <= '9' || c == '.':
+			j := i
+			for j < len(s) && (s[j] >= '0' && s[j] <= '9' || s[j] == '.') {
+				j++
+			}
+			tok.kind, tok.text = tokLiteral, s[i:j]
+			if n, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+				tok.value = n
+			} else if x, ok := new(big.Rat).SetString(tok.text); ok {
+				tok.value = x
+			} else {
+				return nil, fmt.Errorf("invalid number %q at offset %d", tok.text, i)
+			}
+			i = j
+		case c == '\'' || c == '"':
+			j := strings.IndexByte(s[i+1:], c)
+			if j < 0 {
+				return nil, fmt.Errorf("unterminated string at offset %d", i)
+			}
+			tok.kind, tok.text, tok.value, i = tokLiteral, s[i:i+j+2], s[i+1:i+j+1], i+j+2
+		default:
+			tok.kind = tokOp
+			for _, op := range []string{"<=", ">=", "!=", "<>", "=", "<", ">", "~", "+", "-", "*", "/", "(", ")", ","} {
+				if strings.HasPrefix(s[i:], op) {
+					tok.text = op
+					break
+				}
+			}
+			if tok.text == "" {
+				return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+			}
+			if tok.text == "<>" {
+				tok.text = "!="
+			}
+			i += len(tok.text)
+		}
+		tok.end = i
+		toks = append(toks, tok)
+	}
+}
+
+// A parser builds a query from tokens.
+type parser struct {
+	src  string
+	toks []token
+}
+
+func (p *parser) peek() token { return p.toks[0] }
+
+func (p *parser) next() token {
+	tok := p.toks[0]
+	if tok.kind != tokEOF {
+		p.toks = p.toks[1:]
+	}
+	return tok
+}
+
+// accept consumes the next token if it is the given keyword
+// or operator.
+func (p *parser) accept(text string) bool {
+	if tok := p.peek(); (tok.kind == tokKeyword || tok.kind == tokOp) && tok.text == text {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+func (p *parser) unexpected() error {
+	tok := p.peek()
+	if tok.kind == tokEOF {
+		return fmt.Errorf("unexpected end of query")
+	}
+	return fmt.Errorf("unexpected %q at offset %d", p.src[tok.pos:tok.end], tok.pos)
+}
+
+// Parse parses a query.
+func Parse(s string) (*Query, error) {
+	toks, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: s, toks: toks}
+	q, err := p.query()
+	if err != nil {
+		return nil, err
+	}
+	if err := q.check(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (p *parser) query() (*Query, error) {
+	q := &Query{Limit: -1}
+	if err := p.expect("SELECT"); err != nil {
+		return nil, err
+	}
+	for {
+		if p.accept("*") {
+			for _, name := range starColumns {
+				q.Columns = append(q.Columns, Column{Name: name, expr: &column{name: name}})
+			}
+		} else {
+			start := p.peek().pos
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			c := Column{Name: strings.TrimSpace(p.src[start:p.peek().pos]), expr: e}
+			if p.accept("AS") {
+				tok := p.next()
+				if tok.kind != tokIdent {
+					return nil, fmt.Errorf("expected a column name after AS at offset %d", tok.pos)
+				}
+				c.Name, c.alias = tok.text, true
+			}
+			q.Columns = append(q.Columns, c)
+		}
+		if !p.accept(",") {
+			break
+		}
+	}
+	var err error
+	if p.accept("WHERE") {
+		if q.where, err = p.expr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.accept("GROUP") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if e, err = q.resolve(e, "GROUP BY"); err != nil {
+				return nil, err
+			}
+			q.groupBy = append(q.groupBy, e)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.accept("ORDER") {
+		if err := p.expect("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			e, err := p.expr()
+			if err != nil {
+				return nil, err
+			}
+			if e, err = q.resolve(e, "ORDER BY"); err != nil {
+				return nil, err
+			}
+			o := order{expr: e}
+			if p.accept("DESC") {
+				o.desc = true
+			} else {
+				p.accept("ASC")
+			}
+			q.orderBy = append(q.orderBy, o)
+			if !p.accept(",") {
+				break
+			}
+		}
+	}
+	if p.accept("LIMIT") {
+		tok := p.next()
+		n, ok := tok.value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("expected a number after LIMIT at offset %d", tok.pos)
+		}
+		q.Limit = int(n)
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return q, nil
+}
+
+// resolve replaces the position or the alias of an output column
+// in GROUP BY and ORDER BY clauses by its expression.
+func (q *Query) resolve(e expr, clause string) (expr, error) {
+	switch e := e.(type) {
+	case *literal:
+		if n, ok := e.value.(int64); ok {
+			if n < 1 || int(n) > len(q.Columns) {
+				return nil, fmt.Errorf("%s position %d is not between 1 and %d", clause, n, len(q.Columns))
+			}
+			return q.Columns[n-1].expr, nil
+		}
+	case *column:
+		for _, c := range q.Columns {
+			if c.alias && c.Name == e.name {
+				return c.expr, nil
+			}
+		}
+	}
+	return e, nil
+}
+
+// Expressions are parsed by precedence climbing, from OR to
+// unary operators.
+func (p *parser) expr() (expr, error) { return p.binary(0) }
+
+var precedence = [][]string{
+	{"OR"},
+	{"AND"},
+	{"=", "!=", "<", "<=", ">", ">=", "~"},
+	{"+", "-"},
+	{"*", "/"},
+}
+
+func (p *parser) binary(level int) (expr, error) {
+	if level == len(precedence) {
+		return p.unary()
+	}
+	if level == 2 && p.accept("NOT") {
+		x, err := p.binary(level)
+		if err != nil {
+			return nil, err
+		}
+		return &unary{"NOT", x}, nil
+	}
+	x, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		op := ""
+		for _, o := range precedence[level] {
+			if (tok.kind == tokKeyword || tok.kind == tokOp) && tok.text == o {
+				op = o
+			}
+		}
+		if op == "" {
+			return x, nil
+		}
+		p.next()
+		y, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		b := &binary{op: op, x: x, y: y}
+		if op == "~" {
+			if lit, ok := y.(*literal); ok {
+				s, ok := lit.value.(string)
+				if !ok {
+					return nil, fmt.Errorf("expected a regular expression after ~ at offset %d", tok.pos)
+				}
+				if b.re, err = regexp.Compile("(?i)" + s); err != nil {
+					return nil, err
+				}
+			}
+		}
+		x = b
+	}
+}
+
+func (p *parser) unary() (expr, error) {
+	if p.accept("-") {
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unary{"-", x}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (expr, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokLiteral:
+		return &literal{tok.value}, nil
+	case tok.kind == tokKeyword && (tok.text == "TRUE" || tok.text == "FALSE"):
+		return &literal{tok.text == "TRUE"}, nil
+	case tok.kind == tokOp && tok.text == "(":
+		x, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		return x, p.expect(")")
+	case tok.kind == tokIdent && p.accept("("):
+		c := &call{name: tok.text}
+		if functions[c.name] == nil && aggregates[c.name] == nil {
+			return nil, fmt.Errorf("unknown function %s at offset %d", c.name, tok.pos)
+		}
+		if p.accept(")") {
+			return c, nil
+		}
+		for {
+			if c.name == "count" && p.accept("*") {
+				c.args = append(c.args, &literal{int64(1)})
+			} else {
+				x, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+				c.args = append(c.args, x)
+			}
+			if !p.accept(",") {
+				break
+			}
+		}
+		return c, p.expect(")")
+	case tok.kind == tokIdent:
+		// Columns are checked after aliases are resolved.
+		return &column{name: tok.text, pos: tok.pos}, nil
+	}
+	p.toks = append([]token{tok}, p.toks...)
+	return nil, p.unexpected()
+}
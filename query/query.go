@@ -0,0 +1,286 @@
+// Package query implements a SQL-like query language over the flows
+// of a book, in the spirit of Beancount's BQL. For example:
+//
+//	SELECT root(account, 2) AS category, sum(amount)
+//	WHERE type = 'EXPENSE' AND date >= 2013-01-01
+//	GROUP BY category ORDER BY 2 DESC LIMIT 10
+//
+// A query has a list of output expressions, or * for the usual
+// register columns, and optional WHERE, GROUP BY, ORDER BY and LIMIT
+// clauses. GROUP BY and ORDER BY accept column aliases and positions.
+// If the output has aggregate functions and there is no GROUP BY
+// clause, flows are grouped by the other output expressions.
+//
+// Expressions are made of columns, literal numbers, 'strings' and
+// dates (YYYY-MM-DD), the operators OR, AND, NOT, =, != (or <>), <,
+// <=, >, >=, ~ (case-insensitive regular expression match), +, -, *
+// and /, and function calls. Dates may be compared to strings.
+package query
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Value is the value of an expression: nil, a string, an int64,
+// a *big.Rat, a time.Time or a bool.
+type Value interface{}
+
+// A Query is a parsed query.
+type Query struct {
+	Columns []Column
+	Limit   int // Negative if there is no limit.
+
+	where   expr
+	groupBy []expr
+	orderBy []order
+}
+
+// A Column is an output expression of a query.
+type Column struct {
+	Name  string // The alias or the text of the expression.
+	alias bool
+	expr  expr
+}
+
+type order struct {
+	expr expr
+	desc bool
+}
+
+// starColumns are the output columns of SELECT *.
+var starColumns = []string{"date", "number", "description", "account", "memo", "amount", "balance", "state"}
+
+// check reports unknown columns and misplaced aggregate functions.
+func (q *Query) check() error {
+	var err error
+	unknown := func(e expr) {
+		if c, ok := e.(*column); ok && columns[c.name] == nil && err == nil {
+			err = fmt.Errorf("unknown column %s at offset %d", c.name, c.pos)
+		}
+	}
+	walk(q.where, unknown)
+	for _, c := range q.Columns {
+		walk(c.expr, unknown)
+	}
+	for _, e := range q.groupBy {
+		walk(e, unknown)
+	}
+	for _, o := range q.orderBy {
+		walk(o.expr, unknown)
+	}
+	if err != nil {
+		return err
+	}
+	if hasAggregate(q.where) {
+		return fmt.Errorf("aggregate functions are not allowed in WHERE")
+	}
+	for _, e := range q.groupBy {
+		if hasAggregate(e) {
+			return fmt.Errorf("aggregate functions are not allowed in GROUP BY")
+		}
+	}
+	var exprs []expr
+	for _, c := range q.Columns {
+		exprs = append(exprs, c.expr)
+	}
+	for _, o := range q.orderBy {
+		exprs = append(exprs, o.expr)
+	}
+	for _, e := range exprs {
+		walk(e, func(e expr) {
+			if c, ok := e.(*call); ok && aggregates[c.name] != nil {
+				for _, a := range c.args {
+					if hasAggregate(a) {
+						err = fmt.Errorf("nested aggregate function in %s", c.name)
+					}
+				}
+			}
+		})
+	}
+	return err
+}
+
+// grouped reports whether the query aggregates flows.
+func (q *Query) grouped() bool {
+	if len(q.groupBy) > 0 {
+		return true
+	}
+	for _, c := range q.Columns {
+		if hasAggregate(c.expr) {
+			return true
+		}
+	}
+	for _, o := range q.orderBy {
+		if hasAggregate(o.expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// A Result is the table computed by a query.
+type Result struct {
+	Columns []string
+	Rows    [][]Value
+}
+
+// Run runs the query on the flows of book, which must have been
+// recomputed.
+func (q *Query) Run(book *types.Book) (*Result, error) {
+	var rows []*row
+	for _, flows := range book.Flows {
+		bal := new(big.Rat)
+		for _, f := range flows {
+			bal.Add(bal, f.Units().Rat())
+			r := &row{flow: f, balance: new(big.Rat).Set(bal)}
+			if q.where != nil {
+				v, err := q.where.eval(group{r})
+				if err != nil {
+					return nil, err
+				}
+				ok, isBool := v.(bool)
+				if v != nil && !isBool {
+					return nil, fmt.Errorf("expected a boolean in WHERE, got %s", describe(v))
+				}
+				if !ok {
+					continue
+				}
+			}
+			rows = append(rows, r)
+		}
+	}
+	sort.Sort(byDate(rows))
+
+	groups, err := q.group(rows)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	for _, c := range q.Columns {
+		res.Columns = append(res.Columns, c.Name)
+	}
+	keys := make([][]Value, len(groups))
+	for i, g := range groups {
+		line := make([]Value, len(q.Columns))
+		for j, c := range q.Columns {
+			if line[j], err = c.expr.eval(g); err != nil {
+				return nil, err
+			}
+		}
+		res.Rows = append(res.Rows, line)
+		for _, o := range q.orderBy {
+			v, err := o.expr.eval(g)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = append(keys[i], v)
+		}
+	}
+	if len(q.orderBy) > 0 {
+		sort.Stable(&byKeys{q.orderBy, keys, res.Rows})
+	}
+	if q.Limit >= 0 && len(res.Rows) > q.Limit {
+		res.Rows = res.Rows[:q.Limit]
+	}
+	return res, nil
+}
+
+// group splits rows into groups according to the query.
+func (q *Query) group(rows []*row) ([]group, error) {
+	if !q.grouped() {
+		groups := make([]group, len(rows))
+		for i, r := range rows {
+			groups[i] = group{r}
+		}
+		return groups, nil
+	}
+	by := q.groupBy
+	if len(by) == 0 {
+		for _, c := range q.Columns {
+			if !hasAggregate(c.expr) {
+				by = append(by, c.expr)
+			}
+		}
+	}
+	if len(by) == 0 {
+		return []group{group(rows)}, nil
+	}
+	var groups []group
+	index := make(map[string]int)
+	for _, r := range rows {
+		key := ""
+		for _, e := range by {
+			v, err := e.eval(group{r})
+			if err != nil {
+				return nil, err
+			}
+			key += keyString(v) + "\x00"
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], r)
+	}
+	return groups, nil
+}
+
+// keyString returns a string identifying a value in GROUP BY keys.
+func keyString(v Value) string {
+	switch x := v.(type) {
+	case int64, *big.Rat:
+		r, _ := toRat(x)
+		return "n" + r.RatString()
+	case time.Time:
+		return "d" + x.Format("2006-01-02")
+	}
+	return fmt.Sprintf("%T:%v", v, v)
+}
+
+type byDate []*row
+
+func (s byDate) Len() int      { return len(s) }
+func (s byDate) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDate) Less(i, j int) bool {
+	a, b := s[i].flow, s[j].flow
+	if !a.Parent.Date.Equal(b.Parent.Date) {
+		return a.Parent.Date.Before(b.Parent.Date)
+	}
+	if a.Parent.Id != b.Parent.Id {
+		return a.Parent.Id < b.Parent.Id
+	}
+	return a.Account.Name < b.Account.Name
+}
+
+// byKeys sorts result rows by the values of ORDER BY expressions.
+// Nil values come first.
+type byKeys struct {
+	order []order
+	keys  [][]Value
+	rows  [][]Value
+}
+
+func (s *byKeys) Len() int { return len(s.rows) }
+func (s *byKeys) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.rows[i], s.rows[j] = s.rows[j], s.rows[i]
+}
+func (s *byKeys) Less(i, j int) bool {
+	for k, o := range s.order {
+		c := compareValues(s.keys[i][k], s.keys[j][k])
+		if o.desc {
+			c = -c
+		}
+		if c != 0 {
+			return c < 0
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package query
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/ledger"
+	"github.com/remyoudompheng/gocash/types"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func testBook(t *testing.T) *types.Book {
+	book, err := ledger.ReadFile("testdata/query.journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	return book
+}
+
+func TestQuery(t *testing.T) {
+	book := testBook(t)
+	for _, c := range []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT date, description, amount, balance WHERE account = '/Assets/Bank'",
+			"date,description,amount,balance|2013-11-28,Employer,2000.00,2000.00|2013-12-03,Grocer,-60.00,1940.00|" +
+				"2013-12-24,Bakery,-8.40,1931.60|2014-01-06,Grocer,-25.00,1906.60|2014-01-28,Employer,2000.00,3906.60"},
+		{"select root(account, 1) as top, sum(amount), count(*) group by top order by top",
+			"top,sum(amount),count(*)|/Assets,3906.60,5|/Expenses,93.40,4|/Income,-4000.00,2"},
+		{"SELECT year, month, sum(amount) AS total WHERE type = 'EXPENSE' GROUP BY 1, 2 ORDER BY total DESC",
+			"year,month,total|2013,12,68.40|2014,1,25.00"},
+		{"SELECT account, max(amount), min(amount), avg(amount) WHERE account ~ '^/assets' ",
+			"account,max(amount),min(amount),avg(amount)|/Assets/Bank,2000.00,-60.00,781.32"},
+		{"SELECT count(*), sum(abs(amount)) / 3 WHERE date >= '2013-12-01' AND NOT description ~ 'bak'",
+			"count(*),sum(abs(amount)) / 3|7,1390.00"},
+		{"SELECT description, account WHERE (amount < 0 OR number = '201') AND year(date) = 2013 ORDER BY amount LIMIT 3",
+			"description,account|Employer,/Income/Salary|Grocer,/Assets/Bank|Bakery,/Assets/Bank"},
+		{"SELECT * WHERE memo != '' AND state = 'n'",
+			"date,number,description,account,memo,amount,balance,state|" +
+				"2013-12-03,201,Grocer,/Assets/Bank,turkey,-60.00,1940.00,n"},
+		{"SELECT description, notes, count(*) WHERE notes ~ 'christ' GROUP BY 1, 2", "description,notes,count(*)|Grocer,christmas,3"},
+		{"SELECT sum(amount) WHERE date < 2013-01-01", "sum(amount)|0"},
+		{"SELECT 1 + 2 * 3 - -1, 7 / 2, date = 2013-12-24 WHERE description = 'Bakery' AND amount > 0",
+			"1 + 2 * 3 - -1,7 / 2,date = 2013-12-24|8,3.50,TRUE"},
+	} {
+		q, err := Parse(c.query)
+		if err != nil {
+			t.Errorf("%s: %s", c.query, err)
+			continue
+		}
+		res, err := q.Run(book)
+		if err != nil {
+			t.Errorf("%s: %s", c.query, err)
+			continue
+		}
+		var buf bytes.Buffer
+		res.WriteCSV(&buf)
+		s := strings.Replace(strings.TrimSpace(buf.String()), "\n", "|", -1)
+		if s != c.expected {
+			t.Errorf("%s:\ngot      %s\nexpected %s", c.query, s, c.expected)
+		}
+	}
+}
+
+func TestQueryGnucash(t *testing.T) {
+	// Gnucash dates are in the zone of their author, such as
+	// 1997-09-11 21:00:00 -0700, which is the next day in UTC.
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/abc.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	for _, c := range []struct {
+		query    string
+		expected string
+	}{
+		{"SELECT date, description WHERE date = 1997-09-11",
+			"date,description|1997-09-11,move a pile of money to trading acct|1997-09-11,move a pile of money to trading acct"},
+		{"SELECT count(*) WHERE date = '1997-09-12'", "count(*)|0"},
+		{"SELECT count(*) WHERE date >= 1997-11-11", "count(*)|2"},
+	} {
+		q, err := Parse(c.query)
+		if err != nil {
+			t.Errorf("%s: %s", c.query, err)
+			continue
+		}
+		res, err := q.Run(book)
+		if err != nil {
+			t.Errorf("%s: %s", c.query, err)
+			continue
+		}
+		var buf bytes.Buffer
+		res.WriteCSV(&buf)
+		s := strings.Replace(strings.TrimSpace(buf.String()), "\n", "|", -1)
+		if s != c.expected {
+			t.Errorf("%s:\ngot      %s\nexpected %s", c.query, s, c.expected)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	book := testBook(t)
+	for _, c := range []struct {
+		query string
+		err   string
+	}{
+		{"date", `unexpected "date" at offset 0`},
+		{"SELECT", "unexpected end of query"},
+		{"SELECT foo", "unknown column foo at offset 7"},
+		{"SELECT bar(1)", "unknown function bar at offset 7"},
+		{"SELECT date WHERE sum(amount) > 0", "aggregate functions are not allowed in WHERE"},
+		{"SELECT sum(count(*))", "nested aggregate function in sum"},
+		{"SELECT 'abc", "unterminated string at offset 7"},
+		{"SELECT date LIMIT x", "expected a number after LIMIT at offset 18"},
+		{"SELECT date ORDER BY", "unexpected end of query"},
+		{"SELECT date; DROP", `unexpected character ';' at offset 11`},
+		{"SELECT amount + 'x'", "expected a number, got a string"},
+		{"SELECT date WHERE amount = 'x'", "cannot compare a number with a string"},
+		{"SELECT date WHERE date > 'now'", `cannot compare a date with "now"`},
+		{"SELECT amount / 0", "division by zero"},
+		{"SELECT root(account)", "root: root expects 2 arguments"},
+		{"SELECT date WHERE amount", "expected a boolean in WHERE, got a number"},
+		{"SELECT * GROUP BY 9", "GROUP BY position 9 is not between 1 and 8"},
+		{"SELECT date, amount ORDER BY 2, 100", "ORDER BY position 100 is not between 1 and 2"},
+	} {
+		q, err := Parse(c.query)
+		if err == nil {
+			_, err = q.Run(book)
+		}
+		if err == nil || err.Error() != c.err {
+			t.Errorf("%s: got error %v, expected %s", c.query, err, c.err)
+		}
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	q, err := Parse("SELECT account, sum(amount) AS total, count(*) AS n GROUP BY account ORDER BY account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := q.Run(testBook(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := res.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expected := `account                  total  n
+--------------------  --------  -
+/Assets/Bank           3906.60  5
+/Expenses/Food           70.00  2
+/Expenses/Food/Bread      8.40  1
+/Expenses/Home           15.00  1
+/Income/Salary        -4000.00  2
+`
+	if s := buf.String(); s != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", s, expected)
+	}
+}
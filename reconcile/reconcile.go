@@ -0,0 +1,127 @@
+// Package reconcile implements reconciliation of accounts against
+// bank statements.
+//
+// Flows of an account go through three states, as in Gnucash: not
+// cleared, cleared (ticked while reconciling, or marked by the user)
+// and reconciled (confirmed by a statement). Frozen flows count as
+// reconciled, and voided flows are ignored. A reconciliation starts
+// from the balance of reconciled flows, and is finished when the
+// cleared flows account for the difference with the statement
+// ending balance.
+package reconcile
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Statement is the ending balance of an account at a date,
+// in the account commodity.
+type Statement struct {
+	Date    time.Time
+	Balance *big.Rat
+}
+
+// Opening returns the balance of the reconciled flows of acct.
+// The book must have been recomputed.
+func Opening(book *types.Book, acct *types.Account) *big.Rat {
+	bal := new(big.Rat)
+	for _, f := range book.Flows[acct] {
+		if f.State.IsReconciled() {
+			bal.Add(bal, f.Units().Rat())
+		}
+	}
+	return bal
+}
+
+// Candidates returns the flows of acct that are neither reconciled
+// nor voided, up to the end of the statement date.
+func Candidates(book *types.Book, acct *types.Account, date time.Time) []*types.Flow {
+	end := date.AddDate(0, 0, 1)
+	var flows []*types.Flow
+	for _, f := range book.Flows[acct] {
+		if isOpen(f) && f.Parent.Date.Before(end) {
+			flows = append(flows, f)
+		}
+	}
+	return flows
+}
+
+// Difference returns the statement balance minus the balance of
+// reconciled flows of acct and of the given cleared flows.
+func Difference(book *types.Book, acct *types.Account, st Statement, cleared []*types.Flow) *big.Rat {
+	diff := new(big.Rat).Sub(st.Balance, Opening(book, acct))
+	for _, f := range cleared {
+		if isOpen(f) {
+			diff.Sub(diff, f.Units().Rat())
+		}
+	}
+	return diff
+}
+
+// Lookup returns the flows of acct with the given identifiers.
+func Lookup(book *types.Book, acct *types.Account, ids []types.GUID) ([]*types.Flow, error) {
+	byId := make(map[types.GUID]*types.Flow)
+	for _, f := range book.Flows[acct] {
+		byId[f.Id] = f
+	}
+	flows := make([]*types.Flow, 0, len(ids))
+	for _, id := range ids {
+		f := byId[id]
+		if f == nil {
+			return nil, fmt.Errorf("no flow %s in account %s", id, acct.Name)
+		}
+		flows = append(flows, f)
+	}
+	return flows, nil
+}
+
+// Postpone marks the given flows as cleared, and the other
+// candidates of the statement as not cleared, so that an unfinished
+// reconciliation can be resumed.
+func Postpone(book *types.Book, acct *types.Account, st Statement, cleared []*types.Flow) {
+	for _, f := range Candidates(book, acct, st.Date) {
+		f.State = types.NotCleared
+	}
+	for _, f := range cleared {
+		if isOpen(f) {
+			f.State = types.Cleared
+		}
+	}
+}
+
+// isOpen reports whether f can be reconciled.
+func isOpen(f *types.Flow) bool {
+	return f.State == types.NotCleared || f.State == types.Cleared
+}
+
+// Finish marks the given flows as reconciled at the statement date
+// and records the statement date as the last reconciliation date of
+// acct. The cleared flows must account for the statement balance.
+func Finish(book *types.Book, acct *types.Account, st Statement, cleared []*types.Flow) error {
+	end := st.Date.AddDate(0, 0, 1)
+	for _, f := range cleared {
+		if f.Account != acct {
+			return fmt.Errorf("flow %s does not belong to account %s", f.Id, acct.Name)
+		}
+		if !f.Parent.Date.Before(end) {
+			return fmt.Errorf("flow %s of %s is after the statement date",
+				f.Id, f.Parent.Date.Format("2006-01-02"))
+		}
+	}
+	if diff := Difference(book, acct, st, cleared); diff.Sign() != 0 {
+		return fmt.Errorf("statement balance differs by %s %s", diff.FloatString(2), acct.Unit)
+	}
+	Postpone(book, acct, st, nil)
+	for _, f := range cleared {
+		if isOpen(f) {
+			f.State = types.Reconciled
+			f.ReconciledTime = st.Date
+		}
+	}
+	acct.LastReconcile = st.Date
+	return nil
+}
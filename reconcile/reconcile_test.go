@@ -0,0 +1,99 @@
+package reconcile
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func testBook() (*types.Book, *types.Account) {
+	bank := &types.Account{Id: "bank", Name: "/Bank", Type: "BANK", Unit: "EUR"}
+	other := &types.Account{Id: "other", Name: "/Expenses", Type: "EXPENSE", Unit: "EUR"}
+	book := &types.Book{
+		Accounts:     map[types.GUID]*types.Account{"bank": bank, "other": other},
+		Transactions: make(map[types.GUID]*types.Transaction),
+	}
+	for _, t := range []struct {
+		id, date, amount string
+		state            types.ReconcileState
+	}{
+		{"a", "2013-01-15", "1000", types.Reconciled},
+		{"b", "2013-02-03", "-20", types.NotCleared},
+		{"c", "2013-02-10", "-35.50", types.NotCleared},
+		{"d", "2013-02-27", "-4.50", types.NotCleared},
+		{"e", "2013-03-02", "-100", types.NotCleared},
+		{"f", "2013-02-20", "0", types.Voided},
+	} {
+		x, _ := new(big.Rat).SetString(t.amount)
+		book.Transactions[types.GUID(t.id)] = &types.Transaction{
+			Id: types.GUID(t.id), Date: date(t.date), Currency: "EUR",
+			Flows: []types.Flow{
+				{Id: types.GUID(t.id + "1"), Account: bank, Price: (*types.Amount)(x), State: t.state},
+				{Id: types.GUID(t.id + "2"), Account: other, Price: (*types.Amount)(new(big.Rat).Neg(x))},
+			},
+		}
+	}
+	book.Recompute()
+	return book, bank
+}
+
+func TestReconcile(t *testing.T) {
+	book, bank := testBook()
+	st := Statement{Date: date("2013-02-28"), Balance: big.NewRat(9445, 10)}
+
+	if x := Opening(book, bank); x.Cmp(big.NewRat(1000, 1)) != 0 {
+		t.Errorf("got opening balance %s", x.FloatString(2))
+	}
+	cands := Candidates(book, bank, st.Date)
+	if len(cands) != 3 {
+		t.Fatalf("got %d candidates, expected 3", len(cands))
+	}
+	if diff := Difference(book, bank, st, nil); diff.FloatString(2) != "-55.50" {
+		t.Errorf("got difference %s, expected -55.50", diff.FloatString(2))
+	}
+
+	// The bakery purchase is not on the statement yet.
+	cleared, err := Lookup(book, bank, []types.GUID{"b1", "c1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := Difference(book, bank, st, cleared); diff.Sign() != 0 {
+		t.Errorf("got difference %s, expected 0", diff.FloatString(2))
+	}
+	Postpone(book, bank, st, cleared[:1])
+	if s := cleared[0].State.String() + cleared[1].State.String(); s != "cn" {
+		t.Errorf("got states %s after postponing, expected cn", s)
+	}
+	if err := Finish(book, bank, st, cleared[:1]); err == nil {
+		t.Errorf("finished an unbalanced reconciliation")
+	}
+	if err := Finish(book, bank, st, cleared); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range book.Flows[bank] {
+		expected := map[types.GUID]string{"a1": "y", "b1": "y", "c1": "y", "d1": "n", "e1": "n", "f1": "v"}[f.Id]
+		if s := f.State.String(); s != expected {
+			t.Errorf("flow %s: got state %s, expected %s", f.Id, s, expected)
+		}
+	}
+	if !cleared[0].ReconciledTime.Equal(st.Date) || !bank.LastReconcile.Equal(st.Date) {
+		t.Errorf("got reconciliation dates %s, %s", cleared[0].ReconciledTime, bank.LastReconcile)
+	}
+
+	// Flows after the statement date cannot be reconciled.
+	late, _ := Lookup(book, bank, []types.GUID{"e1"})
+	st = Statement{Date: date("2013-02-28"), Balance: big.NewRat(8445, 10)}
+	if err := Finish(book, bank, st, late); err == nil {
+		t.Errorf("reconciled a flow after the statement date")
+	}
+	if _, err := Lookup(book, bank, []types.GUID{"a2"}); err == nil {
+		t.Errorf("found a flow of another account")
+	}
+}
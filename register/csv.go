@@ -0,0 +1,33 @@
+package register
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// WriteCSV writes register lines as CSV with a header row. Dates are
+// written as YYYY-MM-DD, amounts with a decimal point, and the
+// counter-accounts are separated by semicolons.
+func WriteCSV(w io.Writer, lines []Line) error {
+	cw := csv.NewWriter(w)
+	cw.Write(Header)
+	for i := range lines {
+		l := &lines[i]
+		cw.Write([]string{
+			l.Date.Format("2006-01-02"),
+			l.Number,
+			l.Description,
+			l.Account,
+			l.Memo,
+			strings.Join(l.Accounts, "; "),
+			(*types.Amount)(l.Amount).Decimal(),
+			(*types.Amount)(l.Balance).Decimal(),
+			l.State,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,133 @@
+// Package register implements export of account registers to CSV
+// and to Office Open XML spreadsheets.
+package register
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Line is a row of an account register.
+type Line struct {
+	Date        time.Time
+	Number      string
+	Description string
+	Account     string // Name of the account of the flow.
+	Memo        string
+	Accounts    []string // Names of the other accounts of the transaction.
+	Amount      *big.Rat // In the account commodity.
+	Balance     *big.Rat // Running balance after the flow.
+	State       string   // Reconciliation state: n, c, y, f or v.
+	Flow        *types.Flow
+}
+
+// A Filter selects flows of a register. Zero fields select everything.
+type Filter struct {
+	From, To time.Time // Inclusive bounds on transaction calendar dates.
+	Text     string    // Case insensitive substring of description or memo.
+}
+
+// Match reports whether the filter selects flow f.
+func (flt Filter) Match(f *types.Flow) bool {
+	d := day(f.Parent.Date)
+	if !flt.From.IsZero() && d.Before(day(flt.From)) {
+		return false
+	}
+	if !flt.To.IsZero() && d.After(day(flt.To)) {
+		return false
+	}
+	if flt.Text != "" {
+		text := strings.ToLower(flt.Text)
+		if !strings.Contains(strings.ToLower(f.Parent.Description), text) &&
+			!strings.Contains(strings.ToLower(f.Memo), text) {
+			return false
+		}
+	}
+	return true
+}
+
+// day returns the calendar date of t, in its own time zone, as
+// midnight UTC. Gnucash dates carry the zone of their author.
+func day(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// Lines returns the register of acct restricted to flows selected by
+// flt. Running balances include flows that are filtered out, so that
+// they match the full register. The book must have been recomputed.
+func Lines(book *types.Book, acct *types.Account, flt Filter) []Line {
+	var lines []Line
+	bal := new(big.Rat)
+	for _, f := range book.Flows[acct] {
+		bal.Add(bal, f.Units().Rat())
+		if !flt.Match(f) {
+			continue
+		}
+		lines = append(lines, newLine(f, bal))
+	}
+	return lines
+}
+
+// FlowLines returns register lines for flows of any accounts, such as
+// search results. Balances are the running balances of the account of
+// each flow. The book must have been recomputed.
+func FlowLines(book *types.Book, flows []*types.Flow) []Line {
+	balances := make(map[*types.Flow]*big.Rat)
+	done := make(map[*types.Account]bool)
+	for _, f := range flows {
+		if done[f.Account] {
+			continue
+		}
+		done[f.Account] = true
+		bal := new(big.Rat)
+		for _, g := range book.Flows[f.Account] {
+			bal.Add(bal, g.Units().Rat())
+			balances[g] = new(big.Rat).Set(bal)
+		}
+	}
+	lines := make([]Line, len(flows))
+	for i, f := range flows {
+		lines[i] = newLine(f, balances[f])
+	}
+	return lines
+}
+
+func newLine(f *types.Flow, bal *big.Rat) Line {
+	return Line{
+		Date:        f.Parent.Date,
+		Number:      f.Parent.Number,
+		Description: f.Parent.Description,
+		Account:     f.Account.Name,
+		Memo:        f.Memo,
+		Accounts:    counterAccounts(f),
+		Amount:      new(big.Rat).Set(f.Units().Rat()),
+		Balance:     new(big.Rat).Set(bal),
+		State:       f.State.String(),
+		Flow:        f,
+	}
+}
+
+// counterAccounts returns the sorted names of accounts of the other
+// flows of the transaction of f.
+func counterAccounts(f *types.Flow) []string {
+	var names []string
+	seen := map[string]bool{f.Account.Name: true}
+	for i := range f.Parent.Flows {
+		g := &f.Parent.Flows[i]
+		if g == f || g.Account == nil || seen[g.Account.Name] {
+			continue
+		}
+		seen[g.Account.Name] = true
+		names = append(names, g.Account.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Header is the list of column names of exported registers.
+var Header = []string{"Date", "Number", "Description", "Account", "Memo", "Transfer", "Amount", "Balance", "Reconciled"}
@@ -0,0 +1,212 @@
+package register
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func testBook() (*types.Book, *types.Account) {
+	bank := &types.Account{Id: "bank", Name: "/Assets/Bank", Unit: "EUR"}
+	food := &types.Account{Id: "food", Name: "/Expenses/Food", Unit: "EUR"}
+	misc := &types.Account{Id: "misc", Name: "/Expenses/Misc", Unit: "EUR"}
+	salary := &types.Account{Id: "salary", Name: "/Income/Salary", Unit: "EUR"}
+	amt := func(s string) *types.Amount {
+		x, _ := new(big.Rat).SetString(s)
+		return (*types.Amount)(x)
+	}
+	date := func(s string) time.Time {
+		t, _ := time.Parse("2006-01-02", s)
+		return t
+	}
+	book := &types.Book{
+		Accounts: map[types.GUID]*types.Account{"bank": bank, "food": food, "misc": misc, "salary": salary},
+		Transactions: map[types.GUID]*types.Transaction{
+			"t1": {Id: "t1", Date: date("2013-03-01"), Description: "ACME, Inc.", Currency: "EUR",
+				Flows: []types.Flow{
+					{Account: bank, Price: amt("1500"), State: types.Reconciled},
+					{Account: salary, Price: amt("-1500")},
+				}},
+			"t2": {Id: "t2", Date: date("2013-03-05"), Description: "Supermarket", Number: "101", Currency: "EUR",
+				Flows: []types.Flow{
+					{Account: bank, Price: amt("-42.5"), Memo: "weekly \"shopping\""},
+					{Account: food, Price: amt("30")},
+					{Account: misc, Price: amt("12.5")},
+				}},
+			"t3": {Id: "t3", Date: date("2013-04-02"), Description: "Bakery", Currency: "EUR",
+				Flows: []types.Flow{
+					{Account: bank, Price: amt("-3.20")},
+					{Account: food, Price: amt("3.20")},
+				}},
+		},
+	}
+	book.Recompute()
+	return book, bank
+}
+
+func TestCSV(t *testing.T) {
+	book, bank := testBook()
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, Lines(book, bank, Filter{})); err != nil {
+		t.Fatal(err)
+	}
+	expected := `Date,Number,Description,Account,Memo,Transfer,Amount,Balance,Reconciled
+2013-03-01,,"ACME, Inc.",/Assets/Bank,,/Income/Salary,1500.00,1500.00,y
+2013-03-05,101,Supermarket,/Assets/Bank,"weekly ""shopping""",/Expenses/Food; /Expenses/Misc,-42.50,1457.50,n
+2013-04-02,,Bakery,/Assets/Bank,,/Expenses/Food,-3.20,1454.30,n
+`
+	if s := buf.String(); s != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", s, expected)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	book, bank := testBook()
+	for _, c := range []struct {
+		flt      Filter
+		expected []string
+	}{
+		{Filter{From: time.Date(2013, 3, 5, 0, 0, 0, 0, time.UTC)}, []string{"Supermarket", "Bakery"}},
+		{Filter{To: time.Date(2013, 3, 5, 0, 0, 0, 0, time.UTC)}, []string{"ACME, Inc.", "Supermarket"}},
+		{Filter{Text: "SHOPPING"}, []string{"Supermarket"}},
+		{Filter{Text: "bak"}, []string{"Bakery"}},
+	} {
+		var descs []string
+		lines := Lines(book, bank, c.flt)
+		for _, l := range lines {
+			descs = append(descs, l.Description)
+		}
+		if strings.Join(descs, "|") != strings.Join(c.expected, "|") {
+			t.Errorf("filter %+v: got %q, expected %q", c.flt, descs, c.expected)
+		}
+		// Balances are those of the full register.
+		if last := lines[len(lines)-1]; c.flt.Text == "bak" && last.Balance.FloatString(2) != "1454.30" {
+			t.Errorf("filter %+v: got balance %s", c.flt, last.Balance.FloatString(2))
+		}
+	}
+
+	// Gnucash dates are compared by calendar date, in their own zone.
+	f := &types.Flow{Parent: &types.Transaction{Date: time.Date(2013, 3, 5, 21, 0, 0, 0, time.FixedZone("", -7*3600))}}
+	day := time.Date(2013, 3, 5, 0, 0, 0, 0, time.UTC)
+	if !(Filter{From: day, To: day}).Match(f) {
+		t.Errorf("flow of %s not selected on %s", f.Parent.Date, day.Format("2006-01-02"))
+	}
+	if next := day.AddDate(0, 0, 1); (Filter{From: next}).Match(f) {
+		t.Errorf("flow of %s selected from %s", f.Parent.Date, next.Format("2006-01-02"))
+	}
+}
+
+func TestFlowLines(t *testing.T) {
+	book, bank := testBook()
+	flows := []*types.Flow{book.Flows[bank][2], book.Flows[bank][1]}
+	for _, acct := range book.Accounts {
+		if acct.Name == "/Expenses/Food" {
+			flows = append(flows, book.Flows[acct]...)
+		}
+	}
+	var got []string
+	for _, l := range FlowLines(book, flows) {
+		got = append(got, l.Account+" "+l.Amount.FloatString(2)+" "+l.Balance.FloatString(2))
+	}
+	expected := "/Assets/Bank -3.20 1454.30|/Assets/Bank -42.50 1457.50|" +
+		"/Expenses/Food 30.00 30.00|/Expenses/Food 3.20 33.20"
+	if s := strings.Join(got, "|"); s != expected {
+		t.Errorf("got %s, expected %s", s, expected)
+	}
+}
+
+func TestXLSX(t *testing.T) {
+	book, bank := testBook()
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, bank.Name, Lines(book, bank, Filter{})); err != nil {
+		t.Fatal(err)
+	}
+	z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := make(map[string][]byte)
+	for _, f := range z.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Every part must be well-formed XML.
+		d := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			if _, err := d.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s: %s", f.Name, err)
+			}
+		}
+		parts[f.Name] = data
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml",
+		"xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if parts[name] == nil {
+			t.Errorf("missing part %s", name)
+		}
+	}
+	if !bytes.Contains(parts["xl/workbook.xml"], []byte(`name="Assets_Bank"`)) {
+		t.Errorf("unexpected workbook: %s", parts["xl/workbook.xml"])
+	}
+
+	var sheet struct {
+		Rows []struct {
+			R     int `xml:"r,attr"`
+			Cells []struct {
+				R     string `xml:"r,attr"`
+				Value string `xml:"v"`
+				Text  string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal(parts["xl/worksheets/sheet1.xml"], &sheet); err != nil {
+		t.Fatal(err)
+	}
+	if len(sheet.Rows) != 4 {
+		t.Fatalf("got %d rows, expected 4", len(sheet.Rows))
+	}
+	cells := make(map[string]string)
+	for _, row := range sheet.Rows {
+		for _, c := range row.Cells {
+			cells[c.R] = c.Value + c.Text
+		}
+	}
+	for ref, expected := range map[string]string{
+		"A1": "Date", "I1": "Reconciled",
+		"A2": "41334", "C2": "ACME, Inc.", "D2": "/Assets/Bank", "F2": "/Income/Salary", "I2": "y",
+		"B3": "101", "E3": `weekly "shopping"`, "G3": "-42.50", "H3": "1457.50",
+		"H4": "1454.30",
+	} {
+		if cells[ref] != expected {
+			t.Errorf("cell %s: got %q, expected %q", ref, cells[ref], expected)
+		}
+	}
+}
+
+func TestCellRef(t *testing.T) {
+	for _, c := range []struct {
+		col, row int
+		ref      string
+	}{
+		{0, 1, "A1"}, {7, 2, "H2"}, {25, 3, "Z3"}, {26, 4, "AA4"}, {701, 5, "ZZ5"}, {702, 6, "AAA6"},
+	} {
+		if ref := cellRef(c.col, c.row); ref != c.ref {
+			t.Errorf("cellRef(%d, %d) = %s, expected %s", c.col, c.row, ref, c.ref)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package register
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Cell styles, as indices in the cellXfs list of xlsxStyles.
+const (
+	styleDefault = iota
+	styleHeader
+	styleDate
+	styleAmount
+)
+
+// WriteXLSX writes register lines as an Office Open XML workbook with
+// a single sheet of the given name. Dates and amounts are written as
+// numbers with date and number formats, so that spreadsheets can
+// compute with them.
+func WriteXLSX(w io.Writer, sheet string, lines []Line) error {
+	if sheet == "" {
+		sheet = "Register"
+	}
+	z := zip.NewWriter(w)
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRels},
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, escape(sheetName(sheet)))},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/styles.xml", xlsxStyles},
+		{"xl/worksheets/sheet1.xml", worksheet(lines)},
+	}
+	for _, f := range files {
+		fw, err := z.Create(f.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(fw, f.content); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+func worksheet(lines []Line) string {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	b.WriteString(`<cols><col min="1" max="2" width="12" customWidth="1"/>` +
+		`<col min="3" max="6" width="40" customWidth="1"/>` +
+		`<col min="7" max="9" width="14" customWidth="1"/></cols>`)
+	b.WriteString(`<sheetData>`)
+	b.WriteString(`<row r="1">`)
+	for i, h := range Header {
+		stringCell(&b, cellRef(i, 1), h, styleHeader)
+	}
+	b.WriteString(`</row>`)
+	for i := range lines {
+		l := &lines[i]
+		r := i + 2
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%d</v></c>`, cellRef(0, r), styleDate, serialDate(l.Date))
+		stringCell(&b, cellRef(1, r), l.Number, styleDefault)
+		stringCell(&b, cellRef(2, r), l.Description, styleDefault)
+		stringCell(&b, cellRef(3, r), l.Account, styleDefault)
+		stringCell(&b, cellRef(4, r), l.Memo, styleDefault)
+		stringCell(&b, cellRef(5, r), strings.Join(l.Accounts, "; "), styleDefault)
+		fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, cellRef(6, r), styleAmount, (*types.Amount)(l.Amount).Decimal())
+		fmt.Fprintf(&b, `<c r="%s" s="%d"><v>%s</v></c>`, cellRef(7, r), styleAmount, (*types.Amount)(l.Balance).Decimal())
+		stringCell(&b, cellRef(8, r), l.State, styleDefault)
+		b.WriteString(`</row>`)
+	}
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// stringCell writes an inline string cell, or nothing for an empty string.
+func stringCell(b *strings.Builder, ref, s string, style int) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(b, `<c r="%s" s="%d" t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`,
+		ref, style, escape(s))
+}
+
+// cellRef returns the A1 reference of a cell, for a 0-based column
+// and a 1-based row.
+func cellRef(col, row int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return fmt.Sprintf("%s%d", name, row)
+}
+
+// serialDate returns the spreadsheet serial number of the date of t:
+// the number of days since December 30, 1899.
+func serialDate(t time.Time) int {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	return int(day(t).Sub(epoch).Hours() / 24)
+}
+
+// sheetName returns a valid sheet name: at most 31 characters,
+// excluding []:*?/\.
+func sheetName(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.Trim(s, "/"))
+	if r := []rune(s); len(r) > 31 {
+		s = string(r[len(r)-31:])
+	}
+	if s == "" {
+		s = "Register"
+	}
+	return s
+}
+
+func escape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+const xlsxContentTypes = xml.Header +
+	`<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRels = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header +
+	`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header +
+	`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// xlsxStyles defines the cell styles: default, bold header,
+// date (built-in format 14) and amount (built-in format 4, #,##0.00).
+const xlsxStyles = xml.Header +
+	`<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font>` +
+	`<font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill>` +
+	`<fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`</styleSheet>`
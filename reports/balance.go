@@ -2,49 +2,61 @@ package reports
 
 import (
 	"math/big"
-	"sort"
 	"time"
 
 	"github.com/remyoudompheng/gocash/types"
 )
 
 // Balance produces a balance report out of a list of flows.
-func Balance(flows [][]*types.Flow) BalanceReport {
-	perMonth := make(map[int]*big.Rat, 20)
+// Every period between p.Start and p.End appears in the report,
+// including periods without activity.
+func Balance(flows [][]*types.Flow, p Periods) BalanceReport {
+	var rep BalanceReport
+	first, last, ok := p.bounds(flows)
+	if !ok {
+		return rep
+	}
 
+	opening := new(big.Rat)
+	perPeriod := make(map[time.Time]*big.Rat)
 	for _, fs := range flows {
 		for _, f := range fs {
-			when := f.Parent.Date
-			key := when.Year()*16 + int(when.Month())
-			x := perMonth[key]
-			if x == nil {
-				x = new(big.Rat)
+			key := p.Truncate(f.Parent.Date)
+			switch {
+			case key.Before(first):
+				opening.Add(opening, (*big.Rat)(f.Price)) // FIXME: currency.
+			case key.After(last):
+			default:
+				x := perPeriod[key]
+				if x == nil {
+					x = new(big.Rat)
+					perPeriod[key] = x
+				}
+				x.Add(x, (*big.Rat)(f.Price))
 			}
-			perMonth[key] = x.Add(x, (*big.Rat)(f.Price)) // FIXME: currency.
 		}
 	}
 
-	var months []int
-	for m := range perMonth {
-		months = append(months, m)
-	}
-	sort.Ints(months)
-
-	var rep BalanceReport
-	val := new(big.Rat)
-	for _, m := range months {
-		t := time.Date(m/16, time.Month(m%16), 1,
-			0, 0, 0, 0, time.UTC)
-		next := new(big.Rat).Add(val, perMonth[m])
+	val := opening
+	for t := first; !t.After(last); t = p.Next(t) {
+		change := perPeriod[t]
+		if change == nil {
+			change = new(big.Rat)
+		}
+		next := new(big.Rat).Add(val, change)
 		rep.T = append(rep.T, t)
 		rep.Values = append(rep.Values, next)
+		rep.Changes = append(rep.Changes, change)
 		val = next
 	}
+	rep.Opening = opening
 
 	return rep
 }
 
 type BalanceReport struct {
-	T      []time.Time
-	Values []*big.Rat
+	T       []time.Time // Start of each period.
+	Values  []*big.Rat  // Balance at the end of each period.
+	Changes []*big.Rat  // Sum of flows during each period.
+	Opening *big.Rat    // Balance before the first period.
 }
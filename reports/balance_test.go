@@ -0,0 +1,87 @@
+package reports
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func testFlow(date string, amount string) *types.Flow {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		panic(err)
+	}
+	x, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		panic(amount)
+	}
+	return &types.Flow{
+		Price:  (*types.Amount)(x),
+		Parent: &types.Transaction{Date: t},
+	}
+}
+
+func TestBalance(t *testing.T) {
+	flows := [][]*types.Flow{{
+		testFlow("2013-01-05", "100"),
+		testFlow("2013-01-20", "-30"),
+		testFlow("2013-04-02", "50"),
+		testFlow("2013-11-30", "-20"),
+	}}
+
+	type result struct {
+		T      string
+		Value  string
+		Change string
+	}
+	tests := []struct {
+		P   Periods
+		Exp []result
+	}{
+		{Periods{Period: Monthly, End: time.Date(2013, 5, 1, 0, 0, 0, 0, time.UTC)}, []result{
+			{"2013-01-01", "70", "70"},
+			{"2013-02-01", "70", "0"},
+			{"2013-03-01", "70", "0"},
+			{"2013-04-01", "120", "50"},
+			{"2013-05-01", "120", "0"},
+		}},
+		{Periods{Period: Quarterly}, []result{
+			{"2013-01-01", "70", "70"},
+			{"2013-04-01", "120", "50"},
+			{"2013-07-01", "120", "0"},
+			{"2013-10-01", "100", "-20"},
+		}},
+		{Periods{Period: FiscalYear, FiscalStart: time.April}, []result{
+			{"2012-04-01", "70", "70"},
+			{"2013-04-01", "100", "30"},
+		}},
+		{Periods{Period: Yearly, Start: time.Date(2013, 2, 1, 0, 0, 0, 0, time.UTC)}, []result{
+			{"2013-01-01", "100", "100"},
+		}},
+		{Periods{Period: Weekly, Start: time.Date(2013, 4, 1, 0, 0, 0, 0, time.UTC),
+			End: time.Date(2013, 4, 10, 0, 0, 0, 0, time.UTC)}, []result{
+			{"2013-04-01", "120", "50"},
+			{"2013-04-08", "120", "0"},
+		}},
+	}
+
+	for _, test := range tests {
+		rep := Balance(flows, test.P)
+		if len(rep.T) != len(test.Exp) {
+			t.Errorf("%v: got %d periods, expected %d", test.P.Period, len(rep.T), len(test.Exp))
+			continue
+		}
+		for i, exp := range test.Exp {
+			got := result{
+				rep.T[i].Format("2006-01-02"),
+				rep.Values[i].RatString(),
+				rep.Changes[i].RatString(),
+			}
+			if got != exp {
+				t.Errorf("%v: got %v, expected %v", test.P.Period, got, exp)
+			}
+		}
+	}
+}
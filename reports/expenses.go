@@ -0,0 +1,57 @@
+package reports
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Expenses aggregates expense flows per period, grouping accounts
+// by their ancestor at the given depth.
+func Expenses(book *types.Book, depth int, p Periods) ExpenseReport {
+	groups := make(map[string][]*types.Flow)
+	var all [][]*types.Flow
+	for _, acct := range book.Accounts {
+		if acct.Type != "EXPENSE" || len(book.Flows[acct]) == 0 {
+			continue
+		}
+		g := acct.Ancestor(depth)
+		groups[g] = append(groups[g], book.Flows[acct]...)
+		all = append(all, book.Flows[acct])
+	}
+
+	var rep ExpenseReport
+	first, last, ok := p.bounds(all)
+	if !ok {
+		return rep
+	}
+	p.Start, p.End = first, last
+	for g := range groups {
+		rep.Categories = append(rep.Categories, g)
+	}
+	sort.Strings(rep.Categories)
+	rep.Totals = make([]*big.Rat, len(rep.Categories))
+	for _, g := range rep.Categories {
+		b := Balance([][]*types.Flow{groups[g]}, p)
+		rep.Amounts = append(rep.Amounts, b.Changes)
+		if rep.T == nil {
+			rep.T = b.T
+		}
+	}
+	for i, amounts := range rep.Amounts {
+		rep.Totals[i] = new(big.Rat)
+		for _, x := range amounts {
+			rep.Totals[i].Add(rep.Totals[i], x)
+		}
+	}
+	return rep
+}
+
+type ExpenseReport struct {
+	T          []time.Time  // Start of each period.
+	Categories []string     // Names of expense categories.
+	Amounts    [][]*big.Rat // Spending of each category in each period.
+	Totals     []*big.Rat   // Spending of each category over the report.
+}
@@ -0,0 +1,105 @@
+package reports
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// NetWorth values all asset and liability accounts of book at the end
+// of each period, using the price database to convert commodities
+// to the given currency.
+func NetWorth(book *types.Book, currency string, p Periods) NetWorthReport {
+	var rep NetWorthReport
+	var accts []*types.Account
+	var flows [][]*types.Flow
+	for _, acct := range book.Accounts {
+		if acct.IsAsset() || acct.IsLiability() {
+			accts = append(accts, acct)
+			flows = append(flows, book.Flows[acct])
+		}
+	}
+	first, last, ok := p.bounds(flows)
+	if !ok {
+		return rep
+	}
+	for t := first; !t.After(last); t = p.Next(t) {
+		rep.T = append(rep.T, t)
+	}
+
+	groupIdx := make(map[string]int)
+	for _, acct := range accts {
+		g := acct.Ancestor(1)
+		if _, ok := groupIdx[g]; !ok {
+			groupIdx[g] = len(rep.Groups)
+			rep.Groups = append(rep.Groups, g)
+		}
+	}
+	sort.Strings(rep.Groups)
+	for i, g := range rep.Groups {
+		groupIdx[g] = i
+	}
+
+	rep.Series = make([][]*big.Rat, len(rep.Groups))
+	for i := range rep.Series {
+		rep.Series[i] = newRats(len(rep.T))
+	}
+	rep.Assets = newRats(len(rep.T))
+	rep.Liabilities = newRats(len(rep.T))
+	rep.NetWorth = newRats(len(rep.T))
+
+	missing := make(map[string]bool)
+	for k, acct := range accts {
+		fs := flows[k]
+		series := rep.Series[groupIdx[acct.Ancestor(1)]]
+		units := new(big.Rat)
+		idx := 0
+		for j, t := range rep.T {
+			end := p.Next(t)
+			for ; idx < len(fs) && fs[idx].Parent.Date.Before(end); idx++ {
+				units.Add(units, fs[idx].Units().Rat())
+			}
+			if units.Sign() == 0 {
+				continue
+			}
+			price := book.Prices.Lookup(acct.Unit, currency, end)
+			if price == nil {
+				missing[acct.Unit] = true
+				continue
+			}
+			value := new(big.Rat).Mul(units, price.Rat())
+			series[j].Add(series[j], value)
+			if acct.IsAsset() {
+				rep.Assets[j].Add(rep.Assets[j], value)
+			} else {
+				rep.Liabilities[j].Sub(rep.Liabilities[j], value)
+			}
+			rep.NetWorth[j].Add(rep.NetWorth[j], value)
+		}
+	}
+	for c := range missing {
+		rep.Missing = append(rep.Missing, c)
+	}
+	sort.Strings(rep.Missing)
+	return rep
+}
+
+type NetWorthReport struct {
+	T           []time.Time  // Start of each period.
+	Groups      []string     // Names of top-level account groups.
+	Series      [][]*big.Rat // Value of each group at the end of each period.
+	Assets      []*big.Rat   // Total value of assets.
+	Liabilities []*big.Rat   // Total amount owed, as positive numbers.
+	NetWorth    []*big.Rat   // Assets minus liabilities.
+	Missing     []string     // Commodities that could not be valued.
+}
+
+func newRats(n int) []*big.Rat {
+	s := make([]*big.Rat, n)
+	for i := range s {
+		s[i] = new(big.Rat)
+	}
+	return s
+}
@@ -0,0 +1,47 @@
+package reports
+
+import (
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func TestNetWorth(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	rep := NetWorth(book, "USD", Periods{
+		Period: Quarterly,
+		End:    time.Date(2014, 6, 1, 0, 0, 0, 0, time.UTC),
+	})
+	// Broker: 1000 - 500 (Q1), -300 (Q2), +20 (Q4), +350 (2014 Q1).
+	// ACME: 10 shares, 15 shares, 10 shares.
+	// Card: -100 from Q1.
+	expected := []struct {
+		T                      string
+		Assets, Liab, NetWorth string
+	}{
+		{"2013-01-01", "1000", "100", "900"},  // 500 + 10*50
+		{"2013-04-01", "1100", "100", "1000"}, // 200 + 15*60
+		{"2013-07-01", "1100", "100", "1000"}, // 200 + 15*60
+		{"2013-10-01", "1045", "100", "945"},  // 220 + 15*55
+		{"2014-01-01", "1270", "100", "1170"}, // 570 + 10*70
+		{"2014-04-01", "1370", "100", "1270"}, // 570 + 10*80
+	}
+	if len(rep.T) != len(expected) {
+		t.Fatalf("got %d periods, expected %d", len(rep.T), len(expected))
+	}
+	for i, exp := range expected {
+		got := rep.T[i].Format("2006-01-02") + " " + rep.Assets[i].RatString() + " " +
+			rep.Liabilities[i].RatString() + " " + rep.NetWorth[i].RatString()
+		if want := exp.T + " " + exp.Assets + " " + exp.Liab + " " + exp.NetWorth; got != want {
+			t.Errorf("got %s, expected %s", got, want)
+		}
+	}
+	if len(rep.Groups) != 2 || rep.Groups[0] != "/Assets" || rep.Groups[1] != "/Liabilities" {
+		t.Errorf("unexpected groups %q", rep.Groups)
+	}
+}
@@ -0,0 +1,149 @@
+package reports
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Period is the length of the buckets of a report.
+type Period int
+
+const (
+	Monthly Period = iota
+	Daily
+	Weekly
+	Quarterly
+	Yearly
+	FiscalYear
+)
+
+var periodNames = [...]string{
+	Daily:      "daily",
+	Weekly:     "weekly",
+	Monthly:    "monthly",
+	Quarterly:  "quarterly",
+	Yearly:     "yearly",
+	FiscalYear: "fiscal",
+}
+
+func (p Period) String() string {
+	if p < 0 || int(p) >= len(periodNames) {
+		return fmt.Sprintf("Period(%d)", int(p))
+	}
+	return periodNames[p]
+}
+
+// ParsePeriod parses a period name as returned by Period.String.
+func ParsePeriod(s string) (Period, error) {
+	for p, name := range periodNames {
+		if name == s {
+			return Period(p), nil
+		}
+	}
+	return Monthly, fmt.Errorf("unknown period %q", s)
+}
+
+// ParsePeriods builds a Periods from user input. Dates use
+// the YYYY-MM-DD format and may be empty.
+func ParsePeriods(period string, fiscal int, from, to string) (p Periods, err error) {
+	p.Period, err = ParsePeriod(period)
+	if err != nil {
+		return
+	}
+	if fiscal < 1 || fiscal > 12 {
+		return p, fmt.Errorf("invalid fiscal year start month %d", fiscal)
+	}
+	p.FiscalStart = time.Month(fiscal)
+	if from != "" {
+		p.Start, err = time.Parse("2006-01-02", from)
+		if err != nil {
+			return p, fmt.Errorf("invalid start date: %s", err)
+		}
+	}
+	if to != "" {
+		p.End, err = time.Parse("2006-01-02", to)
+		if err != nil {
+			return p, fmt.Errorf("invalid end date: %s", err)
+		}
+	}
+	return p, nil
+}
+
+// Periods describes how a report splits time into buckets.
+type Periods struct {
+	Period Period
+	// FiscalStart is the first month of the fiscal year, used
+	// when Period is FiscalYear. Zero means January.
+	FiscalStart time.Month
+	// Start and End limit the report. A zero Start means the
+	// date of the first flow, a zero End the date of the last one.
+	Start, End time.Time
+}
+
+// Truncate returns the beginning of the period containing t.
+func (p Periods) Truncate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch p.Period {
+	case Daily:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case Weekly:
+		// Weeks start on Monday.
+		off := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-off, 0, 0, 0, 0, time.UTC)
+	case Quarterly:
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, time.UTC)
+	case Yearly:
+		return time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+	case FiscalYear:
+		start := p.FiscalStart
+		if start == 0 {
+			start = time.January
+		}
+		if m < start {
+			y--
+		}
+		return time.Date(y, start, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// Next returns the beginning of the period following the one
+// starting at t.
+func (p Periods) Next(t time.Time) time.Time {
+	switch p.Period {
+	case Daily:
+		return t.AddDate(0, 0, 1)
+	case Weekly:
+		return t.AddDate(0, 0, 7)
+	case Quarterly:
+		return t.AddDate(0, 3, 0)
+	case Yearly, FiscalYear:
+		return t.AddDate(1, 0, 0)
+	default:
+		return t.AddDate(0, 1, 0)
+	}
+}
+
+// bounds returns the first and last periods covered by a report
+// over the given flows.
+func (p Periods) bounds(flows [][]*types.Flow) (first, last time.Time, ok bool) {
+	start, end := p.Start, p.End
+	for _, fs := range flows {
+		for _, f := range fs {
+			when := f.Parent.Date
+			if p.Start.IsZero() && (start.IsZero() || when.Before(start)) {
+				start = when
+			}
+			if p.End.IsZero() && when.After(end) {
+				end = when
+			}
+		}
+	}
+	if start.IsZero() || end.Before(start) {
+		return first, last, false
+	}
+	return p.Truncate(start), p.Truncate(end), true
+}
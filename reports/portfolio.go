@@ -0,0 +1,272 @@
+package reports
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// Portfolio values the STOCK and MUTUAL accounts of book in the given
+// currency at p.End (or now), and computes their returns between
+// p.Start (or the first investment) and p.End.
+func Portfolio(book *types.Book, currency string, p Periods) PortfolioReport {
+	end := p.End
+	if end.IsZero() {
+		end = time.Now()
+	}
+	rep := PortfolioReport{End: end, Value: new(big.Rat), Cost: new(big.Rat), Gain: new(big.Rat)}
+	var all []cashFlow
+	var totalStart *big.Rat
+	for _, acct := range book.Accounts {
+		if acct.Type != "STOCK" && acct.Type != "MUTUAL" {
+			continue
+		}
+		flows := book.Flows[acct]
+		if len(flows) == 0 {
+			continue
+		}
+		h := holding(book, acct, currency, end)
+		start := p.Start
+		if start.IsZero() {
+			start = flows[0].Parent.Date
+		}
+		if rep.Start.IsZero() || start.Before(rep.Start) {
+			rep.Start = start
+		}
+		cfs, v0 := securityCashFlows(book, acct, currency, start, end)
+		h.TWR = timeWeighted(book, acct, currency, start, end)
+		h.IRR = xirr(withValues(cfs, start, v0, end, h.Value))
+		all = append(all, cfs...)
+		if v0 != nil {
+			if totalStart == nil {
+				totalStart = new(big.Rat)
+			}
+			totalStart.Add(totalStart, v0)
+		}
+		rep.Holdings = append(rep.Holdings, h)
+		rep.Value.Add(rep.Value, h.Value)
+		rep.Cost.Add(rep.Cost, h.Cost)
+	}
+	rep.Gain.Sub(rep.Value, rep.Cost)
+	total, _ := rep.Value.Float64()
+	for i := range rep.Holdings {
+		if total != 0 {
+			v, _ := rep.Holdings[i].Value.Float64()
+			rep.Holdings[i].Share = v / total
+		}
+	}
+	sort.Slice(rep.Holdings, func(i, j int) bool {
+		return rep.Holdings[i].Account.Name < rep.Holdings[j].Account.Name
+	})
+	sort.Slice(all, func(i, j int) bool { return all[i].T.Before(all[j].T) })
+	rep.IRR = xirr(withValues(all, rep.Start, totalStart, end, rep.Value))
+	return rep
+}
+
+type PortfolioReport struct {
+	Start, End time.Time
+	Holdings   []Holding
+	Value      *big.Rat // Total market value.
+	Cost       *big.Rat // Total cost basis.
+	Gain       *big.Rat // Total unrealized gain.
+	IRR        float64  // Money-weighted annual return of the portfolio.
+}
+
+// A Holding describes the position in a security.
+type Holding struct {
+	Account  *types.Account
+	Quantity *big.Rat // Number of shares.
+	Cost     *big.Rat // Cost basis, using the average cost method.
+	Price    *big.Rat // Latest known price.
+	PriceAt  time.Time
+	Value    *big.Rat // Market value.
+	Gain     *big.Rat // Unrealized gain.
+	Share    float64  // Fraction of the portfolio value.
+	TWR      float64  // Time-weighted return over the period.
+	IRR      float64  // Money-weighted annual return over the period.
+	// Skipped counts the flows that have no exchange rate to the
+	// report currency: they are left out of the cost and returns.
+	Skipped int
+}
+
+// holding computes the position of an account at time end.
+func holding(book *types.Book, acct *types.Account, currency string, end time.Time) Holding {
+	h := Holding{Account: acct, Quantity: new(big.Rat), Cost: new(big.Rat)}
+	for _, f := range book.Flows[acct] {
+		if f.Parent.Date.After(end) {
+			break
+		}
+		units := f.Units().Rat()
+		if units.Sign() >= 0 || h.Quantity.Sign() == 0 {
+			if value := flowValue(book, f, currency); value != nil {
+				h.Cost.Add(h.Cost, value)
+			} else {
+				h.Skipped++
+			}
+		} else {
+			// Remove the average cost of sold shares.
+			ratio := new(big.Rat).Quo(units, h.Quantity)
+			h.Cost.Add(h.Cost, ratio.Mul(ratio, h.Cost))
+		}
+		h.Quantity.Add(h.Quantity, units)
+	}
+	h.Price, h.PriceAt = securityPrice(book, acct, currency, end)
+	h.Value = new(big.Rat).Mul(h.Quantity, h.Price)
+	h.Gain = new(big.Rat).Sub(h.Value, h.Cost)
+	return h
+}
+
+// securityPrice returns the price of the account commodity at time t,
+// from the price database or else from the last transaction, converted
+// to currency.
+func securityPrice(book *types.Book, acct *types.Account, currency string, t time.Time) (*big.Rat, time.Time) {
+	if v, at := book.Prices.Quote(acct.Unit, currency, t); v != nil {
+		return v.Rat(), at
+	}
+	flows := book.Flows[acct]
+	for i := len(flows) - 1; i >= 0; i-- {
+		f := flows[i]
+		if f.Parent.Date.After(t) || f.Units().Rat().Sign() == 0 {
+			continue
+		}
+		if value := flowValue(book, f, currency); value != nil {
+			return value.Quo(value, f.Units().Rat()), f.Parent.Date
+		}
+	}
+	return new(big.Rat), time.Time{}
+}
+
+// flowValue returns the value of f in currency, converted at the date
+// of its transaction, or nil if no exchange rate is known.
+func flowValue(book *types.Book, f *types.Flow, currency string) *big.Rat {
+	rate := book.Prices.Lookup(f.Parent.Currency, currency, f.Parent.Date)
+	if rate == nil {
+		return nil
+	}
+	return new(big.Rat).Mul(f.Price.Rat(), rate.Rat())
+}
+
+// valueAt returns the market value of an account at time t.
+func valueAt(book *types.Book, acct *types.Account, currency string, t time.Time) *big.Rat {
+	units := new(big.Rat)
+	for _, f := range book.Flows[acct] {
+		if f.Parent.Date.After(t) {
+			break
+		}
+		units.Add(units, f.Units().Rat())
+	}
+	price, _ := securityPrice(book, acct, currency, t)
+	return units.Mul(units, price)
+}
+
+type cashFlow struct {
+	T      time.Time
+	Amount float64 // Positive when money is withdrawn from the investment.
+}
+
+// securityCashFlows returns the cash flows of an account in (start, end]
+// and its value at start (nil if the account did not exist yet).
+func securityCashFlows(book *types.Book, acct *types.Account, currency string, start, end time.Time) (cfs []cashFlow, v0 *big.Rat) {
+	flows := book.Flows[acct]
+	if flows[0].Parent.Date.Before(start) {
+		v0 = valueAt(book, acct, currency, start)
+	}
+	for _, f := range flows {
+		d := f.Parent.Date
+		if d.Before(start) || (v0 != nil && d.Equal(start)) || d.After(end) {
+			continue
+		}
+		value := flowValue(book, f, currency)
+		if value == nil {
+			continue
+		}
+		v, _ := value.Float64()
+		cfs = append(cfs, cashFlow{T: d, Amount: -v})
+	}
+	return cfs, v0
+}
+
+// withValues adds the initial and final valuations to cash flows.
+func withValues(cfs []cashFlow, start time.Time, v0 *big.Rat, end time.Time, v1 *big.Rat) []cashFlow {
+	var all []cashFlow
+	if v0 != nil {
+		x, _ := v0.Float64()
+		all = append(all, cashFlow{T: start, Amount: -x})
+	}
+	all = append(all, cfs...)
+	x, _ := v1.Float64()
+	return append(all, cashFlow{T: end, Amount: x})
+}
+
+// timeWeighted computes the time-weighted return of an account:
+// the period is split at each external flow and the returns of
+// sub-periods are chained.
+func timeWeighted(book *types.Book, acct *types.Account, currency string, start, end time.Time) float64 {
+	growth := 1.0
+	prev := valueAt(book, acct, currency, start)
+	flows := book.Flows[acct]
+	for i := 0; i < len(flows); i++ {
+		d := flows[i].Parent.Date
+		if !d.After(start) || d.After(end) {
+			continue
+		}
+		// Merge flows of the same date.
+		net := new(big.Rat)
+		for ; ; i++ {
+			if value := flowValue(book, flows[i], currency); value != nil {
+				net.Add(net, value)
+			}
+			if i+1 == len(flows) || !flows[i+1].Parent.Date.Equal(d) {
+				break
+			}
+		}
+		after := valueAt(book, acct, currency, d)
+		before := new(big.Rat).Sub(after, net)
+		if prev.Sign() != 0 {
+			r, _ := new(big.Rat).Quo(before, prev).Float64()
+			growth *= r
+		}
+		prev = after
+	}
+	if prev.Sign() != 0 {
+		r, _ := new(big.Rat).Quo(valueAt(book, acct, currency, end), prev).Float64()
+		growth *= r
+	}
+	return growth - 1
+}
+
+// xirr computes the annual rate r such that the net present value
+// of cash flows is zero. It returns NaN if no solution is found.
+func xirr(cfs []cashFlow) float64 {
+	if len(cfs) < 2 {
+		return math.NaN()
+	}
+	t0 := cfs[0].T
+	npv := func(r float64) float64 {
+		sum := 0.0
+		for _, cf := range cfs {
+			years := cf.T.Sub(t0).Hours() / (24 * 365)
+			sum += cf.Amount / math.Pow(1+r, years)
+		}
+		return sum
+	}
+	// Bisection over a wide range of rates.
+	lo, hi := -0.9999, 10.0
+	flo, fhi := npv(lo), npv(hi)
+	if math.IsNaN(flo) || math.IsNaN(fhi) || flo*fhi > 0 {
+		return math.NaN()
+	}
+	for i := 0; i < 200 && hi-lo > 1e-10; i++ {
+		mid := (lo + hi) / 2
+		fmid := npv(mid)
+		if fmid*flo > 0 {
+			lo, flo = mid, fmid
+		} else {
+			hi = mid
+		}
+	}
+	return (lo + hi) / 2
+}
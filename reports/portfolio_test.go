@@ -0,0 +1,127 @@
+package reports
+
+import (
+	"math"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func TestPortfolio(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	rep := Portfolio(book, "USD", Periods{End: time.Date(2014, 7, 1, 0, 0, 0, 0, time.UTC)})
+	if len(rep.Holdings) != 1 {
+		t.Fatalf("got %d holdings, expected 1", len(rep.Holdings))
+	}
+	h := rep.Holdings[0]
+	// Bought 10 @ 50 and 5 @ 60, sold 5: average cost 800/15.
+	checks := []struct{ name, got, exp string }{
+		{"quantity", h.Quantity.RatString(), "10"},
+		{"cost", h.Cost.RatString(), "1600/3"},
+		{"price", h.Price.RatString(), "80"},
+		{"value", h.Value.RatString(), "800"},
+		{"gain", h.Gain.RatString(), "800/3"},
+	}
+	for _, c := range checks {
+		if c.got != c.exp {
+			t.Errorf("%s: got %s, expected %s", c.name, c.got, c.exp)
+		}
+	}
+	if h.Share != 1 {
+		t.Errorf("share: got %v, expected 1", h.Share)
+	}
+	// The price went from 50 to 80 between flows.
+	if math.Abs(h.TWR-0.6) > 1e-9 {
+		t.Errorf("TWR: got %v, expected 0.6", h.TWR)
+	}
+	if math.IsNaN(h.IRR) || h.IRR <= 0 || h.IRR != rep.IRR {
+		t.Errorf("IRR: got %v (portfolio %v)", h.IRR, rep.IRR)
+	}
+}
+
+func TestPortfolioCurrency(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	end := Periods{End: time.Date(2014, 7, 1, 0, 0, 0, 0, time.UTC)}
+	usd := Portfolio(book, "USD", end).Holdings[0]
+	// Quote ACME in EUR, at 1 USD = 0.5 EUR: EUR figures are half of
+	// USD ones, and returns are the same.
+	for _, q := range book.Prices {
+		q.Currency = "EUR"
+		q.Value = (*types.Amount)(new(big.Rat).Quo(q.Value.Rat(), big.NewRat(2, 1)))
+	}
+	book.Prices = append(types.Prices{{Commodity: "USD", Currency: "EUR",
+		Time: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC), Value: (*types.Amount)(big.NewRat(1, 2))}}, book.Prices...)
+	eur := Portfolio(book, "EUR", end).Holdings[0]
+	half := func(x *big.Rat) string { return new(big.Rat).Quo(x, big.NewRat(2, 1)).RatString() }
+	checks := []struct{ name, got, exp string }{
+		{"cost", eur.Cost.RatString(), half(usd.Cost)},
+		{"price", eur.Price.RatString(), half(usd.Price)},
+		{"value", eur.Value.RatString(), half(usd.Value)},
+		{"gain", eur.Gain.RatString(), half(usd.Gain)},
+	}
+	for _, c := range checks {
+		if c.got != c.exp {
+			t.Errorf("%s: got %s, expected %s", c.name, c.got, c.exp)
+		}
+	}
+	if math.Abs(eur.TWR-usd.TWR) > 1e-9 || math.Abs(eur.IRR-usd.IRR) > 1e-6 || eur.Skipped != 0 {
+		t.Errorf("got TWR %v IRR %v (%d skipped), expected TWR %v IRR %v", eur.TWR, eur.IRR, eur.Skipped, usd.TWR, usd.IRR)
+	}
+	// Flows without an exchange rate are counted.
+	if gbp := Portfolio(book, "GBP", end).Holdings[0]; gbp.Skipped != 2 || gbp.Cost.Sign() != 0 {
+		t.Errorf("GBP: got %d skipped flows, cost %s", gbp.Skipped, gbp.Cost.RatString())
+	}
+}
+
+func TestSecurityPrice(t *testing.T) {
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	var acme *types.Account
+	for _, acct := range book.Accounts {
+		if acct.Unit == "ACME" {
+			acme = acct
+		}
+	}
+	end := time.Date(2014, 7, 1, 0, 0, 0, 0, time.UTC)
+	// Quotes of USD in ACME are used in reverse.
+	for _, q := range book.Prices {
+		q.Commodity, q.Currency = q.Currency, q.Commodity
+		q.Value = (*types.Amount)(new(big.Rat).Inv(q.Value.Rat()))
+	}
+	if p, _ := securityPrice(book, acme, "USD", end); p.RatString() != "80" {
+		t.Errorf("got price %s, expected 80", p.RatString())
+	}
+	// Without quotes, the last transaction price is converted.
+	book.Prices = types.Prices{{Commodity: "USD", Currency: "EUR", Time: end.AddDate(-10, 0, 0), Value: (*types.Amount)(big.NewRat(1, 2))}}
+	if p, _ := securityPrice(book, acme, "EUR", end); p.RatString() != "35" {
+		t.Errorf("got price %s, expected 35", p.RatString())
+	}
+	if p, _ := securityPrice(book, acme, "GBP", end); p.Sign() != 0 {
+		t.Errorf("got price %s in GBP, expected none", p.RatString())
+	}
+}
+
+func TestXIRR(t *testing.T) {
+	t0 := time.Date(2010, 1, 1, 0, 0, 0, 0, time.UTC)
+	cfs := []cashFlow{
+		{T: t0, Amount: -1000},
+		{T: t0.AddDate(0, 0, 365), Amount: 1100},
+	}
+	if r := xirr(cfs); math.Abs(r-0.1) > 1e-6 {
+		t.Errorf("got %v, expected 0.1", r)
+	}
+}
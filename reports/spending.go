@@ -0,0 +1,205 @@
+package reports
+
+import (
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// SpendingOptions controls the spending analysis.
+type SpendingOptions struct {
+	Depth     int     // Depth of expense categories in the account tree.
+	Top       int     // Number of ranked categories and payees (0 for all).
+	Trailing  int     // Number of months of the trailing average.
+	Threshold float64 // Deviation from the average, in standard deviations.
+}
+
+// DefaultSpendingOptions are reasonable defaults for Spending.
+var DefaultSpendingOptions = SpendingOptions{
+	Depth:     2,
+	Top:       10,
+	Trailing:  6,
+	Threshold: 2,
+}
+
+// Spending analyses where the money goes between p.Start and p.End:
+// it ranks expense categories and payees and compares the spending
+// of the last month with previous months.
+func Spending(book *types.Book, p Periods, opts SpendingOptions) SpendingReport {
+	var rep SpendingReport
+	var all [][]*types.Flow
+	for _, acct := range book.Accounts {
+		if acct.Type == "EXPENSE" {
+			all = append(all, book.Flows[acct])
+		}
+	}
+	first, last, ok := p.bounds(all)
+	if !ok {
+		return rep
+	}
+	rep.Start, rep.End = first, p.Next(last)
+
+	// Rankings.
+	categories := make(map[string]*big.Rat)
+	payees := make(map[string]*big.Rat)
+	total := new(big.Rat)
+	for _, fs := range all {
+		for _, f := range fs {
+			d := f.Parent.Date
+			if d.Before(rep.Start) || !d.Before(rep.End) {
+				continue
+			}
+			addTo(categories, f.Account.Ancestor(opts.Depth), f.Price.Rat())
+			addTo(payees, payeeName(f.Parent.Description), f.Price.Rat())
+			total.Add(total, f.Price.Rat())
+		}
+	}
+	rep.Total = total
+	rep.Categories = rank(categories, total, opts.Top)
+	rep.Payees = rank(payees, total, opts.Top)
+
+	// Monthly trends up to the end of the report.
+	monthly := Expenses(book, opts.Depth, Periods{Period: Monthly, End: rep.End.AddDate(0, 0, -1)})
+	n := len(monthly.T)
+	if n == 0 {
+		return rep
+	}
+	for i, c := range monthly.Categories {
+		amounts := monthly.Amounts[i]
+		tr := Trend{
+			Category: c,
+			Month:    monthly.T[n-1],
+			Current:  amounts[n-1],
+			Previous: new(big.Rat),
+			LastYear: new(big.Rat),
+		}
+		if n >= 2 {
+			tr.Previous = amounts[n-2]
+		}
+		if n >= 13 {
+			tr.LastYear = amounts[n-13]
+		}
+		tr.MoM = relChange(tr.Current, tr.Previous)
+		tr.YoY = relChange(tr.Current, tr.LastYear)
+
+		lo := n - 1 - opts.Trailing
+		if lo < 0 {
+			lo = 0
+		}
+		window := amounts[lo : n-1]
+		tr.Average, tr.Deviation = deviation(tr.Current, window)
+		if len(window) > 0 {
+			tr.Flagged = math.Abs(tr.Deviation) >= opts.Threshold
+		}
+		rep.Trends = append(rep.Trends, tr)
+	}
+	return rep
+}
+
+type SpendingReport struct {
+	Start, End time.Time // Bounds of the analysed interval (End excluded).
+	Total      *big.Rat
+	Categories []Ranked // Categories by decreasing spending.
+	Payees     []Ranked // Payees by decreasing spending.
+	Trends     []Trend  // Spending of the last month per category.
+}
+
+// A Ranked is an entry of a spending ranking.
+type Ranked struct {
+	Name   string
+	Amount *big.Rat
+	Share  float64 // Fraction of total spending.
+}
+
+// A Trend compares the spending of a category in a month
+// with previous months.
+type Trend struct {
+	Category  string
+	Month     time.Time
+	Current   *big.Rat
+	Previous  *big.Rat // Previous month.
+	LastYear  *big.Rat // Same month of previous year.
+	Average   *big.Rat // Trailing average.
+	MoM, YoY  float64  // Relative changes (NaN if undefined).
+	Deviation float64  // Distance to the average in standard deviations.
+	Flagged   bool     // Whether the deviation exceeds the threshold.
+}
+
+// payeeName normalizes a transaction description.
+func payeeName(desc string) string {
+	return strings.Join(strings.Fields(desc), " ")
+}
+
+func addTo(m map[string]*big.Rat, key string, x *big.Rat) {
+	if m[key] == nil {
+		m[key] = new(big.Rat)
+	}
+	m[key].Add(m[key], x)
+}
+
+func rank(m map[string]*big.Rat, total *big.Rat, top int) []Ranked {
+	var r []Ranked
+	tot, _ := total.Float64()
+	for name, x := range m {
+		v, _ := x.Float64()
+		e := Ranked{Name: name, Amount: x}
+		if tot != 0 {
+			e.Share = v / tot
+		}
+		r = append(r, e)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		if c := r[i].Amount.Cmp(r[j].Amount); c != 0 {
+			return c > 0
+		}
+		return r[i].Name < r[j].Name
+	})
+	if top > 0 && len(r) > top {
+		r = r[:top]
+	}
+	return r
+}
+
+func relChange(cur, prev *big.Rat) float64 {
+	if prev.Sign() == 0 {
+		return math.NaN()
+	}
+	c, _ := cur.Float64()
+	p, _ := prev.Float64()
+	return (c - p) / math.Abs(p)
+}
+
+// deviation returns the average of window and the distance of x
+// to it in standard deviations. If the window has no variance,
+// any difference counts as infinitely many deviations.
+func deviation(x *big.Rat, window []*big.Rat) (avg *big.Rat, dev float64) {
+	avg = new(big.Rat)
+	if len(window) == 0 {
+		return avg, 0
+	}
+	for _, w := range window {
+		avg.Add(avg, w)
+	}
+	avg.Quo(avg, big.NewRat(int64(len(window)), 1))
+	mean, _ := avg.Float64()
+	variance := 0.0
+	for _, w := range window {
+		v, _ := w.Float64()
+		variance += (v - mean) * (v - mean)
+	}
+	variance /= float64(len(window))
+	cur, _ := x.Float64()
+	switch {
+	case variance > 0:
+		return avg, (cur - mean) / math.Sqrt(variance)
+	case cur > mean:
+		return avg, math.Inf(1)
+	case cur < mean:
+		return avg, math.Inf(-1)
+	}
+	return avg, 0
+}
@@ -0,0 +1,64 @@
+package reports
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func TestSpending(t *testing.T) {
+	bank := &types.Account{Id: "bank", Name: "/Bank", Type: "BANK"}
+	food := &types.Account{Id: "food", Name: "/Expenses/Food", Type: "EXPENSE"}
+	rent := &types.Account{Id: "rent", Name: "/Expenses/Rent", Type: "EXPENSE"}
+	book := &types.Book{
+		Accounts:     map[types.GUID]*types.Account{"bank": bank, "food": food, "rent": rent},
+		Transactions: make(map[types.GUID]*types.Transaction),
+	}
+	add := func(date, desc string, acct *types.Account, amount int64) {
+		d, _ := time.Parse("2006-01-02", date)
+		id := types.NewGUID()
+		book.Transactions[id] = &types.Transaction{
+			Id: id, Date: d, Description: desc,
+			Flows: []types.Flow{
+				{Account: acct, Price: (*types.Amount)(big.NewRat(amount, 1))},
+				{Account: bank, Price: (*types.Amount)(big.NewRat(-amount, 1))},
+			},
+		}
+	}
+	for m := 1; m <= 12; m++ {
+		date := time.Date(2013, time.Month(m), 3, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+		add(date, "Landlord", rent, 500)
+		add(date, "Market  ", food, 100+int64(m%2)*20)
+	}
+	add("2013-12-20", "Restaurant", food, 300)
+	book.Recompute()
+
+	rep := Spending(book, Periods{
+		Period: Yearly,
+		Start:  time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC),
+	}, DefaultSpendingOptions)
+
+	if len(rep.Categories) != 2 || rep.Categories[0].Name != "/Expenses/Rent" {
+		t.Fatalf("unexpected category ranking %+v", rep.Categories)
+	}
+	if got := rep.Categories[0].Amount.RatString(); got != "6000" {
+		t.Errorf("rent total: got %s, expected 6000", got)
+	}
+	if len(rep.Payees) != 3 || rep.Payees[1].Name != "Market" {
+		t.Errorf("unexpected payee ranking %+v", rep.Payees)
+	}
+	for _, tr := range rep.Trends {
+		switch tr.Category {
+		case "/Expenses/Food":
+			if !tr.Flagged || tr.Current.RatString() != "400" {
+				t.Errorf("food trend: %+v", tr)
+			}
+		case "/Expenses/Rent":
+			if tr.Flagged || tr.MoM != 0 {
+				t.Errorf("rent trend: %+v", tr)
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package rules
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Classifier is a naive Bayes classifier guessing the counter-account
+// of a transaction from the words of its description, similar to the
+// Bayesian import matcher of Gnucash.
+type Classifier struct {
+	docs   map[*types.Account]int            // Transactions per account.
+	words  map[*types.Account]map[string]int // Word counts per account.
+	totals map[*types.Account]int            // Total word counts per account.
+	vocab  map[string]bool
+	ndocs  int
+}
+
+// Train returns a classifier learning from the categorized transactions
+// of acct in book, which must have been recomputed.
+func Train(book *types.Book, acct *types.Account) *Classifier {
+	c := &Classifier{
+		docs:   make(map[*types.Account]int),
+		words:  make(map[*types.Account]map[string]int),
+		totals: make(map[*types.Account]int),
+		vocab:  make(map[string]bool),
+	}
+	for _, f := range book.Flows[acct] {
+		counter := counterFlow(acct, f.Parent)
+		if counter == nil || IsUncategorized(counter.Account) {
+			continue
+		}
+		c.Add(f.Parent.Description, counter.Account)
+	}
+	return c
+}
+
+// Add records that a transaction with the given description was
+// categorized in counter.
+func (c *Classifier) Add(desc string, counter *types.Account) {
+	if c.words[counter] == nil {
+		c.words[counter] = make(map[string]int)
+	}
+	for _, w := range tokens(desc) {
+		c.words[counter][w]++
+		c.totals[counter]++
+		c.vocab[w] = true
+	}
+	c.docs[counter]++
+	c.ndocs++
+}
+
+// Classify returns the most probable counter-account for a description,
+// and its probability. It returns nil if nothing was learnt.
+func (c *Classifier) Classify(desc string) (*types.Account, float64) {
+	if c.ndocs == 0 {
+		return nil, 0
+	}
+	words := tokens(desc)
+	if len(words) == 0 {
+		return nil, 0
+	}
+	// Log-probabilities with Laplace smoothing.
+	logp := make(map[*types.Account]float64, len(c.docs))
+	for acct, n := range c.docs {
+		p := math.Log(float64(n) / float64(c.ndocs))
+		denom := float64(c.totals[acct] + len(c.vocab))
+		for _, w := range words {
+			p += math.Log(float64(c.words[acct][w]+1) / denom)
+		}
+		logp[acct] = p
+	}
+	var best *types.Account
+	for acct, p := range logp {
+		if best == nil || p > logp[best] || p == logp[best] && acct.Name < best.Name {
+			best = acct
+		}
+	}
+	sum := 0.0
+	for _, p := range logp {
+		sum += math.Exp(p - logp[best])
+	}
+	return best, 1 / sum
+}
+
+// Apply categorizes trn, a transaction imported in acct, if its single
+// counter flow is uncategorized and the classifier guesses an account
+// with probability at least minProb. It returns the chosen account.
+func (c *Classifier) Apply(acct *types.Account, trn *types.Transaction, minProb float64) *types.Account {
+	f := counterFlow(acct, trn)
+	if f == nil || !IsUncategorized(f.Account) {
+		return nil
+	}
+	guess, p := c.Classify(trn.Description)
+	if guess == nil || p < minProb {
+		return nil
+	}
+	f.Account = guess
+	return guess
+}
+
+// tokens returns the lowercase words of a description, ignoring
+// numbers and single letters.
+func tokens(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	out := words[:0]
+	for _, w := range words {
+		if len([]rune(w)) > 1 {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// Propose returns rules for descriptions of acct transactions that
+// were categorized at least minCount times, always in the same
+// account. Descriptions already handled by existing rules (which
+// may be nil) are skipped.
+func Propose(book *types.Book, acct *types.Account, existing *Set, minCount int) []*Rule {
+	type group struct {
+		sample   *types.Transaction
+		counters map[*types.Account]int
+		n        int
+	}
+	groups := make(map[string]*group)
+	for _, f := range book.Flows[acct] {
+		counter := counterFlow(acct, f.Parent)
+		if counter == nil || IsUncategorized(counter.Account) {
+			continue
+		}
+		key := strings.Join(tokens(f.Parent.Description), " ")
+		if key == "" {
+			continue
+		}
+		g := groups[key]
+		if g == nil {
+			g = &group{sample: f.Parent, counters: make(map[*types.Account]int)}
+			groups[key] = g
+		}
+		g.counters[counter.Account]++
+		g.n++
+	}
+	var rules []*Rule
+	counts := make(map[*Rule]int)
+	for key, g := range groups {
+		if g.n < minCount || len(g.counters) != 1 {
+			continue
+		}
+		if existing != nil && existing.Find(acct, g.sample) != nil {
+			continue
+		}
+		var counter *types.Account
+		for a := range g.counters {
+			counter = a
+		}
+		words := strings.Fields(key)
+		for i, w := range words {
+			words[i] = regexp.QuoteMeta(w)
+		}
+		r := &Rule{
+			Name:        key,
+			Description: `(?i)(?:^|\PL)` + strings.Join(words, `\PL+`) + `(?:\PL|$)`,
+			Account:     acct.Name,
+			Counter:     counter.Name,
+		}
+		counts[r] = g.n
+		rules = append(rules, r)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if counts[rules[i]] != counts[rules[j]] {
+			return counts[rules[i]] > counts[rules[j]]
+		}
+		return rules[i].Name < rules[j].Name
+	})
+	return rules
+}
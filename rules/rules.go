@@ -0,0 +1,224 @@
+// Package rules implements automatic categorization of imported
+// transactions, with rules stored in a file and a classifier learning
+// from existing transactions.
+package rules
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Rule assigns a counter-account to matching transactions. All
+// non-empty conditions must match.
+type Rule struct {
+	Name string
+
+	// Conditions.
+	Description string        `json:",omitempty"` // Regular expression on the description.
+	Memo        string        `json:",omitempty"` // Regular expression on notes and memos.
+	Payee       string        `json:",omitempty"` // Exact description, ignoring case.
+	Account     string        `json:",omitempty"` // Source account or one of its parents.
+	Min         *types.Amount `json:",omitempty"` // Minimum amount in the source account.
+	Max         *types.Amount `json:",omitempty"` // Maximum amount in the source account.
+
+	// Actions.
+	Counter        string // Name of the counter-account.
+	SetDescription string `json:",omitempty"` // New description, may refer to $1... of Description.
+	SetMemo        string `json:",omitempty"` // Memo of the counter flow.
+
+	description, memo *regexp.Regexp
+}
+
+// A Set is an ordered list of rules: the first matching rule applies.
+type Set struct {
+	Rules []*Rule
+}
+
+// Load reads a rule set from the named JSON file.
+func Load(name string) (*Set, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Set)
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid rules %s: %s", name, err)
+	}
+	return s, s.Check()
+}
+
+// Save writes the rule set to the named file.
+func (s *Set) Save(name string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, append(data, '\n'), 0644)
+}
+
+// Check compiles the regular expressions of rules and verifies that
+// they are consistent.
+func (s *Set) Check() error {
+	for i, r := range s.Rules {
+		if err := r.compile(); err != nil {
+			return fmt.Errorf("rule %d (%s): %s", i+1, r.Name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rule) compile() (err error) {
+	if r.Counter == "" {
+		return fmt.Errorf("missing counter-account")
+	}
+	r.description, r.memo = nil, nil
+	if r.Description != "" {
+		if r.description, err = regexp.Compile(r.Description); err != nil {
+			return err
+		}
+	}
+	if r.Memo != "" {
+		if r.memo, err = regexp.Compile(r.Memo); err != nil {
+			return err
+		}
+	}
+	if r.Min != nil && r.Max != nil && r.Min.Rat().Cmp(r.Max.Rat()) > 0 {
+		return fmt.Errorf("empty amount range %s..%s", r.Min, r.Max)
+	}
+	return nil
+}
+
+// Match reports whether the rule applies to trn, imported in acct.
+func (r *Rule) Match(acct *types.Account, trn *types.Transaction) bool {
+	if r.description == nil && r.Description != "" || r.memo == nil && r.Memo != "" {
+		if r.compile() != nil {
+			return false
+		}
+	}
+	if r.description != nil && !r.description.MatchString(trn.Description) {
+		return false
+	}
+	if r.memo != nil && !r.matchMemo(trn) {
+		return false
+	}
+	if r.Payee != "" && !strings.EqualFold(strings.TrimSpace(trn.Description), r.Payee) {
+		return false
+	}
+	if r.Account != "" && acct.Name != r.Account && !strings.HasPrefix(acct.Name, r.Account+"/") {
+		return false
+	}
+	if r.Min != nil || r.Max != nil {
+		amount := amountIn(acct, trn)
+		if r.Min != nil && amount.Rat().Cmp(r.Min.Rat()) < 0 {
+			return false
+		}
+		if r.Max != nil && amount.Rat().Cmp(r.Max.Rat()) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *Rule) matchMemo(trn *types.Transaction) bool {
+	if r.memo.MatchString(trn.Notes) {
+		return true
+	}
+	for _, f := range trn.Flows {
+		if f.Memo != "" && r.memo.MatchString(f.Memo) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the first rule matching trn, or nil.
+func (s *Set) Find(acct *types.Account, trn *types.Transaction) *Rule {
+	for _, r := range s.Rules {
+		if r.Match(acct, trn) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Apply categorizes trn, a transaction imported in acct, with the first
+// matching rule, creating the counter-account in book if necessary.
+// Only transactions with a single uncategorized counter flow are
+// modified. It returns the applied rule, or nil.
+func (s *Set) Apply(book *types.Book, acct *types.Account, trn *types.Transaction) *Rule {
+	f := counterFlow(acct, trn)
+	if f == nil || !IsUncategorized(f.Account) {
+		return nil
+	}
+	r := s.Find(acct, trn)
+	if r == nil {
+		return nil
+	}
+	f.Account = book.EnsureAccount(r.Counter, accountType(r.Counter), acct.Unit)
+	if r.SetDescription != "" {
+		if r.description != nil {
+			m := r.description.FindStringSubmatchIndex(trn.Description)
+			trn.Description = string(r.description.ExpandString(nil, r.SetDescription, trn.Description, m))
+		} else {
+			trn.Description = r.SetDescription
+		}
+	}
+	if r.SetMemo != "" {
+		f.Memo = r.SetMemo
+	}
+	return r
+}
+
+// counterFlow returns the single flow of trn outside acct.
+func counterFlow(acct *types.Account, trn *types.Transaction) *types.Flow {
+	var counter *types.Flow
+	for i := range trn.Flows {
+		if f := &trn.Flows[i]; f.Account != acct {
+			if counter != nil {
+				return nil
+			}
+			counter = f
+		}
+	}
+	return counter
+}
+
+// amountIn returns the total of flows of trn in acct.
+func amountIn(acct *types.Account, trn *types.Transaction) *types.Amount {
+	sum := new(types.Amount)
+	for i := range trn.Flows {
+		if f := &trn.Flows[i]; f.Account == acct {
+			sum.Add(f.Units())
+		}
+	}
+	return sum
+}
+
+// accountType guesses the type of a new account from its top-level
+// parent.
+func accountType(name string) string {
+	top := strings.SplitN(strings.TrimPrefix(name, "/"), "/", 2)[0]
+	switch top {
+	case "Expenses":
+		return "EXPENSE"
+	case "Income":
+		return "INCOME"
+	case "Liabilities":
+		return "LIABILITY"
+	case "Equity":
+		return "EQUITY"
+	}
+	return "ASSET"
+}
+
+// IsUncategorized reports whether acct is a placeholder for
+// uncategorized flows, such as Imbalance-USD or Orphan-USD.
+func IsUncategorized(acct *types.Account) bool {
+	base := acct.Name[strings.LastIndex(acct.Name, "/")+1:]
+	return strings.HasPrefix(base, "Imbalance-") || strings.HasPrefix(base, "Orphan-")
+}
@@ -0,0 +1,157 @@
+package rules
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+func newBook() (*types.Book, *types.Account, *types.Account) {
+	book := &types.Book{Transactions: make(map[types.GUID]*types.Transaction)}
+	bank := book.EnsureAccount("/Assets/Bank", "BANK", "EUR")
+	imb := book.EnsureAccount("/Imbalance-EUR", "BANK", "EUR")
+	return book, bank, imb
+}
+
+func newTransaction(bank, counter *types.Account, desc, notes, amount string) *types.Transaction {
+	x, _ := new(big.Rat).SetString(amount)
+	trn := &types.Transaction{
+		Id:          types.NewGUID(),
+		Date:        time.Date(2013, 3, 1, 0, 0, 0, 0, time.UTC),
+		Description: desc,
+		Notes:       notes,
+	}
+	trn.Flows = []types.Flow{
+		{Account: bank, Price: (*types.Amount)(new(big.Rat).Set(x))},
+		{Account: counter, Price: (*types.Amount)(new(big.Rat).Neg(x))},
+	}
+	return trn
+}
+
+func TestApply(t *testing.T) {
+	rules, err := Load("testdata/rules.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book, bank, imb := newBook()
+	for _, c := range []struct {
+		desc, notes, amount string
+		rule                string
+		counter, newDesc    string
+	}{
+		{"CARTE 12/03 SUPERMARCHE", "", "-23.10", "Card payments", "/Expenses/Groceries", "SUPERMARCHE"},
+		{"acme gmbh", "", "1500", "Salary", "/Income/Salary", "acme gmbh"},
+		{"ACME GmbH", "", "-10", "", "/Imbalance-EUR", "ACME GmbH"},
+		{"Bank", "Account fee 03/2013", "-4.50", "Small bank fees", "/Expenses/Bank", "Bank"},
+		{"Bank", "Account fee 03/2013", "-40", "", "/Imbalance-EUR", "Bank"},
+	} {
+		trn := newTransaction(bank, imb, c.desc, c.notes, c.amount)
+		r := rules.Apply(book, bank, trn)
+		name := ""
+		if r != nil {
+			name = r.Name
+		}
+		if name != c.rule || trn.Flows[1].Account.Name != c.counter || trn.Description != c.newDesc {
+			t.Errorf("%s %s: got rule %q, counter %s, description %q", c.desc, c.amount,
+				name, trn.Flows[1].Account.Name, trn.Description)
+		}
+	}
+	if a := book.AccountByName("/Income/Salary"); a == nil || a.Type != "INCOME" {
+		t.Errorf("counter-account not created: %+v", a)
+	}
+
+	// Categorized transactions are left alone.
+	food := book.EnsureAccount("/Expenses/Food", "EXPENSE", "EUR")
+	trn := newTransaction(bank, food, "CARTE 12/03 BAKERY", "", "-3")
+	if r := rules.Apply(book, bank, trn); r != nil || trn.Flows[1].Account != food {
+		t.Errorf("categorized transaction modified by rule %v", r)
+	}
+
+	bad := &Set{Rules: []*Rule{{Name: "bad", Description: "(", Counter: "/Expenses"}}}
+	if err := bad.Check(); err == nil {
+		t.Errorf("expected error for invalid regexp")
+	}
+}
+
+func TestLearn(t *testing.T) {
+	book, bank, imb := newBook()
+	food := book.EnsureAccount("/Expenses/Food", "EXPENSE", "EUR")
+	fuel := book.EnsureAccount("/Expenses/Fuel", "EXPENSE", "EUR")
+	for _, x := range []struct {
+		counter *types.Account
+		desc    string
+	}{
+		{food, "CARTE 01/02 BOULANGERIE PAUL"},
+		{food, "CARTE 03/02 BOULANGERIE PAUL"},
+		{food, "CARTE 05/02 BOULANGERIE PAUL"},
+		{food, "CARTE 07/02 SUPERMARCHE CASINO"},
+		{fuel, "CARTE 02/02 STATION TOTAL"},
+		{fuel, "CARTE 09/02 STATION TOTAL"},
+		{fuel, "CARTE 19/02 STATION TOTAL"},
+		{imb, "CARTE 20/02 UNKNOWN"},
+	} {
+		trn := newTransaction(bank, x.counter, x.desc, "", "-10")
+		book.Transactions[trn.Id] = trn
+	}
+	book.Recompute()
+
+	c := Train(book, bank)
+	if acct, p := c.Classify("CARTE 10/03 BOULANGERIE"); acct != food || p < 0.8 {
+		t.Errorf("Classify(BOULANGERIE) = %v, %.2f", acct, p)
+	}
+	if acct, p := c.Classify("CARTE 11/03 TOTAL ACCESS"); acct != fuel || p < 0.8 {
+		t.Errorf("Classify(TOTAL) = %v, %.2f", acct, p)
+	}
+	trn := newTransaction(bank, imb, "CARTE 12/03 STATION TOTAL", "", "-50")
+	if a := c.Apply(bank, trn, 0.9); a != fuel || trn.Flows[1].Account != fuel {
+		t.Errorf("Apply did not categorize: %v", a)
+	}
+	trn = newTransaction(bank, imb, "CARTE 12/03 NEW SHOP", "", "-50")
+	if a := c.Apply(bank, trn, 0.9); a != nil {
+		t.Errorf("Apply categorized unknown description in %s", a.Name)
+	}
+
+	props := Propose(book, bank, nil, 3)
+	if len(props) != 2 || props[0].Name != "carte boulangerie paul" || props[0].Counter != "/Expenses/Food" ||
+		props[1].Counter != "/Expenses/Fuel" {
+		t.Fatalf("unexpected proposals %+v", props)
+	}
+	set := &Set{Rules: props}
+	if err := set.Check(); err != nil {
+		t.Fatal(err)
+	}
+	trn = newTransaction(bank, imb, "CARTE 14/03 BOULANGERIE PAUL", "", "-4")
+	if r := set.Apply(book, bank, trn); r != props[0] {
+		t.Errorf("proposed rule does not apply: %v", r)
+	}
+	if props := Propose(book, bank, set, 3); len(props) != 0 {
+		t.Errorf("rules proposed twice: %+v", props)
+	}
+}
+
+func TestProposeUnicode(t *testing.T) {
+	book, bank, imb := newBook()
+	power := book.EnsureAccount("/Expenses/Power", "EXPENSE", "EUR")
+	for _, desc := range []string{"PRLV EDF ÉLECTRICITÉ", "PRLV EDF ÉLECTRICITÉ", "PRLV EDF ÉLECTRICITÉ"} {
+		trn := newTransaction(bank, power, desc, "", "-60")
+		book.Transactions[trn.Id] = trn
+	}
+	book.Recompute()
+
+	props := Propose(book, bank, nil, 3)
+	if len(props) != 1 || props[0].Counter != "/Expenses/Power" {
+		t.Fatalf("unexpected proposals %+v", props)
+	}
+	set := &Set{Rules: props}
+	if err := set.Check(); err != nil {
+		t.Fatal(err)
+	}
+	for _, desc := range []string{"PRLV EDF Électricité", "ÉCHÉANCE PRLV EDF ÉLECTRICITÉ 03/13"} {
+		trn := newTransaction(bank, imb, desc, "", "-60")
+		if r := set.Apply(book, bank, trn); r != props[0] {
+			t.Errorf("proposed rule does not apply to %q: %v", desc, r)
+		}
+	}
+}
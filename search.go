@@ -0,0 +1,52 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/remyoudompheng/gocash/register"
+	"github.com/remyoudompheng/gocash/search"
+)
+
+func cmdSearch(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	filename := flags.String("f", "", "path to GNucash XML file")
+	format := flags.String("format", "text", "output format: text or csv")
+	output := flags.String("o", "", "output file (default: standard output)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gocash search [flags] query...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if *format != "text" && *format != "csv" {
+		log.Fatalf("ERROR: unknown format %q", *format)
+	}
+	q, err := search.Parse(strings.Join(flags.Args(), " "))
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+
+	book := loadBook(*filename)
+	flows := search.Search(book, q)
+	out := createOutput(*output)
+	if *format == "csv" {
+		err = register.WriteCSV(out, register.FlowLines(book, flows))
+	} else {
+		for _, f := range flows {
+			_, err = fmt.Fprintf(out, "%s %-6s %12s %s  %-30s %s\n", f.Parent.Date.Format("2006-01-02"),
+				f.Parent.Number, f.Units(), f.State, f.Parent.Description, f.Account.Name)
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = out.Close()
+	}
+	if err != nil {
+		log.Fatalf("ERROR: %s", err)
+	}
+}
@@ -0,0 +1,405 @@
+// Package search finds the flows of a book matching a query.
+//
+// A query is a list of terms separated by spaces, which must all
+// match. A term is either text, which matches words of the
+// description, notes or memo, or a field:value filter:
+//
+//	bakery                  words starting with "bakery"
+//	"weekly shopping"       consecutive words
+//	desc:acme               text of the description only (also memo:, notes:)
+//	amount:-12.50           amount in the account commodity (also >10, <=0, 10..20)
+//	date:2013-03            dates in a year, month or day (also >=2013, 2013-01..2013-03)
+//	account:/Expenses/**    account names, where * does not match "/" and ** does
+//	state:nc                reconcile states (n, c, y, f, v)
+//	number:101              transaction number
+//
+// Values containing spaces are quoted, and a leading "-" negates
+// a term, as in -account:"/Assets/Current Account".
+package search
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/remyoudompheng/gocash/types"
+)
+
+// A Query is a parsed search query.
+type Query struct {
+	terms []term
+}
+
+type term struct {
+	neg   bool
+	words []string // Words of a text term.
+	match func(f *types.Flow) bool
+}
+
+// Parse parses a query.
+func Parse(s string) (*Query, error) {
+	toks, err := split(s)
+	if err != nil {
+		return nil, err
+	}
+	q := new(Query)
+	for _, tok := range toks {
+		t, err := parseTerm(tok)
+		if err != nil {
+			return nil, err
+		}
+		q.terms = append(q.terms, t)
+	}
+	return q, nil
+}
+
+// A token is a term of a query before it is parsed.
+type token struct {
+	neg    bool
+	field  string
+	value  string
+	quoted bool
+}
+
+// split splits a query into tokens.
+func split(s string) ([]token, error) {
+	var toks []token
+	for {
+		s = strings.TrimLeft(s, " \t\n")
+		if s == "" {
+			return toks, nil
+		}
+		var tok token
+		if s[0] == '-' {
+			tok.neg, s = true, s[1:]
+		}
+		if s != "" && s[0] != '"' {
+			if i := strings.IndexAny(s, ": \t\n\""); i > 0 && s[i] == ':' {
+				if field := strings.ToLower(s[:i]); fields[field] != nil {
+					tok.field, s = field, s[i+1:]
+				}
+			}
+		}
+		if s != "" && s[0] == '"' {
+			i := strings.IndexByte(s[1:], '"')
+			if i < 0 {
+				return nil, fmt.Errorf("unterminated quoted text %s", s)
+			}
+			tok.value, tok.quoted, s = s[1:i+1], true, s[i+2:]
+		} else {
+			i := strings.IndexAny(s, " \t\n")
+			if i < 0 {
+				i = len(s)
+			}
+			tok.value, s = s[:i], s[i:]
+		}
+		if tok.value == "" && !tok.quoted {
+			return nil, fmt.Errorf("missing value in search term")
+		}
+		toks = append(toks, tok)
+	}
+}
+
+// fields are the parsers of field:value terms.
+var fields = map[string]func(value string) (func(f *types.Flow) bool, error){
+	"desc":    textField(func(f *types.Flow) string { return f.Parent.Description }),
+	"memo":    textField(func(f *types.Flow) string { return f.Memo }),
+	"notes":   textField(func(f *types.Flow) string { return f.Parent.Notes }),
+	"amount":  parseAmount,
+	"date":    parseDate,
+	"account": parseAccount,
+	"state":   parseState,
+	"number": func(value string) (func(f *types.Flow) bool, error) {
+		return func(f *types.Flow) bool { return f.Parent.Number == value }, nil
+	},
+}
+
+func parseTerm(tok token) (term, error) {
+	t := term{neg: tok.neg}
+	var err error
+	switch tok.field {
+	case "", "desc", "memo", "notes":
+		t.words = types.Words(tok.value)
+		if len(t.words) == 0 {
+			return t, fmt.Errorf("no words in search term %q", tok.value)
+		}
+		if tok.field == "" {
+			t.match = func(f *types.Flow) bool { return hasWords(types.FlowText(f), t.words) }
+			return t, nil
+		}
+	}
+	t.match, err = fields[tok.field](tok.value)
+	if err != nil {
+		return t, fmt.Errorf("invalid %s: %s", tok.field, err)
+	}
+	return t, nil
+}
+
+func textField(text func(f *types.Flow) string) func(string) (func(f *types.Flow) bool, error) {
+	return func(value string) (func(f *types.Flow) bool, error) {
+		words := types.Words(value)
+		return func(f *types.Flow) bool { return hasWords(text(f), words) }, nil
+	}
+}
+
+// hasWords reports whether text has the given consecutive words,
+// the last one being possibly a prefix.
+func hasWords(text string, words []string) bool {
+	tw := types.Words(text)
+	last := len(words) - 1
+	for i := 0; i+last < len(tw); i++ {
+		ok := strings.HasPrefix(tw[i+last], words[last])
+		for j := 0; ok && j < last; j++ {
+			ok = tw[i+j] == words[j]
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAmount parses an exact amount, a comparison such as >=10
+// or an inclusive range such as 10..20.
+func parseAmount(value string) (func(f *types.Flow) bool, error) {
+	rat := func(s string) (*big.Rat, error) {
+		x, ok := new(big.Rat).SetString(s)
+		if !ok {
+			return nil, fmt.Errorf("not a number: %q", s)
+		}
+		return x, nil
+	}
+	if i := strings.Index(value, ".."); i >= 0 {
+		lo, err := rat(value[:i])
+		if err != nil {
+			return nil, err
+		}
+		hi, err := rat(value[i+2:])
+		if err != nil {
+			return nil, err
+		}
+		return func(f *types.Flow) bool {
+			x := f.Units().Rat()
+			return x.Cmp(lo) >= 0 && x.Cmp(hi) <= 0
+		}, nil
+	}
+	op, value := comparison(value)
+	y, err := rat(value)
+	if err != nil {
+		return nil, err
+	}
+	return func(f *types.Flow) bool { return compare(op, f.Units().Rat().Cmp(y)) }, nil
+}
+
+// comparison splits a leading comparison operator from value.
+// The operator is "=" if there is none.
+func comparison(value string) (op, rest string) {
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			return op, value[len(op):]
+		}
+	}
+	return "=", value
+}
+
+// compare reports whether the result cmp of a comparison satisfies op.
+func compare(op string, cmp int) bool {
+	switch op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case "<":
+		return cmp < 0
+	}
+	return cmp == 0
+}
+
+// parseDate parses a period, a comparison with a period such as
+// <2013-04, or an inclusive range of periods such as 2013-01..2013-03,
+// where either end may be omitted.
+func parseDate(value string) (func(f *types.Flow) bool, error) {
+	var start, end time.Time // Selected dates are in [start, end).
+	if i := strings.Index(value, ".."); i >= 0 {
+		if value[:i] != "" {
+			lo, _, err := period(value[:i])
+			if err != nil {
+				return nil, err
+			}
+			start = lo
+		}
+		if value[i+2:] != "" {
+			_, hi, err := period(value[i+2:])
+			if err != nil {
+				return nil, err
+			}
+			end = hi
+		}
+	} else {
+		op, value := comparison(value)
+		lo, hi, err := period(value)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case ">=":
+			start = lo
+		case ">":
+			start = hi
+		case "<=":
+			end = hi
+		case "<":
+			end = lo
+		default:
+			start, end = lo, hi
+		}
+	}
+	return func(f *types.Flow) bool {
+		// Compare calendar dates: Gnucash dates carry the zone of their author.
+		y, m, day := f.Parent.Date.Date()
+		d := time.Date(y, m, day, 0, 0, 0, 0, time.UTC)
+		return (start.IsZero() || !d.Before(start)) && (end.IsZero() || d.Before(end))
+	}, nil
+}
+
+// period parses a year, month or day and returns its first day
+// and the first day after it.
+func period(s string) (start, end time.Time, err error) {
+	for _, p := range []struct {
+		layout string
+		years  int
+		months int
+		days   int
+	}{
+		{"2006", 1, 0, 0},
+		{"2006-01", 0, 1, 0},
+		{"2006-01-02", 0, 0, 1},
+	} {
+		if len(s) == len(p.layout) {
+			start, err = time.Parse(p.layout, s)
+			if err != nil {
+				return start, end, err
+			}
+			return start, start.AddDate(p.years, p.months, p.days), nil
+		}
+	}
+	return start, end, fmt.Errorf("expected YYYY, YYYY-MM or YYYY-MM-DD, got %q", s)
+}
+
+// parseAccount parses a case-insensitive glob pattern of account
+// names, where * matches within a name component and ** matches
+// across components. A leading "/" is implied.
+func parseAccount(value string) (func(f *types.Flow) bool, error) {
+	if !strings.HasPrefix(value, "/") && !strings.HasPrefix(value, "*") {
+		value = "/" + value
+	}
+	var expr strings.Builder
+	expr.WriteString("(?i)^")
+	for i := 0; i < len(value); i++ {
+		switch {
+		case strings.HasPrefix(value[i:], "**"):
+			expr.WriteString(".*")
+			i++
+		case value[i] == '*':
+			expr.WriteString("[^/]*")
+		case value[i] == '?':
+			expr.WriteString("[^/]")
+		default:
+			expr.WriteString(regexp.QuoteMeta(value[i : i+1]))
+		}
+	}
+	expr.WriteString("$")
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return nil, err
+	}
+	return func(f *types.Flow) bool { return f.Account != nil && re.MatchString(f.Account.Name) }, nil
+}
+
+// parseState parses a list of reconcile state letters.
+func parseState(value string) (func(f *types.Flow) bool, error) {
+	var states []types.ReconcileState
+	for _, c := range value {
+		s, err := types.ParseReconcileState(string(c))
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, s)
+	}
+	return func(f *types.Flow) bool {
+		for _, s := range states {
+			if f.State == s {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+// Match reports whether f matches all the terms of q.
+func (q *Query) Match(f *types.Flow) bool {
+	for _, t := range q.terms {
+		if t.match(f) == t.neg {
+			return false
+		}
+	}
+	return true
+}
+
+// Search returns the flows of the book matching q, sorted by date.
+// The book must have been recomputed.
+func Search(book *types.Book, q *Query) []*types.Flow {
+	var results []*types.Flow
+	for _, f := range q.candidates(book) {
+		if q.Match(f) {
+			results = append(results, f)
+		}
+	}
+	sort.Stable(byDate(results))
+	return results
+}
+
+// candidates returns the flows that may match q, using the book
+// index for the words of text terms.
+func (q *Query) candidates(book *types.Book) []*types.Flow {
+	var best []*types.Flow
+	found := false
+	for _, t := range q.terms {
+		if t.neg {
+			continue
+		}
+		for _, w := range t.words {
+			if flows := book.Index.Lookup(w); !found || len(flows) < len(best) {
+				best, found = flows, true
+			}
+		}
+	}
+	if found {
+		return best
+	}
+	var all []*types.Flow
+	for _, flows := range book.Flows {
+		all = append(all, flows...)
+	}
+	return all
+}
+
+type byDate []*types.Flow
+
+func (s byDate) Len() int      { return len(s) }
+func (s byDate) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDate) Less(i, j int) bool {
+	a, b := s[i], s[j]
+	if !a.Parent.Date.Equal(b.Parent.Date) {
+		return a.Parent.Date.Before(b.Parent.Date)
+	}
+	if a.Parent.Id != b.Parent.Id {
+		return a.Parent.Id < b.Parent.Id
+	}
+	return a.Account.Name < b.Account.Name
+}
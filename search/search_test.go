@@ -0,0 +1,133 @@
+package search
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/remyoudompheng/gocash/ledger"
+	"github.com/remyoudompheng/gocash/register"
+	"github.com/remyoudompheng/gocash/types"
+	"github.com/remyoudompheng/gocash/xmlimport"
+)
+
+func testBook(t *testing.T) *types.Book {
+	book, err := ledger.ReadFile("testdata/search.journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	return book
+}
+
+func TestSearch(t *testing.T) {
+	book := testBook(t)
+	for _, c := range []struct {
+		query    string
+		expected string
+	}{
+		{"bak", "Bakery /Assets/Current Account, Bakery /Expenses/Food/Bread"},
+		{"weekly", "Supermarket /Assets/Current Account, Bakery /Expenses/Food/Bread"},
+		{`"weekly shop"`, "Supermarket /Assets/Current Account"},
+		{`"shopping weekly"`, ""},
+		{"memo:bread", "Bakery /Expenses/Food/Bread"},
+		{"desc:march", ""},
+		{"notes:march", "Payroll /Assets/Current Account, Payroll /Income/Salary"},
+		{"amount:>1000", "Payroll /Assets/Current Account"},
+		{"amount:<=-42.50", "Payroll /Income/Salary, Supermarket /Assets/Current Account, Buy ACME /Assets/Current Account"},
+		{"amount:3..50", "Supermarket /Expenses/Food, Buy ACME /Assets/Broker/ACME, Bakery /Expenses/Food/Bread"},
+		{"amount:3.125", "Buy ACME /Assets/Broker/ACME"},
+		{"date:2013-04 account:/expenses/**", "Bakery /Expenses/Food/Bread"},
+		{"date:2013-03-02..2013-03 account:Expenses/*", "Supermarket /Expenses/Food"},
+		{"account:/Assets/*", "Payroll /Assets/Current Account, Supermarket /Assets/Current Account, " +
+			"Buy ACME /Assets/Current Account, Bakery /Assets/Current Account"},
+		{"date:<2013-03-05 -account:/Income/*", "Payroll /Assets/Current Account"},
+		{`account:"/Assets/Current Account" state:cy`, "Payroll /Assets/Current Account, Supermarket /Assets/Current Account"},
+		{"-state:n", "Payroll /Assets/Current Account, Payroll /Income/Salary, Supermarket /Assets/Current Account, Supermarket /Expenses/Food"},
+		{"number:102 acme", "Buy ACME /Assets/Broker/ACME, Buy ACME /Assets/Current Account"},
+		{"date:>2013", ""},
+	} {
+		q, err := Parse(c.query)
+		if err != nil {
+			t.Errorf("%s: %s", c.query, err)
+			continue
+		}
+		var found []string
+		for _, f := range Search(book, q) {
+			found = append(found, f.Parent.Description+" "+f.Account.Name)
+		}
+		if s := strings.Join(found, ", "); s != c.expected {
+			t.Errorf("%s: got %q, expected %q", c.query, s, c.expected)
+		}
+	}
+}
+
+func TestSearchGnucash(t *testing.T) {
+	// Gnucash dates are in the zone of their author, such as
+	// 1997-09-11 21:00:00 -0700, which is the next day in UTC.
+	book, err := xmlimport.ImportFile("../xmlimport/testdata/abc.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	for _, c := range []struct {
+		query    string
+		expected string
+	}{
+		{"date:1997-09-11", "move a pile of money to trading acct"},
+		{"date:1997-09-12", ""},
+		{"date:1997-07", "paycheck, put in more money"},
+		{"date:<1997-07-31", "from my pillow case"},
+		{"date:1997-11-11..", "hal stock"},
+	} {
+		q, err := Parse(c.query)
+		if err != nil {
+			t.Errorf("%s: %s", c.query, err)
+			continue
+		}
+		var found []string
+		for _, f := range Search(book, q) {
+			if len(found) == 0 || found[len(found)-1] != f.Parent.Description {
+				found = append(found, f.Parent.Description)
+			}
+		}
+		if s := strings.Join(found, ", "); s != c.expected {
+			t.Errorf("%s: got %q, expected %q", c.query, s, c.expected)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		`"unterminated`,
+		"amount:abc",
+		"amount:1..x",
+		"date:2013-3",
+		"date:2013-13",
+		"state:z",
+		"account:",
+		"-",
+		"!!",
+	} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	book := testBook(t)
+	q, _ := Parse("number:102")
+	var buf bytes.Buffer
+	if err := register.WriteCSV(&buf, register.FlowLines(book, Search(book, q))); err != nil {
+		t.Fatal(err)
+	}
+	// Quantities of shares are exact.
+	expected := `Date,Number,Description,Account,Memo,Transfer,Amount,Balance,Reconciled
+2013-03-20,102,Buy ACME,/Assets/Broker/ACME,,/Assets/Current Account,3.125,3.125,n
+2013-03-20,102,Buy ACME,/Assets/Current Account,,/Assets/Broker/ACME,-250.00,1207.50,n
+`
+	if s := buf.String(); s != expected {
+		t.Errorf("got:\n%s\nexpected:\n%s", s, expected)
+	}
+}
@@ -4,10 +4,30 @@
 {{ define "body" }}
 <h1>Account {{ .Account.Name }}</h1>
 
+<p>
+    <a class="btn btn-default" href="/account/edit?name={{ .Account.Name }}">Edit account</a>
+    <a class="btn btn-default" href="/account/edit?parent={{ .Account.Name }}">New subaccount</a>
+    <a class="btn btn-default" href="/account/reconcile?name={{ .Account.Name }}">Reconcile</a>
+</p>
+{{ if .Account.Description }}<p>{{ .Account.Description }}</p>{{ end }}
+
 <p>Current balance: {{ index .Book.Balance .Account }} {{ .Account.Unit }}</p>
 
+{{ chart (index .Book.Flows .Account) }}
+
 <h2>Transactions</h2>
 
+<p><a class="btn btn-default" href="/transaction/?back={{ .Account.Name }}">New transaction</a></p>
+
+<form class="form-inline" method="get" action="/account/export">
+    <input type="hidden" name="name" value="{{ .Account.Name }}">
+    <input class="form-control" type="date" name="from" placeholder="from">
+    <input class="form-control" type="date" name="to" placeholder="to">
+    <input class="form-control" type="text" name="text" placeholder="description or memo">
+    <button class="btn btn-default" type="submit" name="format" value="csv">Download CSV</button>
+    <button class="btn btn-default" type="submit" name="format" value="xlsx">Download XLSX</button>
+</form>
+
 <table class="table">
     {{ $flows := index .Book.Flows .Account }}
     {{ $balance := cumul $flows }}
@@ -16,18 +36,22 @@
         <th>Date</th>
         <th>Description</th>
         <th>Memo</th>
+        <th title="Reconciliation state">R</th>
         <th>Amount</th>
         <th>Balance</th>
+        <th></th>
     </tr>
     </thead>
     <tbody>
     {{ range $i, $flow := $flows }}
-    <tr>
+    <tr{{ if $flow.Parent.IsVoid }} class="voided" title="Voided: {{ $flow.Parent.VoidReason }}"{{ end }}>
         <td>{{ $flow.Parent.Date.Format "2006-01-02" }}</td>
         <td>{{ $flow.Parent.Description }}</td>
         <td>{{ $flow.Memo }}</td>
+        <td>{{ $flow.State }}</td>
         <td class="amount">{{ $flow.Price }}</td>
         <td class="amount">{{ index $balance $i }} {{ .Account.Unit}}</td>
+        <td><a href="/transaction/?id={{ $flow.Parent.Id }}&back={{ $.Account.Name }}">Edit</a></td>
     </tr>
     {{ end }}
     </tbody>
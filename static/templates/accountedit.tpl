@@ -0,0 +1,93 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+{{ $form := .AccountForm }}
+<h1>{{ .Title }}</h1>
+
+{{ if $form.Error }}
+<div class="alert alert-danger">{{ $form.Error }}</div>
+{{ end }}
+
+<form class="form-horizontal" method="post" action="/account/save">
+    <input type="hidden" name="id" value="{{ $form.Id }}">
+    <div class="form-group">
+        <label class="col-sm-2 control-label">Name</label>
+        <div class="col-sm-6">
+            <input class="form-control" type="text" name="name" value="{{ $form.Name }}" required>
+        </div>
+    </div>
+    <div class="form-group">
+        <label class="col-sm-2 control-label">Parent</label>
+        <div class="col-sm-6">
+            <select class="form-control" name="parent">
+                <option value="">(top level)</option>
+                {{ range $form.Parents }}
+                <option{{ if eq . $form.Parent }} selected{{ end }}>{{ . }}</option>
+                {{ end }}
+            </select>
+        </div>
+    </div>
+    <div class="form-group">
+        <label class="col-sm-2 control-label">Type</label>
+        <div class="col-sm-6">
+            <select class="form-control" name="type">
+                {{ range accountTypes }}
+                <option{{ if eq . $form.Type }} selected{{ end }}>{{ . }}</option>
+                {{ end }}
+            </select>
+        </div>
+    </div>
+    <div class="form-group">
+        <label class="col-sm-2 control-label">Commodity</label>
+        <div class="col-sm-6">
+            <input class="form-control" type="text" name="unit" value="{{ $form.Unit }}" list="commodities">
+            <datalist id="commodities">
+                {{ range $form.Commodities }}<option value="{{ . }}">{{ end }}
+            </datalist>
+        </div>
+    </div>
+    <div class="form-group">
+        <label class="col-sm-2 control-label">Description</label>
+        <div class="col-sm-6">
+            <input class="form-control" type="text" name="description" value="{{ $form.Description }}">
+        </div>
+    </div>
+    <div class="form-group">
+        <div class="col-sm-offset-2 col-sm-6">
+            <label class="checkbox-inline">
+                <input type="checkbox" name="placeholder" value="1"{{ if $form.Placeholder }} checked{{ end }}> Placeholder
+            </label>
+            <label class="checkbox-inline">
+                <input type="checkbox" name="hidden" value="1"{{ if $form.Hidden }} checked{{ end }}> Hidden
+            </label>
+        </div>
+    </div>
+    <div class="form-group">
+        <div class="col-sm-offset-2 col-sm-6">
+            <button class="btn btn-primary" type="submit">Save</button>
+        </div>
+    </div>
+</form>
+
+{{ if .Account }}
+<h2>Delete account</h2>
+
+{{ if .Account.Children }}
+<p>This account has subaccounts, which must be moved or deleted first.</p>
+{{ else }}
+<form class="form-inline" method="post" action="/account/delete">
+    <input type="hidden" name="name" value="{{ .Account.Name }}">
+    {{ if $form.Flows }}
+    <label>Move its {{ $form.Flows }} flows to</label>
+    <select class="form-control" name="reassign">
+        {{ range $form.Targets }}
+        <option>{{ . }}</option>
+        {{ end }}
+    </select>
+    {{ end }}
+    <button class="btn btn-danger" type="submit" onclick="return confirm('Delete this account?')">Delete</button>
+</form>
+{{ end }}
+{{ end }}
+{{ end }}
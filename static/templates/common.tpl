@@ -5,22 +5,72 @@
         <title>{{ .Title }}</title>
         <link type="text/css" rel="stylesheet" href="/libs/bootstrap/css/bootstrap.min.css">
         <link type="text/css" rel="stylesheet" href="/static/gocash.css">
-        <script src="/libs/jquery.min.js">
-        <script src="/libs/bootstrap/js/bootstrap.min.js">
+        <script src="/libs/jquery.min.js"></script>
+        <script src="/libs/bootstrap/js/bootstrap.min.js"></script>
         <script type="text/javascript">
             {{ template "script" . }}
         </script>
+        <script type="text/javascript">
+            // Refresh the page when the book file is reloaded, unless
+            // it has a form that may be being edited.
+            $(function() {
+                if (!window.EventSource) {
+                    return;
+                }
+                var events = new EventSource("/events");
+                events.addEventListener("reload", function() {
+                    var editor = $("form[method=post]").find("input[type=text], textarea, select");
+                    if (editor.length == 0) {
+                        location.reload();
+                    } else {
+                        $("#reloaded").show();
+                    }
+                });
+            });
+        </script>
     </head>
     <body>
         <nav class="navbar navbar-default" role="navigation">
             <div class="navbar-header">
-                <a class="navbar-brand">Gocash</a>
+                <a class="navbar-brand" href="/">Gocash</a>
             </div>
+            <ul class="nav navbar-nav">
+                <li><a href="/networth/">Net worth</a></li>
+                <li><a href="/expenses/">Expenses</a></li>
+                <li><a href="/spending/">Spending</a></li>
+                <li><a href="/portfolio/">Portfolio</a></li>
+                <li><a href="/transaction/">New transaction</a></li>
+                <li><a href="/query/">Query</a></li>
+                <li><a href="/history/">History</a></li>
+            </ul>
+            <form class="navbar-form navbar-right" method="get" action="/search/">
+                <input class="form-control" type="text" name="q" placeholder="Search transactions">
+            </form>
         </nav>
         <div class="container">
+        <div id="reloaded" class="alert alert-warning" style="display: none">
+            The book file has changed. <a href="">Reload the page</a> to see the new version: unsaved changes will be lost.
+        </div>
         {{ template "body" . }}
         </div>
     </body>
 </html>
 {{ end }}
 
+
+{{ define "periodform" }}
+<form class="form-inline" method="get">
+    <select class="form-control" name="period">
+        {{ $period := .Form.Get "period" }}
+        {{ range $p := periods }}
+        <option{{ if eq $p $period }} selected{{ end }}>{{ $p }}</option>
+        {{ end }}
+    </select>
+    <input class="form-control" type="date" name="from" value="{{ .Form.Get "from" }}" placeholder="from">
+    <input class="form-control" type="date" name="to" value="{{ .Form.Get "to" }}" placeholder="to">
+    {{ if .Currency }}
+    <input class="form-control" type="text" name="currency" value="{{ .Currency }}" size="5">
+    {{ end }}
+    <button class="btn btn-default" type="submit">Update</button>
+</form>
+{{ end }}
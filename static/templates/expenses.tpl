@@ -0,0 +1,34 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+<h1>Expenses by category</h1>
+
+{{ template "periodform" . }}
+
+{{ with .Expenses }}
+<h2>Spending per period</h2>
+{{ $.Chart }}
+
+<h2>Breakdown</h2>
+{{ $.Pie }}
+
+<table class="table">
+    <thead>
+    <tr>
+        <th>Category</th>
+        <th>Total</th>
+    </tr>
+    </thead>
+    <tbody>
+    {{ $rep := . }}
+    {{ range $i, $c := .Categories }}
+    <tr>
+        <td>{{ $c }}</td>
+        <td class="amount">{{ amount (index $rep.Totals $i) }}</td>
+    </tr>
+    {{ end }}
+    </tbody>
+</table>
+{{ end }}
+{{ end }}
@@ -0,0 +1,46 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+<h1>{{ .Title }}</h1>
+
+<div class="form-inline">
+    <form class="form-inline" method="post" action="/history/undo" style="display: inline">
+        {{ with .History.NextUndo }}
+        <button class="btn btn-default" type="submit" title="{{ .Description }}">Undo: {{ .Description }}</button>
+        {{ else }}
+        <button class="btn btn-default" type="submit" disabled>Undo</button>
+        {{ end }}
+    </form>
+    <form class="form-inline" method="post" action="/history/redo" style="display: inline">
+        {{ with .History.NextRedo }}
+        <button class="btn btn-default" type="submit" title="{{ .Description }}">Redo: {{ .Description }}</button>
+        {{ else }}
+        <button class="btn btn-default" type="submit" disabled>Redo</button>
+        {{ end }}
+    </form>
+    <a class="btn btn-default" href="/history/export?format=csv">Download CSV</a>
+    <a class="btn btn-default" href="/history/export?format=json">Download JSON</a>
+</div>
+
+<table class="table">
+    <thead>
+    <tr>
+        <th>Time</th>
+        <th>Action</th>
+        <th>Description</th>
+    </tr>
+    </thead>
+    <tbody>
+    {{ range .Log }}
+    <tr>
+        <td>{{ .Time.Format "2006-01-02 15:04:05" }}</td>
+        <td>{{ .Action }}</td>
+        <td>{{ .Description }}</td>
+    </tr>
+    {{ else }}
+    <tr><td colspan="3">No changes since the book was loaded.</td></tr>
+    {{ end }}
+    </tbody>
+</table>
+{{ end }}
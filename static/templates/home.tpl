@@ -4,6 +4,15 @@
 {{ define "body" }}
 <h1>Gocash: account overview</h1>
 
+<p>
+    <a class="btn btn-default" href="/account/edit">New account</a>
+    {{ if .Form.Get "hidden" }}
+    <a class="btn btn-default" href="/">Hide hidden accounts</a>
+    {{ else }}
+    <a class="btn btn-default" href="/?hidden=1">Show hidden accounts</a>
+    {{ end }}
+</p>
+
 <table class="table">
 <thead>
     <tr>
@@ -12,11 +21,14 @@
     </tr>
 </thead>
 <tbody>
+    {{ $all := .Form.Get "hidden" }}
     {{ range $acct := sortAccts $.Book }}
-    <tr>
+    {{ if or $all (not $acct.Hidden) }}
+    <tr{{ if $acct.Hidden }} class="text-muted"{{ end }}>
         <td><a href="/account/?name={{ $acct.Name }}">{{ $acct.Name }}</a></td>
         <td class="amount">{{ index $.Book.Balance $acct }} {{ $acct.Unit }}</td>
     </tr>
     {{ end }}
+    {{ end }}
 </tbody>
 {{ end }}
@@ -0,0 +1,40 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+<h1>Net worth</h1>
+
+{{ template "periodform" . }}
+
+{{ with .NetWorth }}
+{{ range .Missing }}
+<div class="alert alert-warning">No price for {{ . }} in {{ $.Currency }}.</div>
+{{ end }}
+
+{{ $.Chart }}
+
+<table class="table">
+    <thead>
+    <tr>
+        <th>Date</th>
+        {{ range .Groups }}<th>{{ . }}</th>{{ end }}
+        <th>Assets</th>
+        <th>Liabilities</th>
+        <th>Net worth</th>
+    </tr>
+    </thead>
+    <tbody>
+    {{ $rep := . }}
+    {{ range $i, $t := .T }}
+    <tr>
+        <td>{{ $t.Format "2006-01-02" }}</td>
+        {{ range $rep.Series }}<td class="amount">{{ amount (index . $i) }}</td>{{ end }}
+        <td class="amount">{{ amount (index $rep.Assets $i) }}</td>
+        <td class="amount">{{ amount (index $rep.Liabilities $i) }}</td>
+        <td class="amount">{{ amount (index $rep.NetWorth $i) }} {{ $.Currency }}</td>
+    </tr>
+    {{ end }}
+    </tbody>
+</table>
+{{ end }}
+{{ end }}
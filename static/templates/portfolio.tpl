@@ -0,0 +1,57 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+<h1>Investment portfolio</h1>
+
+{{ template "periodform" . }}
+
+{{ with .Portfolio }}
+<p>Returns from {{ .Start.Format "2006-01-02" }} to {{ .End.Format "2006-01-02" }}.</p>
+
+<table class="table">
+    <thead>
+    <tr>
+        <th>Security</th>
+        <th>Quantity</th>
+        <th>Cost basis</th>
+        <th>Price</th>
+        <th>Market value</th>
+        <th>Unrealized gain</th>
+        <th>Share</th>
+        <th>Time-weighted</th>
+        <th>Money-weighted (annual)</th>
+    </tr>
+    </thead>
+    <tbody>
+    {{ range .Holdings }}
+    <tr>
+        <td><a href="/account/?name={{ .Account.Name }}">{{ .Account.Name }}</a>
+            {{ if .Skipped }}<br><small class="text-warning">{{ .Skipped }} flows without exchange rate</small>{{ end }}</td>
+        <td class="amount">{{ .Quantity.FloatString 4 }} {{ .Account.Unit }}</td>
+        <td class="amount">{{ amount .Cost }}</td>
+        <td class="amount">{{ .Price.FloatString 4 }} ({{ .PriceAt.Format "2006-01-02" }})</td>
+        <td class="amount">{{ amount .Value }}</td>
+        <td class="amount">{{ amount .Gain }}</td>
+        <td class="amount">{{ share .Share }}</td>
+        <td class="amount">{{ percent .TWR }}</td>
+        <td class="amount">{{ percent .IRR }}</td>
+    </tr>
+    {{ end }}
+    <tr>
+        <th>Total</th>
+        <td></td>
+        <td class="amount">{{ amount .Cost }}</td>
+        <td></td>
+        <td class="amount">{{ amount .Value }} {{ $.Currency }}</td>
+        <td class="amount">{{ amount .Gain }}</td>
+        <td></td>
+        <td></td>
+        <td class="amount">{{ percent .IRR }}</td>
+    </tr>
+    </tbody>
+</table>
+
+{{ $.Pie }}
+{{ end }}
+{{ end }}
@@ -0,0 +1,60 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+<h1>{{ .Title }}</h1>
+
+{{ with .Query }}
+<form method="get" action="/query/">
+    <div class="form-group">
+        <textarea class="form-control" name="q" rows="4" placeholder="SELECT account, sum(amount) WHERE date >= 2013-01-01 GROUP BY account">{{ .Query }}</textarea>
+    </div>
+    <button class="btn btn-default" type="submit">Run</button>
+</form>
+
+{{ if .Error }}
+<div class="alert alert-danger">{{ .Error }}</div>
+{{ else if .Query }}
+<p>{{ len .Rows }} rows.</p>
+
+<table class="table table-condensed">
+    <thead>
+    <tr>
+        {{ range .Columns }}<th>{{ . }}</th>{{ end }}
+    </tr>
+    </thead>
+    <tbody>
+    {{ range .Rows }}
+    <tr>
+        {{ range . }}<td{{ if .Number }} class="amount"{{ end }}>{{ .Text }}</td>{{ end }}
+    </tr>
+    {{ end }}
+    </tbody>
+</table>
+{{ end }}
+{{ end }}
+
+<h2>Query syntax</h2>
+
+<pre>SELECT expression [AS name], ... | *
+[WHERE condition]
+[GROUP BY expression, ...]
+[ORDER BY expression [ASC | DESC], ...]
+[LIMIT n]</pre>
+
+<p>
+Each row is a flow of a transaction. GROUP BY and ORDER BY accept column
+names given with AS and column positions. If there are aggregate functions
+and no GROUP BY clause, rows are grouped by the other selected expressions.
+</p>
+
+<table class="table table-condensed">
+    <tbody>
+    <tr><td>Columns</td><td>{{ range $i, $c := queryColumns }}{{ if $i }}, {{ end }}<code>{{ $c }}</code>{{ end }}</td></tr>
+    <tr><td>Literals</td><td><code>12.50</code>, <code>'text'</code>, <code>2013-01-31</code>, <code>TRUE</code></td></tr>
+    <tr><td>Operators</td><td><code>OR</code>, <code>AND</code>, <code>NOT</code>, <code>=</code>, <code>!=</code>, <code>&lt;</code>, <code>&lt;=</code>, <code>&gt;</code>, <code>&gt;=</code>, <code>~</code> (regular expression), <code>+</code>, <code>-</code>, <code>*</code>, <code>/</code></td></tr>
+    <tr><td>Functions</td><td><code>year(date)</code>, <code>month(date)</code>, <code>day(date)</code>, <code>abs(x)</code>, <code>root(account, depth)</code></td></tr>
+    <tr><td>Aggregates</td><td><code>count(*)</code>, <code>sum(x)</code>, <code>avg(x)</code>, <code>min(x)</code>, <code>max(x)</code></td></tr>
+    </tbody>
+</table>
+{{ end }}
@@ -0,0 +1,85 @@
+{{ define "script" }}
+// Show the difference between the statement balance and the
+// reconciled and ticked flows while editing.
+function updateDifference() {
+    var form = document.getElementById("reconcile");
+    var diff = parseFloat(form.elements["balance"].value) - parseFloat(form.dataset.opening);
+    var boxes = form.querySelectorAll("input[name=flow]");
+    for (var i = 0; i < boxes.length; i++) {
+        if (boxes[i].checked) {
+            diff -= parseFloat(boxes[i].dataset.amount);
+        }
+    }
+    diff = Math.round(diff * 1e6) / 1e6;
+    var label = document.getElementById("difference");
+    label.textContent = isNaN(diff) ? "-" : diff.toFixed(2);
+    label.className = diff == 0 ? "text-success" : "text-danger";
+    document.getElementById("finish").disabled = diff != 0;
+}
+
+document.addEventListener("DOMContentLoaded", function() {
+    document.getElementById("reconcile").addEventListener("input", updateDifference);
+    document.getElementById("reconcile").addEventListener("change", updateDifference);
+    updateDifference();
+});
+{{ end }}
+
+{{ define "body" }}
+{{ $form := .Reconcile }}
+<h1>{{ .Title }}</h1>
+
+{{ if $form.Error }}
+<div class="alert alert-danger">{{ $form.Error }}</div>
+{{ end }}
+
+<p>
+    {{ if not .Account.LastReconcile.IsZero }}Last reconciled on {{ .Account.LastReconcile.Format "2006-01-02" }}.{{ end }}
+    Reconciled balance: {{ amount $form.Opening }} {{ .Account.Unit }}.
+</p>
+
+<form class="form-inline" method="get">
+    <input type="hidden" name="name" value="{{ .Account.Name }}">
+    <input class="form-control" type="date" name="date" value="{{ $form.Date }}">
+    <input type="hidden" name="balance" value="{{ $form.Balance }}">
+    <button class="btn btn-default" type="submit">Change statement date</button>
+</form>
+
+<form method="post" action="/account/reconcile/save" id="reconcile" data-opening="{{ $form.Opening.FloatString 6 }}">
+    <input type="hidden" name="name" value="{{ .Account.Name }}">
+    <input type="hidden" name="date" value="{{ $form.Date }}">
+    <div class="form-inline">
+        <label>Ending balance on {{ $form.Date }}</label>
+        <input class="form-control amount" type="text" name="balance" value="{{ $form.Balance }}" required>
+        {{ .Account.Unit }}
+        <label>Difference</label> <span id="difference"></span>
+    </div>
+
+    <table class="table">
+        <thead>
+        <tr>
+            <th></th>
+            <th>Date</th>
+            <th>Number</th>
+            <th>Description</th>
+            <th>Memo</th>
+            <th>Amount</th>
+        </tr>
+        </thead>
+        <tbody>
+        {{ range $form.Flows }}
+        <tr>
+            <td><input type="checkbox" name="flow" value="{{ .Flow.Id }}" data-amount="{{ .Amount }}"{{ if .Checked }} checked{{ end }}></td>
+            <td>{{ .Flow.Parent.Date.Format "2006-01-02" }}</td>
+            <td>{{ .Flow.Parent.Number }}</td>
+            <td>{{ .Flow.Parent.Description }}</td>
+            <td>{{ .Flow.Memo }}</td>
+            <td class="amount">{{ .Amount }}</td>
+        </tr>
+        {{ end }}
+        </tbody>
+    </table>
+
+    <button class="btn btn-default" type="submit" name="action" value="postpone">Postpone</button>
+    <button class="btn btn-primary" type="submit" name="action" value="finish" id="finish">Finish</button>
+</form>
+{{ end }}
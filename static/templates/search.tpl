@@ -0,0 +1,67 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+<h1>{{ .Title }}</h1>
+
+{{ with .Search }}
+<form class="form-inline" method="get" action="/search/">
+    <input class="form-control" type="text" name="q" value="{{ .Query }}" size="60" placeholder="bakery amount:<-10 date:2013-03">
+    <button class="btn btn-default" type="submit">Search</button>
+</form>
+
+{{ if .Error }}
+<div class="alert alert-danger">{{ .Error }}</div>
+{{ else if .Query }}
+<p>
+    {{ .Total }} matching flows{{ if gt .Total (len .Flows) }}, showing the first {{ len .Flows }}{{ end }}.
+</p>
+
+<table class="table">
+    <thead>
+    <tr>
+        <th>Date</th>
+        <th>Number</th>
+        <th>Description</th>
+        <th>Account</th>
+        <th>Memo</th>
+        <th title="Reconciliation state">R</th>
+        <th>Amount</th>
+        <th></th>
+    </tr>
+    </thead>
+    <tbody>
+    {{ range $flow := .Flows }}
+    <tr{{ if $flow.Parent.IsVoid }} class="voided" title="Voided: {{ $flow.Parent.VoidReason }}"{{ end }}>
+        <td>{{ $flow.Parent.Date.Format "2006-01-02" }}</td>
+        <td>{{ $flow.Parent.Number }}</td>
+        <td>{{ $flow.Parent.Description }}</td>
+        <td><a href="/account/?name={{ $flow.Account.Name }}">{{ $flow.Account.Name }}</a></td>
+        <td>{{ $flow.Memo }}</td>
+        <td>{{ $flow.State }}</td>
+        <td class="amount">{{ $flow.Units }} {{ $flow.Account.Unit }}</td>
+        <td><a href="/transaction/?id={{ $flow.Parent.Id }}&back={{ $flow.Account.Name }}">Edit</a></td>
+    </tr>
+    {{ end }}
+    </tbody>
+</table>
+{{ end }}
+{{ end }}
+
+<h2>Query syntax</h2>
+
+<p>All terms must match. Quote values containing spaces, and prefix a term with <code>-</code> to exclude it.</p>
+
+<table class="table table-condensed">
+    <tbody>
+    <tr><td><code>bakery</code></td><td>Words of the description, notes or memo starting with this text</td></tr>
+    <tr><td><code>"weekly shopping"</code></td><td>Consecutive words</td></tr>
+    <tr><td><code>desc:acme</code>, <code>memo:bread</code>, <code>notes:rent</code></td><td>Text of a single field</td></tr>
+    <tr><td><code>amount:-12.50</code>, <code>amount:&gt;100</code>, <code>amount:10..20</code></td><td>Amount in the account commodity</td></tr>
+    <tr><td><code>date:2013</code>, <code>date:&gt;=2013-03</code>, <code>date:2013-01-15..2013-02</code></td><td>Years, months or days</td></tr>
+    <tr><td><code>account:/Expenses/*</code>, <code>account:/Assets/**</code></td><td>Account names: <code>*</code> matches within a name, <code>**</code> also matches subaccounts</td></tr>
+    <tr><td><code>state:nc</code></td><td>Reconciliation state: n (not cleared), c (cleared), y (reconciled), f (frozen), v (voided)</td></tr>
+    <tr><td><code>number:101</code></td><td>Transaction number</td></tr>
+    </tbody>
+</table>
+{{ end }}
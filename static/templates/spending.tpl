@@ -0,0 +1,67 @@
+{{ define "script" }}
+{{ end }}
+
+{{ define "body" }}
+<h1>Spending analysis</h1>
+
+{{ template "periodform" . }}
+
+{{ with .Spending }}
+<p>Total spending from {{ .Start.Format "2006-01-02" }} to {{ .End.Format "2006-01-02" }}:
+{{ if .Total }}{{ amount .Total }}{{ end }}</p>
+
+<h2>Top categories</h2>
+<table class="table">
+    <thead><tr><th>Category</th><th>Amount</th><th>Share</th></tr></thead>
+    <tbody>
+    {{ range .Categories }}
+    <tr>
+        <td>{{ .Name }}</td>
+        <td class="amount">{{ amount .Amount }}</td>
+        <td class="amount">{{ share .Share }}</td>
+    </tr>
+    {{ end }}
+    </tbody>
+</table>
+
+<h2>Top payees</h2>
+<table class="table">
+    <thead><tr><th>Payee</th><th>Amount</th><th>Share</th></tr></thead>
+    <tbody>
+    {{ range .Payees }}
+    <tr>
+        <td>{{ .Name }}</td>
+        <td class="amount">{{ amount .Amount }}</td>
+        <td class="amount">{{ share .Share }}</td>
+    </tr>
+    {{ end }}
+    </tbody>
+</table>
+
+<h2>Trends</h2>
+<table class="table">
+    <thead>
+    <tr>
+        <th>Category</th>
+        <th>Month</th>
+        <th>Amount</th>
+        <th>Average</th>
+        <th>Month over month</th>
+        <th>Year over year</th>
+    </tr>
+    </thead>
+    <tbody>
+    {{ range .Trends }}
+    <tr{{ if .Flagged }} class="warning"{{ end }}>
+        <td>{{ .Category }}</td>
+        <td>{{ .Month.Format "Jan 2006" }}</td>
+        <td class="amount">{{ amount .Current }}</td>
+        <td class="amount">{{ amount .Average }}</td>
+        <td class="amount">{{ percent .MoM }}</td>
+        <td class="amount">{{ percent .YoY }}</td>
+    </tr>
+    {{ end }}
+    </tbody>
+</table>
+{{ end }}
+{{ end }}
@@ -0,0 +1,124 @@
+{{ define "script" }}
+// Show the imbalance of split amounts while typing, and suggest
+// the balancing amount in empty amount fields.
+function updateBalance() {
+    var sum = 0;
+    var inputs = document.querySelectorAll("input.split-amount");
+    for (var i = 0; i < inputs.length; i++) {
+        var x = parseFloat(inputs[i].value);
+        if (!isNaN(x)) {
+            sum += x;
+        }
+    }
+    sum = Math.round(sum * 1e6) / 1e6;
+    var label = document.getElementById("imbalance");
+    label.textContent = sum.toFixed(2);
+    label.className = sum == 0 ? "text-success" : "text-danger";
+    for (var i = 0; i < inputs.length; i++) {
+        inputs[i].placeholder = sum == 0 ? "" : (-sum).toFixed(2);
+    }
+}
+
+function addSplit() {
+    var rows = document.querySelectorAll("#splits tbody tr");
+    var row = rows[rows.length - 1].cloneNode(true);
+    var inputs = row.querySelectorAll("input");
+    for (var i = 0; i < inputs.length; i++) {
+        inputs[i].value = "";
+    }
+    rows[0].parentNode.appendChild(row);
+    updateBalance();
+}
+
+document.addEventListener("DOMContentLoaded", function() {
+    document.getElementById("splits").addEventListener("input", updateBalance);
+    document.getElementById("addsplit").addEventListener("click", addSplit);
+    updateBalance();
+});
+{{ end }}
+
+{{ define "body" }}
+{{ $form := .Transaction }}
+<h1>{{ .Title }}</h1>
+
+{{ if $form.Error }}
+<div class="alert alert-danger">{{ $form.Error }}</div>
+{{ end }}
+{{ if $form.Void }}
+<div class="alert alert-warning">
+    This transaction was voided{{ if $form.VoidReason }}: {{ $form.VoidReason }}{{ end }}.
+    The amounts shown are those before voiding. Unvoid it to edit it.
+</div>
+{{ end }}
+
+<form method="post" action="/transaction/save">
+    <input type="hidden" name="id" value="{{ $form.Id }}">
+    <input type="hidden" name="back" value="{{ $form.Back }}">
+    <div class="form-inline">
+        <input class="form-control" type="date" name="date" value="{{ $form.Date }}" required>
+        <input class="form-control" type="text" name="number" value="{{ $form.Number }}" placeholder="number" size="6">
+        <input class="form-control" type="text" name="description" value="{{ $form.Description }}" placeholder="description" size="40">
+        <input class="form-control" type="text" name="currency" value="{{ $form.Currency }}" placeholder="currency" size="5" required>
+    </div>
+    <textarea class="form-control" name="notes" placeholder="notes" rows="2">{{ $form.Notes }}</textarea>
+
+    <table class="table" id="splits">
+        <thead>
+        <tr>
+            <th>Account</th>
+            <th>Memo</th>
+            <th>Amount</th>
+            <th>Quantity</th>
+        </tr>
+        </thead>
+        <tbody>
+        {{ range $form.Splits }}
+        <tr>
+            <td>
+                <input type="hidden" name="split" value="{{ .Id }}">
+                <input class="form-control" type="text" name="account" value="{{ .Account }}" list="accounts">
+            </td>
+            <td><input class="form-control" type="text" name="memo" value="{{ .Memo }}"></td>
+            <td><input class="form-control split-amount amount" type="text" name="amount" value="{{ .Amount }}"></td>
+            <td><input class="form-control amount" type="text" name="quantity" value="{{ .Quantity }}" placeholder="same as amount"></td>
+        </tr>
+        {{ end }}
+        </tbody>
+        <tfoot>
+        <tr>
+            <td><button class="btn btn-default" type="button" id="addsplit">Add split</button></td>
+            <td>Imbalance</td>
+            <td class="amount"><span id="imbalance"></span></td>
+            <td></td>
+        </tr>
+        </tfoot>
+    </table>
+
+    <datalist id="accounts">
+        {{ range sortAccts .Book }}{{ if not (or (eq .Type "ROOT") .Placeholder .Hidden) }}
+        <option value="{{ .Name }}">
+        {{ end }}{{ end }}
+    </datalist>
+
+    <button class="btn btn-primary" type="submit"{{ if $form.Void }} disabled{{ end }}>Save</button>
+</form>
+
+{{ if $form.Id }}
+<form class="form-inline" method="post" action="/transaction/delete">
+    <input type="hidden" name="id" value="{{ $form.Id }}">
+    <input type="hidden" name="back" value="{{ $form.Back }}">
+    <a class="btn btn-default" href="/transaction/?dup={{ $form.Id }}&back={{ $form.Back }}">Duplicate</a>
+    <button class="btn btn-danger" type="submit" onclick="return confirm('Delete this transaction?')">Delete</button>
+</form>
+<form class="form-inline" method="post" action="/transaction/void">
+    <input type="hidden" name="id" value="{{ $form.Id }}">
+    <input type="hidden" name="back" value="{{ $form.Back }}">
+    {{ if $form.Void }}
+    <button class="btn btn-default" type="submit" name="action" value="unvoid">Unvoid</button>
+    {{ else }}
+    <input class="form-control" type="text" name="reason" placeholder="reason" required>
+    <button class="btn btn-warning" type="submit" name="action" value="void">Void</button>
+    {{ end }}
+</form>
+{{ end }}
+{{ end }}
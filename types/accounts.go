@@ -0,0 +1,223 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AccountByName returns the account with the given full name,
+// or nil if it does not exist.
+func (book *Book) AccountByName(name string) *Account {
+	for _, acct := range book.Accounts {
+		if acct.Name == name {
+			return acct
+		}
+	}
+	return nil
+}
+
+// Root returns the root account of the book, if any.
+func (book *Book) Root() *Account {
+	for _, acct := range book.Accounts {
+		if acct.Type == "ROOT" {
+			return acct
+		}
+	}
+	return nil
+}
+
+// EnsureAccount returns the account with the given full name,
+// creating it and its missing ancestors with the given type and unit.
+func (book *Book) EnsureAccount(name, typ, unit string) *Account {
+	if !strings.HasPrefix(name, "/") {
+		name = "/" + name
+	}
+	if acct := book.AccountByName(name); acct != nil {
+		return acct
+	}
+	parent := book.Root()
+	if i := strings.LastIndex(name, "/"); i > 0 {
+		parent = book.EnsureAccount(name[:i], typ, unit)
+	}
+	acct := &Account{
+		Id:    NewGUID(),
+		Name:  name,
+		Type:  typ,
+		Unit:  unit,
+		Denom: 100,
+	}
+	if book.Accounts == nil {
+		book.Accounts = make(map[GUID]*Account)
+	}
+	book.Accounts[acct.Id] = acct
+	if parent != nil {
+		parent.Children = append(parent.Children, acct)
+	}
+	return acct
+}
+
+// AccountTypes lists the Gnucash account types.
+var AccountTypes = []string{
+	"BANK", "CASH", "ASSET", "STOCK", "MUTUAL", "RECEIVABLE",
+	"CREDIT", "LIABILITY", "PAYABLE",
+	"INCOME", "EXPENSE", "EQUITY", "TRADING",
+}
+
+// Parent returns the parent of acct, or nil for the root account.
+func (book *Book) Parent(acct *Account) *Account {
+	for _, a := range book.Accounts {
+		for _, c := range a.Children {
+			if c == acct {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// childName returns the full name of an account named leaf
+// under parent.
+func childName(parent *Account, leaf string) string {
+	if parent == nil || parent.Type == "ROOT" {
+		return "/" + leaf
+	}
+	return parent.Name + "/" + leaf
+}
+
+func checkLeafName(leaf string) error {
+	if strings.TrimSpace(leaf) == "" {
+		return fmt.Errorf("empty account name")
+	}
+	if strings.Contains(leaf, "/") {
+		return fmt.Errorf("account name %q contains a slash", leaf)
+	}
+	return nil
+}
+
+// AddAccount creates an account named leaf under parent,
+// or at the top level if parent is nil.
+func (book *Book) AddAccount(parent *Account, leaf, typ, unit, description string) (*Account, error) {
+	if err := checkLeafName(leaf); err != nil {
+		return nil, err
+	}
+	if typ == "" || typ == "ROOT" {
+		return nil, fmt.Errorf("invalid account type %q", typ)
+	}
+	if parent == nil {
+		parent = book.Root()
+	}
+	name := childName(parent, leaf)
+	if book.AccountByName(name) != nil {
+		return nil, fmt.Errorf("account %s already exists", name)
+	}
+	acct := &Account{
+		Id:          NewGUID(),
+		Name:        name,
+		Type:        typ,
+		Unit:        unit,
+		Denom:       100,
+		Description: description,
+	}
+	if book.Accounts == nil {
+		book.Accounts = make(map[GUID]*Account)
+	}
+	book.Accounts[acct.Id] = acct
+	if parent != nil {
+		parent.Children = append(parent.Children, acct)
+	}
+	return acct, nil
+}
+
+// MoveAccount renames acct to leaf and moves it under parent, or at
+// the top level if parent is nil. The names of its descendants are
+// updated accordingly.
+func (book *Book) MoveAccount(acct, parent *Account, leaf string) error {
+	if err := checkLeafName(leaf); err != nil {
+		return err
+	}
+	if acct.Type == "ROOT" {
+		return fmt.Errorf("cannot move the root account")
+	}
+	if parent == nil {
+		parent = book.Root()
+	}
+	for p := parent; p != nil; p = book.Parent(p) {
+		if p == acct {
+			return fmt.Errorf("cannot move %s under itself", acct.Name)
+		}
+	}
+	name := childName(parent, leaf)
+	if other := book.AccountByName(name); other != nil && other != acct {
+		return fmt.Errorf("account %s already exists", name)
+	}
+	if old := book.Parent(acct); old != parent {
+		if old != nil {
+			old.Children = removeAccount(old.Children, acct)
+		}
+		if parent != nil {
+			parent.Children = append(parent.Children, acct)
+		}
+	}
+	var rename func(a *Account, name string)
+	rename = func(a *Account, name string) {
+		a.Name = name
+		for _, c := range a.Children {
+			rename(c, name+"/"+c.Name[strings.LastIndex(c.Name, "/")+1:])
+		}
+	}
+	rename(acct, name)
+	return nil
+}
+
+// DeleteAccount deletes acct, which must have no children. Its flows
+// are assigned to the account reassign, which must have the same
+// commodity; reassign may be nil only if acct has no flows.
+// The book must be recomputed afterwards.
+func (book *Book) DeleteAccount(acct, reassign *Account) error {
+	if acct.Type == "ROOT" {
+		return fmt.Errorf("cannot delete the root account")
+	}
+	if len(acct.Children) > 0 {
+		return fmt.Errorf("account %s has subaccounts", acct.Name)
+	}
+	if reassign == acct {
+		return fmt.Errorf("cannot reassign flows of %s to itself", acct.Name)
+	}
+	var flows []*Flow
+	for _, trn := range book.Transactions {
+		for i := range trn.Flows {
+			if trn.Flows[i].Account == acct {
+				flows = append(flows, &trn.Flows[i])
+			}
+		}
+	}
+	if len(flows) > 0 {
+		if reassign == nil {
+			return fmt.Errorf("account %s has %d flows", acct.Name, len(flows))
+		}
+		if reassign.Unit != acct.Unit {
+			return fmt.Errorf("cannot reassign flows in %s to account %s in %s",
+				acct.Unit, reassign.Name, reassign.Unit)
+		}
+		if reassign.Placeholder {
+			return fmt.Errorf("cannot reassign flows to placeholder account %s", reassign.Name)
+		}
+	}
+	for _, f := range flows {
+		f.Account = reassign
+	}
+	if parent := book.Parent(acct); parent != nil {
+		parent.Children = removeAccount(parent.Children, acct)
+	}
+	delete(book.Accounts, acct.Id)
+	return nil
+}
+
+func removeAccount(accts []*Account, acct *Account) []*Account {
+	for i, a := range accts {
+		if a == acct {
+			return append(accts[:i:i], accts[i+1:]...)
+		}
+	}
+	return accts
+}
@@ -0,0 +1,146 @@
+package types
+
+import (
+	"math/big"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func testAccounts() *Book {
+	book := &Book{Transactions: make(map[GUID]*Transaction)}
+	book.Accounts = map[GUID]*Account{"root": {Id: "root", Name: "Root Account", Type: "ROOT"}}
+	book.EnsureAccount("/Assets/Bank/Checking", "BANK", "EUR")
+	book.EnsureAccount("/Assets/Bank/Savings", "BANK", "EUR")
+	book.EnsureAccount("/Expenses/Food", "EXPENSE", "EUR")
+	book.EnsureAccount("/Expenses/Misc", "EXPENSE", "EUR")
+	return book
+}
+
+func accountNames(book *Book) string {
+	var names []string
+	for _, a := range book.Accounts {
+		if a.Type != "ROOT" {
+			names = append(names, a.Name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, " ")
+}
+
+func TestAddAccount(t *testing.T) {
+	book := testAccounts()
+	bank := book.AccountByName("/Assets/Bank")
+	acct, err := book.AddAccount(bank, "Joint", "BANK", "EUR", "joint account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acct.Name != "/Assets/Bank/Joint" || book.Parent(acct) != bank {
+		t.Errorf("got account %s under %v", acct.Name, book.Parent(acct))
+	}
+	if _, err := book.AddAccount(nil, "Equity", "EQUITY", "EUR", ""); err != nil {
+		t.Fatal(err)
+	}
+	if book.Parent(book.AccountByName("/Equity")) != book.Root() {
+		t.Errorf("top-level account is not a child of the root")
+	}
+	for _, c := range []struct{ leaf, typ, err string }{
+		{"Joint", "BANK", "account /Assets/Bank/Joint already exists"},
+		{"a/b", "BANK", `account name "a/b" contains a slash`},
+		{" ", "BANK", "empty account name"},
+		{"Other", "", `invalid account type ""`},
+	} {
+		_, err := book.AddAccount(bank, c.leaf, c.typ, "EUR", "")
+		if err == nil || err.Error() != c.err {
+			t.Errorf("AddAccount(%q, %q): got error %v, expected %q", c.leaf, c.typ, err, c.err)
+		}
+	}
+}
+
+func TestMoveAccount(t *testing.T) {
+	book := testAccounts()
+	bank := book.AccountByName("/Assets/Bank")
+	assets := book.AccountByName("/Assets")
+	expenses := book.AccountByName("/Expenses")
+
+	if err := book.MoveAccount(bank, assets, "Banks"); err != nil {
+		t.Fatal(err)
+	}
+	const renamed = "/Assets /Assets/Banks /Assets/Banks/Checking /Assets/Banks/Savings /Expenses /Expenses/Food /Expenses/Misc"
+	if s := accountNames(book); s != renamed {
+		t.Errorf("got %s, expected %s", s, renamed)
+	}
+
+	if err := book.MoveAccount(bank, nil, "Banks"); err != nil {
+		t.Fatal(err)
+	}
+	const moved = "/Assets /Banks /Banks/Checking /Banks/Savings /Expenses /Expenses/Food /Expenses/Misc"
+	if s := accountNames(book); s != moved {
+		t.Errorf("got %s, expected %s", s, moved)
+	}
+	if len(assets.Children) != 0 || book.Parent(bank) != book.Root() {
+		t.Errorf("children of /Assets not updated: %v", assets.Children)
+	}
+
+	for _, c := range []struct {
+		acct, parent *Account
+		leaf, err    string
+	}{
+		{bank, book.AccountByName("/Banks/Savings"), "Banks", "cannot move /Banks under itself"},
+		{bank, nil, "Expenses", "account /Expenses already exists"},
+		{expenses, bank, "", "empty account name"},
+	} {
+		err := book.MoveAccount(c.acct, c.parent, c.leaf)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("got error %v, expected %q", err, c.err)
+		}
+	}
+	if s := accountNames(book); s != moved {
+		t.Errorf("failed moves changed names: %s", s)
+	}
+}
+
+func TestDeleteAccount(t *testing.T) {
+	book := testAccounts()
+	checking := book.AccountByName("/Assets/Bank/Checking")
+	food := book.AccountByName("/Expenses/Food")
+	misc := book.AccountByName("/Expenses/Misc")
+	x := big.NewRat(42, 10)
+	book.Transactions["t"] = &Transaction{Id: "t", Currency: "EUR", Flows: []Flow{
+		{Account: checking, Price: (*Amount)(new(big.Rat).Neg(x))},
+		{Account: food, Price: (*Amount)(new(big.Rat).Set(x))},
+	}}
+	usd, _ := book.AddAccount(book.AccountByName("/Expenses"), "Travel", "EXPENSE", "USD", "")
+
+	for _, c := range []struct {
+		acct, reassign *Account
+		err            string
+	}{
+		{book.AccountByName("/Expenses"), nil, "account /Expenses has subaccounts"},
+		{food, nil, "account /Expenses/Food has 1 flows"},
+		{food, food, "cannot reassign flows of /Expenses/Food to itself"},
+		{food, usd, "cannot reassign flows in EUR to account /Expenses/Travel in USD"},
+	} {
+		err := book.DeleteAccount(c.acct, c.reassign)
+		if err == nil || err.Error() != c.err {
+			t.Errorf("got error %v, expected %q", err, c.err)
+		}
+	}
+
+	if err := book.DeleteAccount(food, misc); err != nil {
+		t.Fatal(err)
+	}
+	if book.Transactions["t"].Flows[1].Account != misc {
+		t.Errorf("flow was not reassigned")
+	}
+	if err := book.DeleteAccount(usd, nil); err != nil {
+		t.Fatal(err)
+	}
+	const expected = "/Assets /Assets/Bank /Assets/Bank/Checking /Assets/Bank/Savings /Expenses /Expenses/Misc"
+	if s := accountNames(book); s != expected {
+		t.Errorf("got %s, expected %s", s, expected)
+	}
+	if n := len(book.AccountByName("/Expenses").Children); n != 1 {
+		t.Errorf("got %d children of /Expenses, expected 1", n)
+	}
+}
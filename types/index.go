@@ -0,0 +1,72 @@
+package types
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// An Index is an inverted index of the words of transaction
+// descriptions and notes, and of flow memos. It maps words to the
+// flows whose text contains them.
+type Index struct {
+	words []string // Sorted.
+	flows map[string][]*Flow
+}
+
+// Words splits s into lower case words of letters and digits.
+func Words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// FlowText returns the text of a flow indexed by the book index:
+// the description and notes of its transaction, and its memo.
+func FlowText(f *Flow) string {
+	return f.Parent.Description + "\n" + f.Parent.Notes + "\n" + f.Memo
+}
+
+func (book *Book) buildIndex() *Index {
+	idx := &Index{flows: make(map[string][]*Flow)}
+	for _, flows := range book.Flows {
+		for _, f := range flows {
+			seen := make(map[string]bool)
+			for _, w := range Words(FlowText(f)) {
+				if !seen[w] {
+					seen[w] = true
+					idx.flows[w] = append(idx.flows[w], f)
+				}
+			}
+		}
+	}
+	for w := range idx.flows {
+		idx.words = append(idx.words, w)
+	}
+	sort.Strings(idx.words)
+	return idx
+}
+
+// Lookup returns the flows having a word that starts with prefix,
+// which must be lower case.
+func (idx *Index) Lookup(prefix string) []*Flow {
+	i := sort.SearchStrings(idx.words, prefix)
+	var words []string
+	for ; i < len(idx.words) && strings.HasPrefix(idx.words[i], prefix); i++ {
+		words = append(words, idx.words[i])
+	}
+	if len(words) == 1 {
+		return idx.flows[words[0]]
+	}
+	seen := make(map[*Flow]bool)
+	var flows []*Flow
+	for _, w := range words {
+		for _, f := range idx.flows[w] {
+			if !seen[f] {
+				seen[f] = true
+				flows = append(flows, f)
+			}
+		}
+	}
+	return flows
+}
@@ -0,0 +1,78 @@
+package types
+
+import (
+	"math/big"
+	"sort"
+	"time"
+)
+
+// A Commodity is a currency or a security.
+type Commodity struct {
+	Space    string // ISO4217 for currencies, or an exchange name.
+	Id       string // The currency code or ticker symbol.
+	Name     string
+	Fraction int // The smallest tradable fraction (usually 100).
+}
+
+// IsCurrency reports whether c is a currency.
+func (c *Commodity) IsCurrency() bool { return c.Space == "ISO4217" || c.Space == "CURRENCY" }
+
+// A Price is a quote of a commodity in terms of a currency.
+type Price struct {
+	Id        GUID
+	Commodity string
+	Currency  string
+	Time      time.Time
+	Source    string
+	Type      string
+	Value     *Amount
+}
+
+// Prices is a price database, sorted by time.
+type Prices []*Price
+
+// Sort sorts the price database by time.
+func (p Prices) Sort() { sort.Stable(pricesByTime(p)) }
+
+// Lookup returns the value of one unit of commodity expressed in currency
+// at time t, using the latest quote not after t. Reverse quotes are used
+// if no direct quote exists. It returns nil if no quote is available.
+// The price database must be sorted.
+func (p Prices) Lookup(commodity, currency string, t time.Time) *Amount {
+	v, _ := p.Quote(commodity, currency, t)
+	return v
+}
+
+// Quote is like Lookup, and also returns the time of the quote used.
+func (p Prices) Quote(commodity, currency string, t time.Time) (*Amount, time.Time) {
+	if commodity == currency {
+		return (*Amount)(big.NewRat(1, 1)), t
+	}
+	n := sort.Search(len(p), func(i int) bool { return p[i].Time.After(t) })
+	for i := n - 1; i >= 0; i-- {
+		q := p[i]
+		switch {
+		case q.Commodity == commodity && q.Currency == currency:
+			return q.Value, q.Time
+		case q.Commodity == currency && q.Currency == commodity && q.Value.Rat().Sign() != 0:
+			return (*Amount)(new(big.Rat).Inv(q.Value.Rat())), q.Time
+		}
+	}
+	return nil, time.Time{}
+}
+
+// Latest returns the most recent quote of commodity in currency.
+func (p Prices) Latest(commodity, currency string) *Price {
+	for i := len(p) - 1; i >= 0; i-- {
+		if p[i].Commodity == commodity && p[i].Currency == currency {
+			return p[i]
+		}
+	}
+	return nil
+}
+
+type pricesByTime Prices
+
+func (s pricesByTime) Len() int           { return len(s) }
+func (s pricesByTime) Less(i, j int) bool { return s[i].Time.Before(s[j].Time) }
+func (s pricesByTime) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
@@ -0,0 +1,93 @@
+package types
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// Imbalance returns the sum of flow values of the transaction,
+// which is zero for a balanced transaction.
+func (trn *Transaction) Imbalance() *big.Rat {
+	sum := new(big.Rat)
+	for _, f := range trn.Flows {
+		if f.Price != nil {
+			sum.Add(sum, f.Price.Rat())
+		}
+	}
+	return sum
+}
+
+// Check verifies that the transaction follows double-entry rules:
+// it has a date, a currency and flows with an account and a value,
+// and the values of its flows sum to zero.
+func (trn *Transaction) Check() error {
+	if trn.Date.IsZero() {
+		return fmt.Errorf("transaction has no date")
+	}
+	if trn.Currency == "" {
+		return fmt.Errorf("transaction has no currency")
+	}
+	if len(trn.Flows) == 0 {
+		return fmt.Errorf("transaction has no flows")
+	}
+	for i, f := range trn.Flows {
+		if f.Account == nil {
+			return fmt.Errorf("flow %d has no account", i+1)
+		}
+		if f.Price == nil {
+			return fmt.Errorf("flow %d has no value", i+1)
+		}
+		if f.Account.Type == "ROOT" {
+			return fmt.Errorf("flow %d uses the root account", i+1)
+		}
+		if f.Account.Placeholder {
+			return fmt.Errorf("flow %d uses placeholder account %s", i+1, f.Account.Name)
+		}
+	}
+	if x := trn.Imbalance(); x.Sign() != 0 {
+		return fmt.Errorf("transaction is not balanced: %s %s", x.FloatString(2), trn.Currency)
+	}
+	return nil
+}
+
+// Void voids the transaction, as Gnucash does: the amounts of its
+// flows are set to zero, their former amounts are kept in VoidPrice
+// and VoidQuantity, and they are marked as voided.
+func (trn *Transaction) Void(reason string, now time.Time) error {
+	if trn.IsVoid() {
+		return fmt.Errorf("transaction is already voided")
+	}
+	for i := range trn.Flows {
+		f := &trn.Flows[i]
+		if f.State == Frozen {
+			return fmt.Errorf("flow %d is frozen", i+1)
+		}
+	}
+	for i := range trn.Flows {
+		f := &trn.Flows[i]
+		f.VoidPrice, f.VoidQuantity = f.Price, f.Units()
+		f.Price, f.Quantity = new(Amount), new(Amount)
+		f.State = Voided
+	}
+	trn.VoidReason, trn.VoidTime = reason, now
+	return nil
+}
+
+// Unvoid restores the amounts of a voided transaction. Its flows
+// become not cleared.
+func (trn *Transaction) Unvoid() error {
+	if !trn.IsVoid() {
+		return fmt.Errorf("transaction is not voided")
+	}
+	for i := range trn.Flows {
+		f := &trn.Flows[i]
+		if f.VoidPrice != nil {
+			f.Price, f.Quantity = f.VoidPrice, f.VoidQuantity
+		}
+		f.VoidPrice, f.VoidQuantity = nil, nil
+		f.State = NotCleared
+	}
+	trn.VoidReason, trn.VoidTime = "", time.Time{}
+	return nil
+}
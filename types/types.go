@@ -11,36 +11,142 @@ import (
 type Account struct {
 	Id            GUID
 	Name          string     // A slash separated hierarchy of words.
-	Type          string     // BANK, EXPENSE, INCOME, ASSET, CASH.
+	Type          string     // BANK, EXPENSE, INCOME, ASSET, CASH, STOCK...
 	Unit          string     // A currency or security name.
 	Denom         int        // The unit denominator (usually 100).
 	Description   string     // A free text description.
 	LastReconcile time.Time  // The time of last reconciliation.
+	Placeholder   bool       // The account only groups other accounts.
+	Hidden        bool       // The account is not shown by default.
 	Children      []*Account `json:"-"`
 }
 
+// Ancestor returns the name of the ancestor of the account at the
+// given depth, where top-level accounts have depth 1. The account's own
+// name is returned if it is not that deep.
+func (a *Account) Ancestor(depth int) string {
+	n := 0
+	for i, c := range a.Name {
+		if c == '/' && i > 0 {
+			n++
+			if n == depth {
+				return a.Name[:i]
+			}
+		}
+	}
+	return a.Name
+}
+
+// IsAsset reports whether the account holds assets.
+func (a *Account) IsAsset() bool {
+	switch a.Type {
+	case "ASSET", "BANK", "CASH", "STOCK", "MUTUAL", "RECEIVABLE":
+		return true
+	}
+	return false
+}
+
+// IsLiability reports whether the account holds liabilities.
+func (a *Account) IsLiability() bool {
+	switch a.Type {
+	case "LIABILITY", "CREDIT", "PAYABLE":
+		return true
+	}
+	return false
+}
+
 type Transaction struct {
 	Id          GUID
 	Date        time.Time // The value date of the transaction.
 	Stamp       time.Time // When the transaction was entered.
+	Currency    string    // The currency of flow prices.
 	Description string
-	Notes       string // Additional notes
-	Number      string // A sequence number (checks...)
+	Notes       string    // Additional notes
+	Number      string    // A sequence number (checks...)
+	ExternalId  string    // An identifier assigned by a bank statement.
+	VoidReason  string    // Why the transaction was voided.
+	VoidTime    time.Time // When the transaction was voided.
 	Flows       []Flow
 }
 
+// IsVoid reports whether the transaction has been voided.
+func (trn *Transaction) IsVoid() bool {
+	for _, f := range trn.Flows {
+		if f.State == Voided {
+			return true
+		}
+	}
+	return false
+}
+
 // A Flow is a part of a split transaction. A flow is positive for
 // debit actions, negative for credit actions.
 type Flow struct {
 	Id             GUID
 	Memo           string
 	Account        *Account `json:"-"`
-	Price          *Amount
-	Reconciled     bool
+	Price          *Amount  // Value in the transaction currency.
+	Quantity       *Amount  // Amount in the account commodity.
+	State          ReconcileState
 	ReconciledTime time.Time
+	VoidPrice      *Amount      // Value before the flow was voided.
+	VoidQuantity   *Amount      // Quantity before the flow was voided.
 	Parent         *Transaction `json:"-"`
 }
 
+// A ReconcileState is the reconciliation state of a flow. The zero
+// value is NotCleared.
+type ReconcileState int
+
+const (
+	NotCleared ReconcileState = iota
+	Cleared                   // Cleared but not yet reconciled.
+	Reconciled                // Confirmed by a bank statement.
+	Frozen                    // Reconciled and no longer editable.
+	Voided                    // Part of a voided transaction.
+)
+
+// reconcileLetters are the Gnucash letters of reconcile states.
+const reconcileLetters = "ncyfv"
+
+// ParseReconcileState parses a Gnucash reconcile state letter.
+func ParseReconcileState(s string) (ReconcileState, error) {
+	if len(s) == 1 {
+		for i := 0; i < len(reconcileLetters); i++ {
+			if s[0] == reconcileLetters[i] {
+				return ReconcileState(i), nil
+			}
+		}
+	}
+	return NotCleared, fmt.Errorf("invalid reconcile state %q", s)
+}
+
+// String returns the Gnucash letter of the state: n, c, y, f or v.
+func (s ReconcileState) String() string {
+	if s < 0 || int(s) >= len(reconcileLetters) {
+		return "?"
+	}
+	return reconcileLetters[s : s+1]
+}
+
+// IsReconciled reports whether the state is reconciled or frozen.
+func (s ReconcileState) IsReconciled() bool { return s == Reconciled || s == Frozen }
+
+func (s ReconcileState) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+func (s *ReconcileState) UnmarshalText(b []byte) (err error) {
+	*s, err = ParseReconcileState(string(b))
+	return err
+}
+
+// Units returns the amount of the flow in the account commodity.
+func (f *Flow) Units() *Amount {
+	if f.Quantity != nil {
+		return f.Quantity
+	}
+	return f.Price
+}
+
 type Amount big.Rat
 
 func (amt *Amount) Rat() *big.Rat               { return (*big.Rat)(amt) }
@@ -50,14 +156,40 @@ func (amt *Amount) String() string {
 	return (*big.Rat)(amt).FloatString(2)
 }
 
+// Decimal formats amt exactly if it is a decimal number, with at
+// least 2 decimals. Other numbers are rounded to 18 decimals.
+func (amt *Amount) Decimal() string {
+	return amt.DecimalRound(18)
+}
+
+// DecimalRound formats amt like Decimal, rounded to at most
+// maxPrec decimals, for display of computed values.
+func (amt *Amount) DecimalRound(maxPrec int) string {
+	x := (*big.Rat)(amt)
+	prec := 2
+	pow := big.NewInt(100)
+	ten := big.NewInt(10)
+	for prec < maxPrec && new(big.Int).Mod(pow, x.Denom()).Sign() != 0 {
+		pow.Mul(pow, ten)
+		prec++
+	}
+	return x.FloatString(prec)
+}
+
 func (amt *Amount) MarshalJSON() (s []byte, err error) {
 	return []byte(string('"') + (*big.Rat)(amt).RatString() + string('"')), nil
 }
 
+// UnmarshalJSON accepts fractions and decimal numbers, either as
+// strings or as JSON numbers.
 func (amt *Amount) UnmarshalJSON(s []byte) error {
-	str, err := strconv.Unquote(string(s))
-	if err != nil {
-		return fmt.Errorf("invalid price string %q: %s", s, err)
+	str := string(s)
+	if len(s) > 0 && s[0] == '"' {
+		var err error
+		str, err = strconv.Unquote(str)
+		if err != nil {
+			return fmt.Errorf("invalid price string %q: %s", s, err)
+		}
 	}
 	_, ok := (*big.Rat)(amt).SetString(str)
 	if !ok {
@@ -72,11 +204,15 @@ func (x *Amount) Add(y *Amount) *Amount { (*big.Rat)(x).Add((*big.Rat)(x), (*big
 type Book struct {
 	Accounts     map[GUID]*Account
 	Transactions map[GUID]*Transaction
+	Commodities  []*Commodity
+	Prices       Prices
 
 	// Computed data.
 	Balance map[*Account]*Amount `json:"-"`
 	// Flows by account.
 	Flows map[*Account][]*Flow `json:"-"`
+	// Words of transactions and flows.
+	Index *Index `json:"-"`
 }
 
 func (book *Book) Recompute() {
@@ -85,11 +221,29 @@ func (book *Book) Recompute() {
 	for _, act := range book.Accounts {
 		book.Balance[act] = sumFlows(book.Flows[act])
 	}
+	book.Prices.Sort()
+	book.Index = book.buildIndex()
+}
+
+// DefaultCurrency returns the most used transaction currency.
+func (book *Book) DefaultCurrency() string {
+	count := make(map[string]int)
+	best := ""
+	for _, trn := range book.Transactions {
+		count[trn.Currency]++
+		if n := count[trn.Currency]; n > count[best] || n == count[best] && trn.Currency < best {
+			best = trn.Currency
+		}
+	}
+	return best
 }
 
 func sumFlows(flows []*Flow) *Amount {
 	total := new(Amount)
 	for _, f := range flows {
+		if f.State == Voided {
+			continue
+		}
 		total = total.Add(f.Price)
 	}
 	return total
@@ -3,7 +3,9 @@ package types
 import (
 	"encoding/json"
 	"math/big"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestAmountJSON(t *testing.T) {
@@ -32,3 +34,88 @@ func TestAmountJSON(t *testing.T) {
 		t.Errorf("got %s, expected %s", str, "3.67")
 	}
 }
+
+func TestAmountDecimal(t *testing.T) {
+	for _, c := range []struct{ x, s string }{
+		{"3", "3.00"},
+		{"-42.5", "-42.50"},
+		{"3.125", "3.125"},
+		{"1/3", "0.333333333333333333"},
+	} {
+		x, _ := new(big.Rat).SetString(c.x)
+		if s := (*Amount)(x).Decimal(); s != c.s {
+			t.Errorf("%s: got %s, expected %s", c.x, s, c.s)
+		}
+	}
+}
+
+func TestAmountDecimalRound(t *testing.T) {
+	for _, c := range []struct{ x, s string }{
+		{"7/2", "3.50"},
+		{"1.0625", "1.0625"},
+		{"2/3", "0.666667"},
+		{"-1/7", "-0.142857"},
+	} {
+		x, _ := new(big.Rat).SetString(c.x)
+		if s := (*Amount)(x).DecimalRound(6); s != c.s {
+			t.Errorf("%s: got %s, expected %s", c.x, s, c.s)
+		}
+	}
+}
+
+func TestTransactionCheck(t *testing.T) {
+	root := &Account{Name: "Root Account", Type: "ROOT"}
+	bank := &Account{Name: "/Bank", Type: "BANK", Unit: "EUR"}
+	food := &Account{Name: "/Food", Type: "EXPENSE", Unit: "EUR"}
+	amt := func(s string) *Amount {
+		x, _ := new(big.Rat).SetString(s)
+		return (*Amount)(x)
+	}
+	date := time.Date(2013, 3, 1, 0, 0, 0, 0, time.UTC)
+	for _, c := range []struct {
+		trn Transaction
+		err string
+	}{
+		{Transaction{Date: date, Currency: "EUR", Flows: []Flow{
+			{Account: bank, Price: amt("-4.20")}, {Account: food, Price: amt("4.20")},
+		}}, ""},
+		{Transaction{Date: date, Currency: "EUR", Flows: []Flow{
+			{Account: bank, Price: amt("-4.20")}, {Account: food, Price: amt("4")},
+		}}, "transaction is not balanced: -0.20 EUR"},
+		{Transaction{Currency: "EUR"}, "transaction has no date"},
+		{Transaction{Date: date, Currency: "EUR"}, "transaction has no flows"},
+		{Transaction{Date: date, Currency: "EUR", Flows: []Flow{
+			{Account: bank, Price: amt("0")}, {Price: amt("0")},
+		}}, "flow 2 has no account"},
+		{Transaction{Date: date, Currency: "EUR", Flows: []Flow{
+			{Account: root, Price: amt("0")},
+		}}, "flow 1 uses the root account"},
+	} {
+		err := c.trn.Check()
+		if err == nil && c.err != "" || err != nil && err.Error() != c.err {
+			t.Errorf("got error %v, expected %q", err, c.err)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	acct := &Account{Name: "/Bank"}
+	book := &Book{Transactions: map[GUID]*Transaction{
+		"t1": {Description: "Bakery", Notes: "Croissants, bread", Flows: []Flow{{Account: acct, Memo: "Bread"}}},
+		"t2": {Description: "Bank fees", Flows: []Flow{{Account: acct}, {Account: acct, Memo: "card-2013"}}},
+	}}
+	book.Recompute()
+	for _, c := range []struct {
+		prefix string
+		flows  int
+	}{
+		{"ba", 3}, {"bread", 1}, {"croissant", 1}, {"2013", 1}, {"fees", 2}, {"z", 0}, {"Bread", 0},
+	} {
+		if n := len(book.Index.Lookup(c.prefix)); n != c.flows {
+			t.Errorf("%s: got %d flows, expected %d", c.prefix, n, c.flows)
+		}
+	}
+	if w := Words("Card-2013, ÉTÉ"); strings.Join(w, " ") != "card 2013 été" {
+		t.Errorf("got words %q", w)
+	}
+}
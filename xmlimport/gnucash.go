@@ -9,6 +9,7 @@ import (
 	"math/big"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/remyoudompheng/gocash/types"
@@ -72,6 +73,10 @@ func (file *File) Import() (book *types.Book, err error) {
 	}
 	book.Accounts = accountsById
 
+	for _, xmlcommo := range file.Book.Commos {
+		book.Commodities = append(book.Commodities, xmlcommo.Import())
+	}
+
 	// Resolve account hierarchy.
 	actNames := make(map[types.GUID]string)
 	for _, xmlacct := range file.Book.Accounts {
@@ -104,6 +109,16 @@ func (file *File) Import() (book *types.Book, err error) {
 		book.Transactions[xmltrn.Id] = trn
 	}
 
+	// Parse prices.
+	for _, xmlprice := range file.Book.Prices {
+		p, err := xmlprice.Import()
+		if err != nil {
+			return nil, fmt.Errorf("error in price %s: %s", xmlprice.Id, err)
+		}
+		book.Prices = append(book.Prices, p)
+	}
+	book.Prices.Sort()
+
 	return book, nil
 }
 
@@ -113,12 +128,54 @@ type Book struct {
 	Commos       []Commodity   `xml:"http://www.gnucash.org/XML/gnc commodity"`
 	Accounts     []Account     `xml:"http://www.gnucash.org/XML/gnc account"`
 	Transactions []Transaction `xml:"http://www.gnucash.org/XML/gnc transaction"`
+	Prices       []Price       `xml:"pricedb>price"`
 }
 
 type Commodity struct {
-	XMLName xml.Name
-	Space   string `xml:"http://www.gnucash.org/XML/cmdty space"`
-	Id      string `xml:"http://www.gnucash.org/XML/cmdty id"`
+	XMLName  xml.Name
+	Space    string `xml:"http://www.gnucash.org/XML/cmdty space"`
+	Id       string `xml:"http://www.gnucash.org/XML/cmdty id"`
+	Name     string `xml:"http://www.gnucash.org/XML/cmdty name"`
+	Fraction int    `xml:"http://www.gnucash.org/XML/cmdty fraction"`
+}
+
+func (xmlcommo *Commodity) Import() *types.Commodity {
+	return &types.Commodity{
+		Space:    xmlcommo.Space,
+		Id:       xmlcommo.Id,
+		Name:     xmlcommo.Name,
+		Fraction: xmlcommo.Fraction,
+	}
+}
+
+// A Price is an entry of the price database.
+type Price struct {
+	Id        types.GUID `xml:"http://www.gnucash.org/XML/price id"`
+	Commodity Commodity  `xml:"http://www.gnucash.org/XML/price commodity"`
+	Currency  Commodity  `xml:"http://www.gnucash.org/XML/price currency"`
+	Time      TimeStamp  `xml:"http://www.gnucash.org/XML/price time"`
+	Source    string     `xml:"http://www.gnucash.org/XML/price source"`
+	Type      string     `xml:"http://www.gnucash.org/XML/price type"`
+	Value     string     `xml:"http://www.gnucash.org/XML/price value"`
+}
+
+func (xmlprice *Price) Import() (p *types.Price, err error) {
+	p = &types.Price{
+		Id:        xmlprice.Id,
+		Commodity: xmlprice.Commodity.Id,
+		Currency:  xmlprice.Currency.Id,
+		Source:    xmlprice.Source,
+		Type:      xmlprice.Type,
+		Value:     new(types.Amount),
+	}
+	if _, ok := (*big.Rat)(p.Value).SetString(xmlprice.Value); !ok {
+		return p, fmt.Errorf("incorrect price format: %q", xmlprice.Value)
+	}
+	p.Time, err = xmlprice.Time.Time()
+	if err != nil {
+		return p, fmt.Errorf("invalid price time: %s", err)
+	}
+	return p, nil
 }
 
 type Account struct {
@@ -138,7 +195,10 @@ func (xmlact *Account) Import() (act types.Account, err error) {
 		Type: xmlact.Type,
 		Unit: xmlact.Commodity.Id,
 	}
-	slots := xmlact.Slots.Map()
+	slots, err := xmlact.Slots.Map()
+	if err != nil {
+		return act, fmt.Errorf("account %s: %s", act.Name, err)
+	}
 	if slots != nil && slots["notes"] != nil {
 		if notes, ok := slots["notes"].(string); ok {
 			act.Description = notes
@@ -146,6 +206,13 @@ func (xmlact *Account) Import() (act types.Account, err error) {
 			return act, fmt.Errorf("description of account %s is not a string", act.Name)
 		}
 	}
+	act.Placeholder = slots["placeholder"] == "true"
+	act.Hidden = slots["hidden"] == "true"
+	if info, ok := slots["reconcile-info"].(map[string]interface{}); ok {
+		if t, ok := info["last-date"].(int); ok {
+			act.LastReconcile = time.Unix(int64(t), 0)
+		}
+	}
 	return act, nil
 }
 
@@ -164,10 +231,15 @@ type Transaction struct {
 func (xmltrn *Transaction) Import(accts map[types.GUID]*types.Account) (trn *types.Transaction, err error) {
 	trn = &types.Transaction{
 		Id:          xmltrn.Id,
+		Currency:    xmltrn.Currency.Id,
 		Description: xmltrn.Description,
 		Number:      xmltrn.Number,
 	}
-	if slots := xmltrn.Slots.Map(); slots != nil && slots["notes"] != nil {
+	slots, err := xmltrn.Slots.Map()
+	if err != nil {
+		return trn, err
+	}
+	if slots != nil && slots["notes"] != nil {
 		if notes, ok := slots["notes"].(string); ok {
 			trn.Notes = notes
 		} else {
@@ -175,6 +247,13 @@ func (xmltrn *Transaction) Import(accts map[types.GUID]*types.Account) (trn *typ
 		}
 	}
 
+	if slots != nil && slots["void-reason"] != nil {
+		trn.VoidReason, _ = slots["void-reason"].(string)
+		if s, ok := slots["void-time"].(string); ok {
+			trn.VoidTime = parseVoidTime(s)
+		}
+	}
+
 	trn.Flows = make([]types.Flow, len(xmltrn.Splits))
 	for i, split := range xmltrn.Splits {
 		trn.Flows[i], err = split.Import(accts)
@@ -194,6 +273,21 @@ func (xmltrn *Transaction) Import(accts map[types.GUID]*types.Account) (trn *typ
 	return trn, nil
 }
 
+// parseVoidTime parses the void-time slot of a transaction, whose
+// format varies with Gnucash versions. It returns the zero time for
+// unknown formats.
+func parseVoidTime(s string) time.Time {
+	for _, layout := range []string{
+		"2006-01-02 15:04:05.999999999 -0700",
+		"2006-01-02 15:04:05.999999999",
+	} {
+		if t, err := time.Parse(layout, strings.TrimSpace(s)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
 type Split struct {
 	XMLName       xml.Name
 	Id            types.GUID `xml:"id"`
@@ -203,14 +297,16 @@ type Split struct {
 	ReconcileDate *TimeStamp `xml:"reconcile-date"`
 	Value         string     `xml:"value"`
 	Quantity      string     `xml:"quantity"`
+	Slots         Slots      `xml:"slots>slot"`
 }
 
 func (split *Split) Import(accts map[types.GUID]*types.Account) (flow types.Flow, err error) {
 	flow = types.Flow{
-		Id:      split.Id,
-		Account: accts[split.Account],
-		Price:   new(types.Amount),
-		Memo:    split.Memo,
+		Id:       split.Id,
+		Account:  accts[split.Account],
+		Price:    new(types.Amount),
+		Quantity: new(types.Amount),
+		Memo:     split.Memo,
 	}
 
 	if flow.Account == nil {
@@ -220,20 +316,33 @@ func (split *Split) Import(accts map[types.GUID]*types.Account) (flow types.Flow
 	if !ok {
 		return flow, fmt.Errorf("incorrect price format: %q", split.Value)
 	}
-	switch split.Reconciled {
-	case "y":
-		flow.Reconciled = true
-		if split.ReconcileDate == nil {
-			break
-		}
+	if split.Quantity == "" {
+		flow.Quantity.SetRat(flow.Price.Rat())
+	} else if _, ok := (*big.Rat)(flow.Quantity).SetString(split.Quantity); !ok {
+		return flow, fmt.Errorf("incorrect quantity format: %q", split.Quantity)
+	}
+	flow.State, err = types.ParseReconcileState(split.Reconciled)
+	if err != nil {
+		return flow, err
+	}
+	if flow.State.IsReconciled() && split.ReconcileDate != nil {
 		flow.ReconciledTime, err = split.ReconcileDate.Time()
 		if err != nil {
 			return flow, fmt.Errorf("invalid reconcile-date: %s", err)
 		}
-	case "n":
-		flow.Reconciled = false
-	default:
-		return flow, fmt.Errorf("invalid reconciled state %q", split.Reconciled)
+	}
+	if flow.State == types.Voided {
+		// Gnucash keeps the amounts of voided splits in slots.
+		slots, err := split.Slots.Map()
+		if err != nil {
+			return flow, err
+		}
+		if x, ok := slots["void-former-value"].(*big.Rat); ok {
+			flow.VoidPrice = (*types.Amount)(x)
+		}
+		if x, ok := slots["void-former-amount"].(*big.Rat); ok {
+			flow.VoidQuantity = (*types.Amount)(x)
+		}
 	}
 	return flow, nil
 }
@@ -248,12 +357,15 @@ type SlotValue struct {
 	Type   string `xml:"type,attr"`
 	String string `xml:",chardata"`
 	Date   string `xml:"gdate"`
+	TSDate string `xml:"http://www.gnucash.org/XML/ts date"`
 	Values Slots  `xml:"slot"`
 }
 
 type Slots []Slot
 
-func (s Slots) Map() (m map[string]interface{}) {
+// Map converts slots to a map, whose values are strings, integers,
+// numbers, times, or maps for frames.
+func (s Slots) Map() (m map[string]interface{}, err error) {
 	for _, slot := range s {
 		val := slot.Value
 		var v interface{}
@@ -261,35 +373,54 @@ func (s Slots) Map() (m map[string]interface{}) {
 		case "integer":
 			n, err := strconv.Atoi(val.String)
 			if err != nil {
-				panic(err)
+				return nil, fmt.Errorf("slot %s: %s", slot.Key, err)
 			}
 			v = n
-		case "string":
+		case "string", "guid":
 			v = val.String
+		case "numeric":
+			x, ok := new(big.Rat).SetString(val.String)
+			if !ok {
+				return nil, fmt.Errorf("slot %s: invalid numeric %q", slot.Key, val.String)
+			}
+			v = x
+		case "timespec":
+			t, err := TimeStamp{Date: val.TSDate}.Time()
+			if err != nil {
+				return nil, fmt.Errorf("slot %s: %s", slot.Key, err)
+			}
+			v = t
 		case "frame":
-			v = val.Values.Map()
+			frame, err := val.Values.Map()
+			if err != nil {
+				return nil, fmt.Errorf("slot %s: %s", slot.Key, err)
+			}
+			v = frame
 		case "gdate":
 			t, err := time.Parse("2006-01-02", val.Date)
 			if err != nil {
-				panic(err)
+				return nil, fmt.Errorf("slot %s: %s", slot.Key, err)
 			}
 			v = t
 		default:
-			err := fmt.Errorf("unknown slot type: %s", val.Type)
-			panic(err)
+			return nil, fmt.Errorf("slot %s: unknown slot type: %s", slot.Key, val.Type)
 		}
 		if m == nil {
 			m = make(map[string]interface{})
 		}
 		m[slot.Key] = v
 	}
-	return m
+	return m, nil
 }
 
 // MarshalJSON implements a JSON representation of a Slots as a
 // simplified map. It is intended for debugging purposes.
 func (s Slots) MarshalJSON() ([]byte, error) {
-	return json.Marshal(s.Map())
+	m, err := s.Map()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(m)
 }
 
 type TimeStamp struct {
@@ -2,10 +2,12 @@ package xmlimport
 
 import (
 	"encoding/json"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/remyoudompheng/gocash/types"
 )
@@ -93,3 +95,79 @@ func parsePrice(s string) float64 {
 	}
 	panic("unreachable")
 }
+
+func TestImportPrices(t *testing.T) {
+	book, err := ImportFile("testdata/stocks.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Prices) != 5 {
+		t.Fatalf("got %d prices, expected 5", len(book.Prices))
+	}
+	at := time.Date(2013, 7, 1, 0, 0, 0, 0, time.UTC)
+	if p := book.Prices.Lookup("ACME", "USD", at); p == nil || p.String() != "60.00" {
+		t.Errorf("got price %v for ACME, expected 60.00", p)
+	}
+	if p := book.Prices.Lookup("USD", "ACME", at); p == nil || p.Rat().RatString() != "1/60" {
+		t.Errorf("got reverse price %v, expected 1/60", p)
+	}
+	for _, trn := range book.Transactions {
+		for _, f := range trn.Flows {
+			if f.Account.Type == "STOCK" && f.Quantity.Rat().Cmp(f.Price.Rat()) == 0 {
+				t.Errorf("quantity of %q equals its value", trn.Description)
+			}
+		}
+	}
+}
+
+func TestImportStates(t *testing.T) {
+	book, err := ImportFile("testdata/states.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	acct := book.AccountByName("/Checking")
+	states := ""
+	for _, f := range book.Flows[acct] {
+		states += f.State.String()
+	}
+	if states != "fycnv" && states != "fycvn" {
+		t.Errorf("got states %q, expected fycnv", states)
+	}
+	if bal := book.Balance[acct]; bal.String() != "1923.00" {
+		t.Errorf("got balance %s, expected 1923.00", bal)
+	}
+	trn := book.Transactions["b0000000000000000000000000000005"]
+	if !trn.IsVoid() || trn.VoidReason != "Entered twice" || trn.VoidTime.IsZero() {
+		t.Errorf("got void reason %q, time %s", trn.VoidReason, trn.VoidTime)
+	}
+	if p := trn.Flows[0].VoidPrice; p == nil || p.String() != "-23.00" {
+		t.Errorf("got former value %v, expected -23.00", p)
+	}
+	if err := trn.Unvoid(); err != nil {
+		t.Fatal(err)
+	}
+	book.Recompute()
+	if bal := book.Balance[acct]; bal.String() != "1900.00" {
+		t.Errorf("got balance %s after unvoiding, expected 1900.00", bal)
+	}
+	if err := trn.Void("Entered twice", time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if err := trn.Void("again", time.Now()); err == nil {
+		t.Errorf("voided a transaction twice")
+	}
+}
+
+func TestImportBadSlot(t *testing.T) {
+	data, err := os.ReadFile("testdata/states.gml2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bad := strings.Replace(string(data), `<slot:value type="numeric">2300/100</slot:value>`,
+		`<slot:value type="numeric">23,00</slot:value>`, 1)
+	_, err = Import(strings.NewReader(bad))
+	if err == nil || !strings.Contains(err.Error(), `invalid numeric "23,00"`) {
+		t.Errorf("got error %v, expected invalid numeric", err)
+	}
+}